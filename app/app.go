@@ -1,5 +1,7 @@
 package app
 
+import "time"
+
 type status string
 
 const (
@@ -9,17 +11,22 @@ const (
 
 const (
 	BadRequestMsg          string = "Invalid request body, Please check your request body and try again!"
+	UnauthorizedMsg        string = "Authentication is required and has failed or has not yet been provided."
+	ForbiddenMsg           string = "You do not have permission to access the requested resource."
 	NotFoundMsg            string = "The requested resource could not be found but may be available in the future."
 	ConflictMsg            string = "The request could not be completed due to a conflict with the current state of the target resource."
 	StoreErrorMsg          string = "The server encountered an unexpected condition which prevented it from fulfilling the request."
 	InternalServerErrorMsg string = "The server encountered an unexpected condition which prevented it from fulfilling the request."
+	TimeoutMsg             string = "The server did not finish the request in time, Please try again later!"
+	TooManyRequestsMsg     string = "Too many attempts, Please try again later!"
 )
 
 type Response struct {
 	Status  status `json:"status"`
 	Message string `json:"message"`
 	Paging
-	Data any `json:"data,omitempty"`
+	Data   any          `json:"data,omitempty"`
+	Errors []ErrorField `json:"errors,omitempty"`
 }
 
 type Paging struct {
@@ -33,6 +40,7 @@ type ErrorField struct {
 	Field string `json:"field"`
 	Value any    `json:"value"`
 	Tag   string `json:"tag"`
+	Param string `json:"param,omitempty"`
 }
 
 type Error Response
@@ -42,27 +50,206 @@ func (err *Error) Error() string {
 }
 
 type TokenData struct {
-	UserID    int    `json:"userId"`
-	Username  string `json:"username"`
-	FirstName string `json:"firstname"`
-	LastName  string `json:"lastname"`
-	Role      string `json:"role"`
+	UserID      int      `json:"userId"`
+	Username    string   `json:"username"`
+	FirstName   string   `json:"firstname"`
+	LastName    string   `json:"lastname"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+func (t *TokenData) HasPermission(permission string) bool {
+	for _, p := range t.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type Config struct {
 	Env      string   `mapstructure:"env"`
 	Server   Server   `mapstructure:"server"`
 	Database Database `mapstructure:"db"`
+	Tracing  Tracing  `mapstructure:"tracing"`
+	Log      Log      `mapstructure:"log"`
+	Auth     Auth     `mapstructure:"auth"`
+	Mail     Mail     `mapstructure:"mail"`
+	Admin    Admin    `mapstructure:"admin"`
+}
+
+// Admin is the account given the admin role at startup, so a fresh
+// deployment has someone who can assign roles to the others.
+type Admin struct {
+	// Username is the admin account; empty seeds nobody.
+	Username string `mapstructure:"username"`
+	// Password is set when the account is created. An existing account
+	// keeps its password.
+	Password string `mapstructure:"password"`
+	Email    string `mapstructure:"email"`
 }
 
 type Server struct {
 	Port string `mapstructure:"port"`
+	// RequestTimeout bounds each request, including the queries it runs.
+	// Zero means no limit.
+	RequestTimeout time.Duration `mapstructure:"requestTimeout"`
+	// ShutdownDelay is how long /readyz fails after SIGTERM before the
+	// server stops accepting connections. It must exceed the readiness
+	// probe period, or load balancers never see the failure.
+	ShutdownDelay time.Duration `mapstructure:"shutdownDelay"`
+	// TrustedProxies are the IPs or CIDRs of the reverse proxies in front of
+	// the server. Only their X-Forwarded-For is believed for the client IP;
+	// empty means the server is reached directly and the header is ignored.
+	TrustedProxies []string `mapstructure:"trustedProxies"`
 }
 
 type Database struct {
+	// Driver is mysql (the default), postgres or sqlite.
+	Driver   string `mapstructure:"driver"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	Database string `mapstructure:"database"`
+	// SSLMode is the postgres sslmode, "disable" when empty.
+	SSLMode string `mapstructure:"sslmode"`
+	// Path is the sqlite database file, or ":memory:".
+	Path string `mapstructure:"path"`
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool `mapstructure:"autoMigrate"`
+}
+
+type Tracing struct {
+	// Exporter is where finished spans go: none (the default), stdout, file
+	// or otlp.
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint is the OTLP/HTTP collector, e.g. "localhost:4318". When empty
+	// the OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure sends OTLP over plain HTTP instead of HTTPS.
+	Insecure bool `mapstructure:"insecure"`
+	// File is where the file exporter appends spans as JSON.
+	File string `mapstructure:"file"`
+	// SampleRatio is the fraction of new traces recorded, from 0 (none) to 1
+	// (all); unset records every trace. Requests with a sampled traceparent
+	// are always recorded.
+	SampleRatio *float64 `mapstructure:"sampleRatio"`
+	// ServiceName is reported as service.name, "go-restapi" when empty.
+	ServiceName string `mapstructure:"serviceName"`
+}
+
+type Log struct {
+	// Level is the lowest level logged: debug, info (the default), warn or
+	// error. It can be changed at runtime from the admin endpoint.
+	Level string `mapstructure:"level"`
+	// RedactKeys are attribute keys masked in every record, on top of the
+	// built-in ones such as password and token.
+	RedactKeys []string  `mapstructure:"redactKeys"`
+	AccessLog  AccessLog `mapstructure:"accessLog"`
+	SQL        SQLLog    `mapstructure:"sql"`
+}
+
+type SQLLog struct {
+	// Level is silent, error, warn (errors and slow queries) or info (the
+	// default, every query). Queries that are neither failed nor slow are
+	// debug records, so they also need the debug log level or X-Debug-Log.
+	Level string `mapstructure:"level"`
+	// SlowThreshold logs queries running longer as warnings. Zero disables it.
+	SlowThreshold time.Duration `mapstructure:"slowThreshold"`
+}
+
+type AccessLog struct {
+	// Exclude lists request paths or route templates, e.g. "/healthz", that
+	// are never logged.
+	Exclude []string `mapstructure:"exclude"`
+	// SuccessSampleRatio is the fraction of requests answered below 400 that
+	// are logged; zero logs all of them. Failed requests are always logged.
+	SuccessSampleRatio float64 `mapstructure:"successSampleRatio"`
+}
+
+// Auth is the token policy of the deployment. Zero values take the defaults
+// of auth.DefaultConfig; auth.ValidateConfig checks it at startup.
+type Auth struct {
+	AccessTokenTTL  time.Duration `mapstructure:"accessTokenTTL"`
+	RefreshTokenTTL time.Duration `mapstructure:"refreshTokenTTL"`
+	// Issuer goes in the iss claim and is required when verifying.
+	Issuer string `mapstructure:"issuer"`
+	// Audience, when set, goes in the aud claim and is required when
+	// verifying.
+	Audience string `mapstructure:"audience"`
+	// Algorithm signs new access tokens: RS256, ES256, ES384, ES512 or
+	// EdDSA. The active key must be of this algorithm.
+	Algorithm     string        `mapstructure:"algorithm"`
+	Lockout       Lockout       `mapstructure:"lockout"`
+	Keys          Keys          `mapstructure:"keys"`
+	PasswordReset PasswordReset `mapstructure:"passwordReset"`
+	MFA           MFA           `mapstructure:"mfa"`
+}
+
+// MFA is the TOTP second factor of logins.
+type MFA struct {
+	// Issuer names the account in authenticator apps; empty uses the token
+	// issuer.
+	Issuer string `mapstructure:"issuer"`
+	// RequiredRoles must log in with a second factor. Users holding one of
+	// them without MFA have to enroll before their login completes.
+	RequiredRoles []string `mapstructure:"requiredRoles"`
+	// ChallengeTTL is how long a login waits for its second factor.
+	ChallengeTTL time.Duration `mapstructure:"challengeTTL"`
+}
+
+// PasswordReset is how reset tokens are handed out by POST /password/forgot.
+type PasswordReset struct {
+	// TokenTTL is how long a reset token can be used.
+	TokenTTL time.Duration `mapstructure:"tokenTTL"`
+	// URL is the client page that resets passwords. The token is added to it
+	// as the token query parameter; empty mails the token alone.
+	URL string `mapstructure:"url"`
+}
+
+// Keys locates the keys access tokens are signed with. Dir holds one PEM
+// file per key, named <kid>.pem; a file with only a public key verifies
+// tokens but never signs. Empty Dir loads config/private.pem alone.
+type Keys struct {
+	Dir string `mapstructure:"dir"`
+	// Active is the kid new tokens are signed with. Empty picks the private
+	// key whose kid sorts last.
+	Active string `mapstructure:"active"`
+	// ReloadInterval is how often Dir is read again to pick up rotated keys.
+	// Zero reads it at startup only.
+	ReloadInterval time.Duration `mapstructure:"reloadInterval"`
+}
+
+// Lockout throttles password guessing. MaxFailures failed logins for one
+// username, or IPMaxFailures from one client IP, within Window lock further
+// logins for LockDuration. Each failure while the count stays above the
+// limit doubles the lock, up to MaxLockDuration. Zero values take the
+// defaults of auth.DefaultLockout.
+type Lockout struct {
+	MaxFailures     int           `mapstructure:"maxFailures"`
+	IPMaxFailures   int           `mapstructure:"ipMaxFailures"`
+	Window          time.Duration `mapstructure:"window"`
+	LockDuration    time.Duration `mapstructure:"lockDuration"`
+	MaxLockDuration time.Duration `mapstructure:"maxLockDuration"`
+}
+
+type Mail struct {
+	// Driver delivers mail: log writes it to the application log and file
+	// appends it to File, both for local development, while smtp sends it
+	// through SMTP. log is the default with env local and refused elsewhere.
+	Driver string `mapstructure:"driver"`
+	// From is the sender address of every mail.
+	From string `mapstructure:"from"`
+	File string `mapstructure:"file"`
+	SMTP SMTP   `mapstructure:"smtp"`
+}
+
+type SMTP struct {
+	Host string `mapstructure:"host"`
+	Port string `mapstructure:"port"`
+	// Username and Password log in with PLAIN auth, which needs TLS unless
+	// Host is localhost. Empty Username sends without logging in.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
 }
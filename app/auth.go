@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+	"go-restapi/logger"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tokenDataKey = "tokenData"
+
+// DebugLogHeader set to true asks for debug logs of a single request.
+const DebugLogHeader = "X-Debug-Log"
+
+var ErrMissingBearerToken = NewCodedError("MISSING_BEARER_TOKEN", http.StatusUnauthorized, "missing bearer token")
+var ErrPermissionDenied = NewCodedError("PERMISSION_DENIED", http.StatusForbidden, "permission denied")
+
+// TokenVerifier checks a raw access token and returns the data it carries.
+type TokenVerifier func(token string) (*TokenData, error)
+
+// AuthMiddleware rejects requests without a valid `Authorization: Bearer`
+// token and stores the decoded TokenData for Context.GetTokenData.
+func AuthMiddleware(verify TokenVerifier) Middleware {
+	return func(ctx Context, next Next) {
+		token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			ctx.Unauthorized(ErrMissingBearerToken)
+			return
+		}
+
+		data, err := verify(token)
+		if err != nil {
+			ctx.Unauthorized(err)
+			return
+		}
+
+		ctx.SetTokenData(data)
+		next()
+	}
+}
+
+// PermissionMiddleware rejects requests whose token data, set by
+// AuthMiddleware, does not grant the given permission.
+func PermissionMiddleware(permission string) Middleware {
+	return func(ctx Context, next Next) {
+		data := ctx.GetTokenData()
+		if data == nil {
+			ctx.Unauthorized(ErrMissingBearerToken)
+			return
+		}
+
+		if !data.HasPermission(permission) {
+			ctx.Forbidden(fmt.Errorf("%w: user %d requires %s", ErrPermissionDenied, data.UserID, permission))
+			return
+		}
+		ctx.Debugf("user %d granted %s", data.UserID, permission)
+
+		next()
+	}
+}
+
+// debugLog logs the rest of the request down to debug level when it has the
+// X-Debug-Log header and its token data grants permission. Other requests,
+// including ones sending the header without the permission, are unchanged.
+func debugLog(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled, _ := strconv.ParseBool(c.GetHeader(DebugLogHeader)); enabled {
+			v, _ := c.Get(tokenDataKey)
+			if data, ok := v.(*TokenData); ok && data != nil && data.HasPermission(permission) {
+				c.Request = c.Request.WithContext(logger.WithDebug(c.Request.Context()))
+			}
+		}
+		c.Next()
+	}
+}
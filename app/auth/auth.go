@@ -1,22 +1,78 @@
 package auth
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"go-restapi/app"
+	"go-restapi/app/keys"
+	"go-restapi/app/role"
 	"go-restapi/app/user"
+	"go-restapi/mail"
 	"go-restapi/utils"
+	"net/http"
 	"time"
 )
 
 const (
-	RefreshTokenTableName  = "refresh_tokens"
-	AccessTokenExpireHour  = 6
-	RefreshTokenExpireHour = 24
-	FormatDateTime         = "2006-01-02 15:04:05"
+	RefreshTokenTableName = "refresh_tokens"
+	LoginAttemptTableName = "login_attempts"
+	ResetTokenTableName   = "password_reset_tokens"
+	MFATableName          = "user_mfa"
+	RecoveryCodeTableName = "mfa_recovery_codes"
+	MFAChallengeTableName = "mfa_challenges"
+	FormatDateTime        = "2006-01-02 15:04:05"
+)
+
+// Purposes of an MFA challenge: verify asks for a code of the enrolled
+// second factor, enroll makes a user who must have one enroll it first.
+const (
+	MFAChallengeVerify = "verify"
+	MFAChallengeEnroll = "enroll"
 )
 
 type AuthRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// ClientIP is set by the handler, for throttling logins per client.
+	ClientIP string `json:"-"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+	All          bool   `json:"all"`
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=8,max=50"`
+}
+
+// MFALoginRequest completes a login with the second factor. Code is a TOTP
+// code or, for verify challenges, a recovery code.
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+	// ClientIP is set by the handler, for throttling logins per client.
+	ClientIP string `json:"-"`
+}
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+}
+
+// MFACodeRequest confirms or disables the second factor of the current user.
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+	// ClientIP is set by the handler, for throttling guessed codes.
+	ClientIP string `json:"-"`
 }
 
 type AuthResponse struct {
@@ -24,22 +80,177 @@ type AuthResponse struct {
 	AccessTokenExpireAt  string `json:"accessTokenExpireAt"`
 	RefreshToken         string `json:"refreshToken"`
 	RefreshTokenExpireAt string `json:"refreshTokenExpireAt"`
+	// RecoveryCodes are set once, when a login enrolls the second factor.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// MFAChallengeResponse answers a login whose password is right but that
+// still needs the second factor, sent with ChallengeToken to /login/mfa.
+type MFAChallengeResponse struct {
+	MFARequired       bool   `json:"mfaRequired"`
+	Purpose           string `json:"purpose"`
+	ChallengeToken    string `json:"challengeToken"`
+	ChallengeExpireAt string `json:"challengeExpireAt"`
+}
+
+// LoginResponse is the outcome of a login with the right password: Tokens,
+// or Challenge when the login still needs its second factor. Exactly one is
+// set.
+type LoginResponse struct {
+	Tokens    *AuthResponse
+	Challenge *MFAChallengeResponse
+}
+
+// MFAEnrollResponse is the secret to add to an authenticator app, as is or
+// as a QR code of OTPAuthURI.
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type MFAConfirmResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+var ErrUserNotFound = app.NewCodedError("USER_NOT_FOUND", http.StatusNotFound, "user not found")
+var ErrInvalidCredentials = app.NewCodedError("INVALID_CREDENTIALS", http.StatusUnauthorized, "invalid username or password")
+var ErrRefreshTokenNotFound = app.NewCodedError("REFRESH_TOKEN_NOT_FOUND", http.StatusUnauthorized, "refresh token not found")
+var ErrRefreshTokenExpired = app.NewCodedError("REFRESH_TOKEN_EXPIRED", http.StatusUnauthorized, "refresh token expired")
+var ErrRefreshTokenRevoked = app.NewCodedError("REFRESH_TOKEN_REVOKED", http.StatusUnauthorized, "refresh token revoked")
+var ErrAccountLocked = app.NewCodedError("ACCOUNT_LOCKED", http.StatusTooManyRequests, "too many failed logins")
+var ErrResetTokenInvalid = app.NewCodedError("RESET_TOKEN_INVALID", http.StatusBadRequest, "reset token is invalid or expired")
+var ErrMFAChallengeInvalid = app.NewCodedError("MFA_CHALLENGE_INVALID", http.StatusUnauthorized, "mfa challenge is invalid or expired")
+var ErrMFACodeInvalid = app.NewCodedError("MFA_CODE_INVALID", http.StatusBadRequest, "mfa code is invalid")
+var ErrMFAAlreadyEnabled = app.NewCodedError("MFA_ALREADY_ENABLED", http.StatusConflict, "mfa is already enabled")
+var ErrMFANotEnrolled = app.NewCodedError("MFA_NOT_ENROLLED", http.StatusConflict, "mfa is not enrolled")
+var ErrMFARequired = app.NewCodedError("MFA_REQUIRED", http.StatusForbidden, "mfa is required for the roles of the user")
+
+// LockedError is returned by Login, and by checks of the current password,
+// while the username or the client IP is locked after too many failed
+// logins. It wraps ErrAccountLocked.
+type LockedError struct {
+	UnlockAt time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, locked until %s", ErrAccountLocked.Title, e.UnlockAt.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
 }
 
-var ErrUserNotFound = errors.New("user not found")
-var ErrPasswordNotMatch = errors.New("password not match")
+// RetryAt makes the response tell the client when it may log in again.
+func (e *LockedError) RetryAt() time.Time {
+	return e.UnlockAt
+}
 
 type RefreshTokenModel struct {
-	ID        int       `db:"id" gorm:"primaryKey" `
-	UserID    int       `db:"user_id"`
-	Token     string    `db:"token" gorm:"unique"`
-	ExpiredAt time.Time `db:"expired_at"`
-	CreatedAt time.Time `db:"created_at" gorm:"autoCreateTime"`
-	CreatedBy string    `db:"created_by" gorm:"default:'SYSTEM'"`
-	UpdatedAt time.Time `db:"updated_at" gorm:"autoUpdateTime"`
-	UpdatedBy string    `db:"updated_by"`
-}
-
-func New(userStorage user.UserStorage, refreshTokenStorage RefreshTokenStorage) AuthHandler {
-	return NewAuthHandler(NewAuthService(userStorage, refreshTokenStorage, utils.NewUtils()))
+	ID        int        `db:"id" gorm:"primaryKey" `
+	UserID    int        `db:"user_id"`
+	Token     string     `db:"token" gorm:"unique"`
+	ExpiredAt time.Time  `db:"expired_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	// MFAVerified is set when the session was opened with the second
+	// factor, and kept by rotation.
+	MFAVerified bool      `db:"mfa_verified"`
+	CreatedAt   time.Time `db:"created_at" gorm:"autoCreateTime"`
+	CreatedBy   string    `db:"created_by" gorm:"default:'SYSTEM'"`
+	UpdatedAt   time.Time `db:"updated_at" gorm:"autoUpdateTime"`
+	UpdatedBy   string    `db:"updated_by"`
+}
+
+// LoginAttemptModel counts the recent failed logins of a subject, either a
+// username or a client IP.
+type LoginAttemptModel struct {
+	Subject      string     `db:"subject" gorm:"primaryKey"`
+	Failures     int        `db:"failures"`
+	LastFailedAt time.Time  `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+	UpdatedAt    time.Time  `db:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ResetTokenModel is a password reset token. Only the SHA-256 of the token
+// is stored; the token itself is mailed to the user.
+type ResetTokenModel struct {
+	ID        int        `db:"id" gorm:"primaryKey"`
+	UserID    int        `db:"user_id"`
+	TokenHash string     `db:"token_hash" gorm:"unique"`
+	ExpiredAt time.Time  `db:"expired_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at" gorm:"autoCreateTime"`
+}
+
+// MFAModel is the TOTP secret of a user. EnabledAt is nil until a first code
+// confirms it; codes of steps up to LastUsedStep are refused.
+type MFAModel struct {
+	UserID       int        `db:"user_id" gorm:"primaryKey"`
+	Secret       string     `db:"secret"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `db:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type RecoveryCodeModel struct {
+	ID        int        `db:"id" gorm:"primaryKey"`
+	UserID    int        `db:"user_id"`
+	CodeHash  string     `db:"code_hash"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at" gorm:"autoCreateTime"`
+}
+
+// MFAChallengeModel is a login waiting for its second factor. Only the
+// SHA-256 of the token is stored.
+type MFAChallengeModel struct {
+	ID        int        `db:"id" gorm:"primaryKey"`
+	UserID    int        `db:"user_id"`
+	TokenHash string     `db:"token_hash" gorm:"unique"`
+	Purpose   string     `db:"purpose"`
+	ExpiredAt time.Time  `db:"expired_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at" gorm:"autoCreateTime"`
+}
+
+type tokenRevoker struct {
+	refreshTokenStorage RefreshTokenStorage
+	resetTokenStorage   ResetTokenStorage
+}
+
+// NewTokenRevoker revokes the refresh tokens and uses up the reset tokens of
+// a user when package user changes its password.
+func NewTokenRevoker(refreshTokenStorage RefreshTokenStorage, resetTokenStorage ResetTokenStorage) user.TokenRevoker {
+	return &tokenRevoker{
+		refreshTokenStorage: refreshTokenStorage,
+		resetTokenStorage:   resetTokenStorage,
+	}
+}
+
+func (r *tokenRevoker) RevokeTokensByUserID(ctx context.Context, userID int) error {
+	if err := r.refreshTokenStorage.RevokeRefreshTokensByUserID(ctx, userID); err != nil {
+		return err
+	}
+	return r.resetTokenStorage.UseResetTokensByUserID(ctx, userID)
+}
+
+type passwordLimiter struct {
+	lockout *lockout
+}
+
+// NewPasswordLimiter counts the wrong current passwords given to package user
+// as failed logins of the user, so they share the login lockout.
+func NewPasswordLimiter(loginAttemptStorage LoginAttemptStorage, policy app.Lockout) user.PasswordLimiter {
+	return &passwordLimiter{lockout: newLockout(loginAttemptStorage, policy)}
+}
+
+func (l *passwordLimiter) Check(ctx context.Context, username, clientIP string) error {
+	return l.lockout.check(ctx, username, clientIP)
+}
+
+func (l *passwordLimiter) Failed(ctx context.Context, username, clientIP string) error {
+	return l.lockout.failed(ctx, username, clientIP)
+}
+
+func New(userStorage user.UserStorage, refreshTokenStorage RefreshTokenStorage, roleStorage role.RoleStorage, loginAttemptStorage LoginAttemptStorage, resetTokenStorage ResetTokenStorage, mfaStorage MFAStorage, signer keys.Signer, mailer mail.Mailer, conf app.Auth) AuthHandler {
+	return NewAuthHandler(NewAuthService(userStorage, refreshTokenStorage, roleStorage, loginAttemptStorage, resetTokenStorage, mfaStorage, signer, mailer, utils.NewUtils(), conf))
 }
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"go-restapi/app"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,6 @@ import (
 func TestAuth(t *testing.T) {
 	userStorage := &mockUserStorage{}
 	refreshTokenStorage := &mockRefreshTokenStorage{}
-	got := New(userStorage, refreshTokenStorage)
+	got := New(userStorage, refreshTokenStorage, &mockRoleStorage{}, newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, app.Auth{})
 	assert.NotNil(t, got)
 }
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"go-restapi/app"
+	"go-restapi/app/keys"
+	"go-restapi/utils"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultConfig fills the zero values of the configured auth policy.
+var DefaultConfig = app.Auth{
+	AccessTokenTTL:  6 * time.Hour,
+	RefreshTokenTTL: 24 * time.Hour,
+	Issuer:          "go-restapi",
+	Algorithm:       "RS256",
+	Lockout:         DefaultLockout,
+	PasswordReset: app.PasswordReset{
+		TokenTTL: 30 * time.Minute,
+	},
+	MFA: app.MFA{
+		ChallengeTTL: 5 * time.Minute,
+	},
+}
+
+var ErrInvalidConfig = errors.New("invalid auth config")
+
+func withDefaults(conf app.Auth) app.Auth {
+	if conf.AccessTokenTTL == 0 {
+		conf.AccessTokenTTL = DefaultConfig.AccessTokenTTL
+	}
+	if conf.RefreshTokenTTL == 0 {
+		conf.RefreshTokenTTL = DefaultConfig.RefreshTokenTTL
+	}
+	if conf.Issuer == "" {
+		conf.Issuer = DefaultConfig.Issuer
+	}
+	if conf.Algorithm == "" {
+		conf.Algorithm = DefaultConfig.Algorithm
+	}
+	if conf.PasswordReset.TokenTTL == 0 {
+		conf.PasswordReset.TokenTTL = DefaultConfig.PasswordReset.TokenTTL
+	}
+	if conf.MFA.ChallengeTTL == 0 {
+		conf.MFA.ChallengeTTL = DefaultConfig.MFA.ChallengeTTL
+	}
+	return conf
+}
+
+// NewTokenVerifier checks the access tokens issued under conf, finding their
+// keys with keyfunc.
+func NewTokenVerifier(keyfunc jwt.Keyfunc, conf app.Auth) app.TokenVerifier {
+	conf = withDefaults(conf)
+	u := utils.NewUtils()
+	return func(token string) (*app.TokenData, error) {
+		return u.ParseAccessToken(keyfunc, conf, token)
+	}
+}
+
+// ValidateConfig fills conf with the defaults and rejects a policy the
+// service cannot run with, so a bad deployment fails at startup rather than
+// at the first login.
+func ValidateConfig(conf app.Auth) (app.Auth, error) {
+	conf = withDefaults(conf)
+
+	var errs []error
+	if conf.AccessTokenTTL < time.Second {
+		errs = append(errs, fmt.Errorf("%w: accessTokenTTL %s is shorter than a second", ErrInvalidConfig, conf.AccessTokenTTL))
+	}
+	if conf.RefreshTokenTTL < conf.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("%w: refreshTokenTTL %s is shorter than accessTokenTTL %s", ErrInvalidConfig, conf.RefreshTokenTTL, conf.AccessTokenTTL))
+	}
+	if !slices.Contains(keys.Algorithms, conf.Algorithm) {
+		errs = append(errs, fmt.Errorf("%w: algorithm %q is not one of %v", ErrInvalidConfig, conf.Algorithm, keys.Algorithms))
+	}
+	lockout := conf.Lockout
+	if lockout.MaxFailures < 0 || lockout.IPMaxFailures < 0 || lockout.Window < 0 || lockout.LockDuration < 0 || lockout.MaxLockDuration < 0 {
+		errs = append(errs, fmt.Errorf("%w: lockout values must not be negative", ErrInvalidConfig))
+	}
+	if conf.PasswordReset.TokenTTL < time.Second {
+		errs = append(errs, fmt.Errorf("%w: passwordReset.tokenTTL %s is shorter than a second", ErrInvalidConfig, conf.PasswordReset.TokenTTL))
+	}
+	if _, err := url.Parse(conf.PasswordReset.URL); err != nil {
+		errs = append(errs, fmt.Errorf("%w: passwordReset.url: %w", ErrInvalidConfig, err))
+	}
+	if conf.MFA.ChallengeTTL < time.Second {
+		errs = append(errs, fmt.Errorf("%w: mfa.challengeTTL %s is shorter than a second", ErrInvalidConfig, conf.MFA.ChallengeTTL))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return app.Auth{}, err
+	}
+	return conf, nil
+}
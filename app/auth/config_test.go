@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"go-restapi/app"
+	"go-restapi/app/keys"
+	"go-restapi/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Run("Should fill the defaults", func(t *testing.T) {
+		got, err := ValidateConfig(app.Auth{Audience: "books-api"})
+		assert.NoError(t, err)
+		assert.Equal(t, app.Auth{
+			AccessTokenTTL:  DefaultConfig.AccessTokenTTL,
+			RefreshTokenTTL: DefaultConfig.RefreshTokenTTL,
+			Issuer:          DefaultConfig.Issuer,
+			Audience:        "books-api",
+			Algorithm:       DefaultConfig.Algorithm,
+			PasswordReset:   DefaultConfig.PasswordReset,
+			MFA:             DefaultConfig.MFA,
+		}, got)
+	})
+
+	testCases := []struct {
+		name string
+		conf app.Auth
+	}{
+		{"Should reject a lifetime under a second", app.Auth{AccessTokenTTL: time.Millisecond}},
+		{"Should reject a negative lifetime", app.Auth{AccessTokenTTL: -time.Minute}},
+		{"Should reject refresh tokens shorter than access tokens", app.Auth{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Minute}},
+		{"Should reject an unknown algorithm", app.Auth{Algorithm: "HS256"}},
+		{"Should reject negative lockout values", app.Auth{Lockout: app.Lockout{Window: -time.Minute}}},
+		{"Should reject a negative reset token lifetime", app.Auth{PasswordReset: app.PasswordReset{TokenTTL: -time.Minute}}},
+		{"Should reject an invalid reset url", app.Auth{PasswordReset: app.PasswordReset{URL: "http://[::1"}}},
+		{"Should reject a negative mfa challenge lifetime", app.Auth{MFA: app.MFA{ChallengeTTL: -time.Minute}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ValidateConfig(tc.conf)
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		})
+	}
+}
+
+func TestNewTokenVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	key, err := keys.NewKey("key-1", ecKey)
+	assert.NoError(t, err)
+	manager := keys.NewStaticManager(key)
+	data := app.TokenData{UserID: 1, Username: "admin"}
+
+	t.Run("Should verify tokens issued with the default policy", func(t *testing.T) {
+		token, _, err := utils.NewUtils().GetAccessToken(key, data, DefaultConfig)
+		assert.NoError(t, err)
+
+		got, err := NewTokenVerifier(manager.Keyfunc, app.Auth{})(token)
+		assert.NoError(t, err)
+		assert.Equal(t, data.UserID, got.UserID)
+	})
+
+	t.Run("Should reject tokens of another issuer", func(t *testing.T) {
+		token, _, err := utils.NewUtils().GetAccessToken(key, data, app.Auth{AccessTokenTTL: time.Minute, Issuer: "other"})
+		assert.NoError(t, err)
+
+		_, err = NewTokenVerifier(manager.Keyfunc, app.Auth{})(token)
+		assert.Error(t, err)
+	})
+}
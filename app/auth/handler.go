@@ -3,12 +3,21 @@ package auth
 import (
 	"errors"
 	"go-restapi/app"
+	"strconv"
 )
 
 type AuthHandler interface {
 	Login(ctx app.Context)
-	// Logout(ctx app.Context)
-	// RefreshToken(ctx app.Context)
+	Logout(ctx app.Context)
+	RefreshToken(ctx app.Context)
+	UnlockUser(ctx app.Context)
+	ForgotPassword(ctx app.Context)
+	ResetPassword(ctx app.Context)
+	VerifyMFA(ctx app.Context)
+	EnrollMFAChallenge(ctx app.Context)
+	EnrollMFA(ctx app.Context)
+	ConfirmMFA(ctx app.Context)
+	DisableMFA(ctx app.Context)
 }
 
 type authHandler struct {
@@ -28,19 +37,164 @@ func (h *authHandler) Login(ctx app.Context) {
 		return
 	}
 
-	if _, err := ctx.Validate(&req); err != nil {
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	req.ClientIP = ctx.ClientIP()
+	res, err := h.authSvc.Login(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrAccountLocked) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.InternalServerError(err)
+		return
+	}
+
+	if res.Challenge != nil {
+		ctx.OK(res.Challenge)
+		return
+	}
+	ctx.OK(res.Tokens)
+}
+
+func (h *authHandler) Logout(ctx app.Context) {
+	var req LogoutRequest
+	if err := ctx.Bind(&req); err != nil {
 		ctx.BadRequest(err)
 		return
 	}
 
-	res, err := h.authSvc.Login(req)
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.authSvc.Logout(ctx, req); err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) || errors.Is(err, ErrRefreshTokenRevoked) {
+			ctx.Unauthorized(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *authHandler) RefreshToken(ctx app.Context) {
+	var req RefreshTokenRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	res, err := h.authSvc.RefreshToken(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) || errors.Is(err, ErrRefreshTokenExpired) || errors.Is(err, ErrRefreshTokenRevoked) || errors.Is(err, ErrUserNotFound) {
+			ctx.Unauthorized(err)
+			return
+		}
+		if errors.Is(err, ErrMFARequired) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.OK(res)
+}
+
+func (h *authHandler) UnlockUser(ctx app.Context) {
+	id, err := strconv.Atoi(ctx.GetParam("id"))
 	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if err := h.authSvc.UnlockUser(ctx, id); err != nil {
 		if errors.Is(err, ErrUserNotFound) {
-			ctx.NotFound()
+			ctx.HandleError(err)
 			return
 		}
-		if errors.Is(err, ErrPasswordNotMatch) {
-			ctx.BadRequest(err)
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+// ForgotPassword answers the same whether or not the username exists.
+func (h *authHandler) ForgotPassword(ctx app.Context) {
+	var req ForgotPasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.authSvc.ForgotPassword(ctx, req); err != nil {
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *authHandler) ResetPassword(ctx app.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.authSvc.ResetPassword(ctx, req); err != nil {
+		if errors.Is(err, ErrResetTokenInvalid) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+// VerifyMFA completes a login that answered with a challenge.
+func (h *authHandler) VerifyMFA(ctx app.Context) {
+	var req MFALoginRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	req.ClientIP = ctx.ClientIP()
+	res, err := h.authSvc.VerifyMFA(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrMFAChallengeInvalid) || errors.Is(err, ErrMFACodeInvalid) || errors.Is(err, ErrMFANotEnrolled) || errors.Is(err, ErrMFAAlreadyEnabled) || errors.Is(err, ErrAccountLocked) {
+			ctx.HandleError(err)
 			return
 		}
 		ctx.InternalServerError(err)
@@ -50,10 +204,91 @@ func (h *authHandler) Login(ctx app.Context) {
 	ctx.OK(res)
 }
 
-// func (h *authHandler) Logout(ctx app.Context) {
-// 	ctx.OK(nil)
-// }
+func (h *authHandler) EnrollMFAChallenge(ctx app.Context) {
+	var req MFAChallengeRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	res, err := h.authSvc.EnrollMFAChallenge(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrMFAChallengeInvalid) || errors.Is(err, ErrMFAAlreadyEnabled) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(res)
+}
 
-// func (h *authHandler) RefreshToken(ctx app.Context) {
-// 	ctx.OK(nil)
-// }
+func (h *authHandler) EnrollMFA(ctx app.Context) {
+	res, err := h.authSvc.EnrollMFA(ctx, ctx.GetTokenData().UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrMFAAlreadyEnabled) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(res)
+}
+
+func (h *authHandler) ConfirmMFA(ctx app.Context) {
+	var req MFACodeRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	res, err := h.authSvc.ConfirmMFA(ctx, ctx.GetTokenData().UserID, req)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnrolled) || errors.Is(err, ErrMFAAlreadyEnabled) || errors.Is(err, ErrMFACodeInvalid) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(res)
+}
+
+func (h *authHandler) DisableMFA(ctx app.Context) {
+	var req MFACodeRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	req.ClientIP = ctx.ClientIP()
+	if err := h.authSvc.DisableMFA(ctx, ctx.GetTokenData().UserID, req); err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrMFARequired) || errors.Is(err, ErrMFANotEnrolled) || errors.Is(err, ErrMFACodeInvalid) || errors.Is(err, ErrAccountLocked) {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go-restapi/app"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultLockout fills the zero values of the configured lockout.
+var DefaultLockout = app.Lockout{
+	MaxFailures:     5,
+	IPMaxFailures:   50,
+	Window:          15 * time.Minute,
+	LockDuration:    time.Minute,
+	MaxLockDuration: time.Hour,
+}
+
+// lockout tracks failed logins per username and per client IP, and locks
+// either once it fails too often.
+type lockout struct {
+	storage LoginAttemptStorage
+	policy  app.Lockout
+	now     func() time.Time
+}
+
+func newLockout(storage LoginAttemptStorage, policy app.Lockout) *lockout {
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = DefaultLockout.MaxFailures
+	}
+	if policy.IPMaxFailures <= 0 {
+		policy.IPMaxFailures = DefaultLockout.IPMaxFailures
+	}
+	if policy.Window <= 0 {
+		policy.Window = DefaultLockout.Window
+	}
+	if policy.LockDuration <= 0 {
+		policy.LockDuration = DefaultLockout.LockDuration
+	}
+	if policy.MaxLockDuration <= 0 {
+		policy.MaxLockDuration = DefaultLockout.MaxLockDuration
+	}
+	if policy.MaxLockDuration < policy.LockDuration {
+		policy.MaxLockDuration = policy.LockDuration
+	}
+	return &lockout{storage: storage, policy: policy, now: time.Now}
+}
+
+// Usernames are counted case-insensitively, so changing the case of a name
+// does not start a new count.
+func userSubject(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipSubject(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// check returns a *LockedError when the username or the client IP is locked.
+func (l *lockout) check(ctx context.Context, username, clientIP string) error {
+	subjects := []string{userSubject(username)}
+	if clientIP != "" {
+		subjects = append(subjects, ipSubject(clientIP))
+	}
+
+	now := l.now()
+	var unlockAt time.Time
+	for _, subject := range subjects {
+		attempt, err := l.storage.GetLoginAttempt(ctx, subject)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) && attempt.LockedUntil.After(unlockAt) {
+			unlockAt = *attempt.LockedUntil
+		}
+	}
+	if !unlockAt.IsZero() {
+		return &LockedError{UnlockAt: unlockAt}
+	}
+	return nil
+}
+
+// failed counts a failed login against the username and the client IP.
+func (l *lockout) failed(ctx context.Context, username, clientIP string) error {
+	if err := l.count(ctx, userSubject(username), l.policy.MaxFailures); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	return l.count(ctx, ipSubject(clientIP), l.policy.IPMaxFailures)
+}
+
+// reset clears the failures of the username, after a successful login or
+// when an admin unlocks it. Those of the client IP stay, so one valid account
+// does not reset the count of a guessing client.
+func (l *lockout) reset(ctx context.Context, username string) error {
+	return l.storage.DeleteLoginAttempt(ctx, userSubject(username))
+}
+
+// prune deletes the attempts whose failures are forgotten already, see
+// count, so the usernames and IPs that failed once do not pile up.
+func (l *lockout) prune(ctx context.Context) error {
+	return l.storage.DeleteExpiredLoginAttempts(ctx, l.now().Add(-l.policy.Window))
+}
+
+// PruneLoginAttempts deletes the forgotten login attempts every lockout
+// window until ctx is done. Failed prunes are logged and retried at the next
+// tick.
+func PruneLoginAttempts(ctx context.Context, storage LoginAttemptStorage, policy app.Lockout, logger *slog.Logger) {
+	l := newLockout(storage, policy)
+	ticker := time.NewTicker(l.policy.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.prune(ctx); err != nil {
+				logger.ErrorContext(ctx, "prune login attempts: "+err.Error())
+			}
+		}
+	}
+}
+
+// count adds a failure to subject and locks it from the maxFailures-th one.
+// Failures are forgotten once Window passes after the last failure or the end
+// of the lock, whichever is later; until then each further failure doubles
+// the lock. The storage applies concurrent counts of a subject one after
+// another, so parallel failed logins cannot overwrite each other's failures.
+func (l *lockout) count(ctx context.Context, subject string, maxFailures int) error {
+	return l.storage.UpdateLoginAttempt(ctx, subject, func(attempt *LoginAttemptModel) {
+		now := l.now()
+		last := attempt.LastFailedAt
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(last) {
+			last = *attempt.LockedUntil
+		}
+		if now.Sub(last) > l.policy.Window {
+			attempt.Failures = 0
+			attempt.LockedUntil = nil
+		}
+
+		attempt.Failures++
+		attempt.LastFailedAt = now
+		if attempt.Failures >= maxFailures {
+			lock := l.policy.LockDuration << min(attempt.Failures-maxFailures, 30)
+			if lock <= 0 || lock > l.policy.MaxLockDuration {
+				lock = l.policy.MaxLockDuration
+			}
+			lockedUntil := now.Add(lock)
+			attempt.LockedUntil = &lockedUntil
+		}
+	})
+}
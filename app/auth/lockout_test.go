@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"go-restapi/app"
+	"go-restapi/app/user"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLockout(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	policy := app.Lockout{MaxFailures: 2, Window: 10 * time.Minute, LockDuration: time.Minute, MaxLockDuration: 3 * time.Minute}
+
+	newTestLockout := func() (*lockout, *fakeLoginAttemptStorage) {
+		storage := newFakeLoginAttemptStorage()
+		l := newLockout(storage, policy)
+		l.now = func() time.Time { return now }
+		return l, storage
+	}
+
+	t.Run("Should double the lock up to the max", func(t *testing.T) {
+		l, storage := newTestLockout()
+		ctx := context.Background()
+		want := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute}
+		for _, lock := range want {
+			assert.NoError(t, l.failed(ctx, "admin", ""))
+			attempt := storage.attempts[userSubject("admin")]
+			if lock == 0 {
+				assert.Nil(t, attempt.LockedUntil)
+				continue
+			}
+			assert.Equal(t, now.Add(lock), *attempt.LockedUntil)
+		}
+
+		err := l.check(ctx, "ADMIN", "")
+		var locked *LockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.Equal(t, now.Add(3*time.Minute), locked.RetryAt())
+	})
+
+	t.Run("Should forget failures after the window", func(t *testing.T) {
+		l, storage := newTestLockout()
+		ctx := context.Background()
+		assert.NoError(t, l.failed(ctx, "admin", ""))
+
+		now = now.Add(11 * time.Minute)
+		assert.NoError(t, l.failed(ctx, "admin", ""))
+		assert.Equal(t, 1, storage.attempts[userSubject("admin")].Failures)
+		assert.NoError(t, l.check(ctx, "admin", ""))
+	})
+
+	t.Run("Should unlock once the lock ends", func(t *testing.T) {
+		l, _ := newTestLockout()
+		ctx := context.Background()
+		assert.NoError(t, l.failed(ctx, "admin", ""))
+		assert.NoError(t, l.failed(ctx, "admin", ""))
+		assert.Error(t, l.check(ctx, "admin", ""))
+
+		now = now.Add(time.Minute + time.Second)
+		assert.NoError(t, l.check(ctx, "admin", ""))
+	})
+
+	t.Run("Should count parallel failures", func(t *testing.T) {
+		l, storage := newTestLockout()
+		ctx := context.Background()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, l.failed(ctx, "admin", "192.0.2.1"))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 20, storage.attempts[userSubject("admin")].Failures)
+		assert.Equal(t, 20, storage.attempts[ipSubject("192.0.2.1")].Failures)
+		var locked *LockedError
+		assert.ErrorAs(t, l.check(ctx, "admin", "192.0.2.1"), &locked)
+	})
+
+	t.Run("Should prune forgotten attempts only", func(t *testing.T) {
+		l, storage := newTestLockout()
+		ctx := context.Background()
+		assert.NoError(t, l.failed(ctx, "old", ""))
+		assert.NoError(t, l.failed(ctx, "locked", ""))
+		assert.NoError(t, l.failed(ctx, "locked", ""))
+
+		now = now.Add(10*time.Minute + 30*time.Second)
+		assert.NoError(t, l.failed(ctx, "recent", ""))
+		assert.NoError(t, l.prune(ctx))
+
+		assert.NotContains(t, storage.attempts, userSubject("old"))
+		assert.Contains(t, storage.attempts, userSubject("locked"))
+		assert.Contains(t, storage.attempts, userSubject("recent"))
+	})
+
+	t.Run("Should fill the defaults", func(t *testing.T) {
+		l := newLockout(newFakeLoginAttemptStorage(), app.Lockout{})
+		assert.Equal(t, DefaultLockout, l.policy)
+	})
+}
+
+func TestPasswordLimiter(t *testing.T) {
+	userStroage := &mockUserStorage{}
+	userStroage.On("GetUserByID", mock.Anything, 1).Return(&user.UserModel{ID: 1, Username: "admin", Password: "hash"}, nil)
+	utils := &mockUtils{}
+	utils.On("CheckPasswordHash", "wrong", "hash").Return(false)
+	loginAttemptStorage := newFakeLoginAttemptStorage()
+	limiter := NewPasswordLimiter(loginAttemptStorage, app.Lockout{MaxFailures: 3})
+	service := user.NewUserService(userStroage, nil, nil, limiter, utils)
+	req := user.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password", ClientIP: "192.0.2.1"}
+
+	for i := 0; i < 3; i++ {
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.ErrorIs(t, err, user.ErrCurrentPasswordNotMatch)
+	}
+
+	err := service.ChangePassword(context.Background(), 1, req)
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	var locked *LockedError
+	assert.ErrorAs(t, err, &locked)
+	assert.True(t, locked.RetryAt().After(time.Now()))
+	utils.AssertNumberOfCalls(t, "CheckPasswordHash", 3)
+
+	// The failures are those of the login, so the login is locked as well.
+	_, err = newMFAService(newFakeMFAStorage(), loginAttemptStorage, app.Auth{}).Login(context.Background(), AuthRequest{Username: "admin", Password: "password"})
+	assert.ErrorIs(t, err, ErrAccountLocked)
+}
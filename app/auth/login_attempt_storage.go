@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptStorage interface {
+	GetLoginAttempt(ctx context.Context, subject string) (*LoginAttemptModel, error)
+	UpdateLoginAttempt(ctx context.Context, subject string, update func(attempt *LoginAttemptModel)) error
+	DeleteLoginAttempt(ctx context.Context, subject string) error
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+}
+
+type loginAttemptStorage struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptStorage(db *gorm.DB) LoginAttemptStorage {
+	return &loginAttemptStorage{
+		db: db,
+	}
+}
+
+func (s *loginAttemptStorage) GetLoginAttempt(ctx context.Context, subject string) (*LoginAttemptModel, error) {
+	var attempt LoginAttemptModel
+	if err := s.db.WithContext(ctx).Table(LoginAttemptTableName).Where("subject = ?", subject).First(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// UpdateLoginAttempt applies update to the attempt of subject, a new one
+// without failures when there is none, and saves the result. The row is
+// locked from read to write, so concurrent updates of a subject apply one
+// after another instead of overwriting each other.
+func (s *loginAttemptStorage) UpdateLoginAttempt(ctx context.Context, subject string, update func(attempt *LoginAttemptModel)) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Creating the row first gives the first failures of a subject a row
+		// to lock as well.
+		q := tx.Table(LoginAttemptTableName).Clauses(clause.OnConflict{DoNothing: true}).Create(&LoginAttemptModel{Subject: subject, LastFailedAt: time.Now()})
+		if q.Error != nil {
+			return q.Error
+		}
+
+		// SQLite has no FOR UPDATE; the write above already holds its
+		// database lock until the transaction ends.
+		q = tx.Table(LoginAttemptTableName).Where("subject = ?", subject)
+		if tx.Dialector.Name() != "sqlite" {
+			q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var attempt LoginAttemptModel
+		if err := q.First(&attempt).Error; err != nil {
+			return err
+		}
+
+		update(&attempt)
+		attempt.Subject = subject
+		return tx.Table(LoginAttemptTableName).Save(&attempt).Error
+	})
+}
+
+func (s *loginAttemptStorage) DeleteLoginAttempt(ctx context.Context, subject string) error {
+	q := s.db.WithContext(ctx).Table(LoginAttemptTableName).Where("subject = ?", subject).Delete(&LoginAttemptModel{})
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+// DeleteExpiredLoginAttempts removes the attempts whose last failure and lock
+// both ended before before.
+func (s *loginAttemptStorage) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	q := s.db.WithContext(ctx).Table(LoginAttemptTableName).
+		Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&LoginAttemptModel{})
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
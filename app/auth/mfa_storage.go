@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MFAStorage keeps the TOTP secrets, recovery codes and pending challenges
+// of the second factor.
+type MFAStorage interface {
+	GetMFA(ctx context.Context, userID int) (*MFAModel, error)
+	SaveMFA(ctx context.Context, mfa MFAModel) error
+	EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error
+	UseMFAStep(ctx context.Context, userID int, step int64) error
+	DeleteMFA(ctx context.Context, userID int) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CreateMFAChallenge(ctx context.Context, challenge MFAChallengeModel) error
+	GetMFAChallengeByHash(ctx context.Context, tokenHash string) (*MFAChallengeModel, error)
+	UseMFAChallenge(ctx context.Context, tokenHash string) error
+}
+
+type mfaStorage struct {
+	db *gorm.DB
+}
+
+func NewMFAStorage(db *gorm.DB) MFAStorage {
+	return &mfaStorage{
+		db: db,
+	}
+}
+
+func (s *mfaStorage) GetMFA(ctx context.Context, userID int) (*MFAModel, error) {
+	var mfa MFAModel
+	if err := s.db.WithContext(ctx).Table(MFATableName).Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// SaveMFA creates the secret of the user or replaces it, with its enabled
+// state and last used step.
+func (s *mfaStorage) SaveMFA(ctx context.Context, mfa MFAModel) error {
+	q := s.db.WithContext(ctx).Table(MFATableName).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_used_step", "updated_at"}),
+	}).Create(&mfa)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+// EnableMFA enables the pending secret of the user, confirmed by the code of
+// step, and replaces the recovery codes. It fails with gorm.ErrRecordNotFound
+// when there is no pending secret.
+func (s *mfaStorage) EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Table(MFATableName).Where("user_id = ? AND enabled_at IS NULL", userID).
+			Updates(map[string]interface{}{"enabled_at": time.Now(), "last_used_step": step, "updated_at": time.Now()})
+		if q.Error != nil {
+			return q.Error
+		}
+		if q.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Table(RecoveryCodeTableName).Where("user_id = ?", userID).Delete(&RecoveryCodeModel{}).Error; err != nil {
+			return err
+		}
+		codes := make([]RecoveryCodeModel, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = RecoveryCodeModel{UserID: userID, CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Table(RecoveryCodeTableName).Create(&codes).Error
+	})
+}
+
+// UseMFAStep records step as used. It fails with gorm.ErrRecordNotFound when
+// MFA is not enabled or a code of step, or of a later one, was already used,
+// so every code succeeds only once.
+func (s *mfaStorage) UseMFAStep(ctx context.Context, userID int, step int64) error {
+	q := s.db.WithContext(ctx).Table(MFATableName).Where("user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at": time.Now()})
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMFA removes the secret and the recovery codes of the user.
+func (s *mfaStorage) DeleteMFA(ctx context.Context, userID int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(RecoveryCodeTableName).Where("user_id = ?", userID).Delete(&RecoveryCodeModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Table(MFATableName).Where("user_id = ?", userID).Delete(&MFAModel{}).Error
+	})
+}
+
+// UseRecoveryCode marks the code used. It fails with gorm.ErrRecordNotFound
+// when the code is missing or already used.
+func (s *mfaStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	q := s.db.WithContext(ctx).Table(RecoveryCodeTableName).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).Update("used_at", time.Now())
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *mfaStorage) CreateMFAChallenge(ctx context.Context, challenge MFAChallengeModel) error {
+	q := s.db.WithContext(ctx).Table(MFAChallengeTableName).Create(&challenge)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *mfaStorage) GetMFAChallengeByHash(ctx context.Context, tokenHash string) (*MFAChallengeModel, error) {
+	var challenge MFAChallengeModel
+	if err := s.db.WithContext(ctx).Table(MFAChallengeTableName).Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// UseMFAChallenge marks the challenge used. It fails with
+// gorm.ErrRecordNotFound when the challenge is missing or already used.
+func (s *mfaStorage) UseMFAChallenge(ctx context.Context, tokenHash string) error {
+	q := s.db.WithContext(ctx).Table(MFAChallengeTableName).Where("token_hash = ? AND used_at IS NULL", tokenHash).Update("used_at", time.Now())
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
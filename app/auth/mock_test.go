@@ -1,12 +1,18 @@
 package auth
 
 import (
-	"crypto/rsa"
+	"context"
 	"go-restapi/app"
+	"go-restapi/app/keys"
+	"go-restapi/app/role"
 	"go-restapi/app/user"
+	"go-restapi/mail"
 	"go-restapi/utils"
+	"sync"
+	"time"
 
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 type mockUserStorage struct {
@@ -14,14 +20,27 @@ type mockUserStorage struct {
 	user.UserStorage
 }
 
-func (m *mockUserStorage) GetUserByUsername(username string) (*user.UserModel, error) {
-	args := m.Called(username)
+func (m *mockUserStorage) GetUserByUsername(ctx context.Context, username string) (*user.UserModel, error) {
+	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*user.UserModel), args.Error(1)
 }
 
+func (m *mockUserStorage) GetUserByID(ctx context.Context, id int) (*user.UserModel, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserModel), args.Error(1)
+}
+
+func (m *mockUserStorage) UpdateUser(ctx context.Context, u user.UserModel) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
 // ----------------------------
 
 type mockRefreshTokenStorage struct {
@@ -29,34 +48,364 @@ type mockRefreshTokenStorage struct {
 	RefreshTokenStorage
 }
 
-func (m *mockRefreshTokenStorage) GetRefreshTokenByToken(token string) (*RefreshTokenModel, error) {
-	args := m.Called(token)
+func (m *mockRefreshTokenStorage) GetRefreshTokenByToken(ctx context.Context, token string) (*RefreshTokenModel, error) {
+	args := m.Called(ctx, token)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*RefreshTokenModel), args.Error(1)
 }
 
-func (m *mockRefreshTokenStorage) UpsertRefreshToken(refreshToken RefreshTokenModel) error {
-	args := m.Called(refreshToken)
+func (m *mockRefreshTokenStorage) CreateRefreshToken(ctx context.Context, refreshToken RefreshTokenModel) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *mockRefreshTokenStorage) RotateRefreshToken(ctx context.Context, oldToken string, refreshToken RefreshTokenModel) error {
+	args := m.Called(ctx, oldToken, refreshToken)
+	return args.Error(0)
+}
+
+func (m *mockRefreshTokenStorage) RevokeRefreshToken(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockRefreshTokenStorage) RevokeRefreshTokensByUserID(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
 	return args.Error(0)
 }
 
 // ----------------------------
 
+type mockRoleStorage struct {
+	mock.Mock
+	role.RoleStorage
+}
+
+func (m *mockRoleStorage) GetRolesByUserID(ctx context.Context, userID int) ([]role.RoleModel, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]role.RoleModel), args.Error(1)
+}
+
+func (m *mockRoleStorage) GetPermissionsByUserID(ctx context.Context, userID int) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func newMockRoleStorage() *mockRoleStorage {
+	m := &mockRoleStorage{}
+	m.On("GetRolesByUserID", mock.Anything, mock.Anything).Return([]role.RoleModel{{ID: 1, Name: "admin"}}, nil)
+	m.On("GetPermissionsByUserID", mock.Anything, mock.Anything).Return([]string{role.PermissionBooksRead}, nil)
+	return m
+}
+
+// ----------------------------
+
+type fakeLoginAttemptStorage struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttemptModel
+}
+
+func newFakeLoginAttemptStorage() *fakeLoginAttemptStorage {
+	return &fakeLoginAttemptStorage{attempts: map[string]LoginAttemptModel{}}
+}
+
+func (f *fakeLoginAttemptStorage) GetLoginAttempt(ctx context.Context, subject string) (*LoginAttemptModel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	attempt, ok := f.attempts[subject]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &attempt, nil
+}
+
+func (f *fakeLoginAttemptStorage) UpdateLoginAttempt(ctx context.Context, subject string, update func(attempt *LoginAttemptModel)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	attempt, ok := f.attempts[subject]
+	if !ok {
+		attempt = LoginAttemptModel{Subject: subject}
+	}
+	update(&attempt)
+	f.attempts[subject] = attempt
+	return nil
+}
+
+func (f *fakeLoginAttemptStorage) DeleteLoginAttempt(ctx context.Context, subject string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.attempts, subject)
+	return nil
+}
+
+func (f *fakeLoginAttemptStorage) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for subject, attempt := range f.attempts {
+		if attempt.LastFailedAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(f.attempts, subject)
+		}
+	}
+	return nil
+}
+
+// ----------------------------
+
+type fakeResetTokenStorage struct {
+	tokens map[string]ResetTokenModel
+	err    error
+}
+
+func newFakeResetTokenStorage() *fakeResetTokenStorage {
+	return &fakeResetTokenStorage{tokens: map[string]ResetTokenModel{}}
+}
+
+func (f *fakeResetTokenStorage) CreateResetToken(ctx context.Context, resetToken ResetTokenModel) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.tokens[resetToken.TokenHash] = resetToken
+	return nil
+}
+
+func (f *fakeResetTokenStorage) GetResetTokenByHash(ctx context.Context, tokenHash string) (*ResetTokenModel, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	rt, ok := f.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rt, nil
+}
+
+func (f *fakeResetTokenStorage) UseResetToken(ctx context.Context, tokenHash string) error {
+	rt, ok := f.tokens[tokenHash]
+	if !ok || rt.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	rt.UsedAt = &now
+	f.tokens[tokenHash] = rt
+	return nil
+}
+
+func (f *fakeResetTokenStorage) UseResetTokensByUserID(ctx context.Context, userID int) error {
+	if f.err != nil {
+		return f.err
+	}
+	now := time.Now()
+	for hash, rt := range f.tokens {
+		if rt.UserID == userID && rt.UsedAt == nil {
+			rt.UsedAt = &now
+			f.tokens[hash] = rt
+		}
+	}
+	return nil
+}
+
+// ----------------------------
+
+type fakeMFAStorage struct {
+	mfa        map[int]MFAModel
+	codes      map[int]map[string]bool
+	challenges map[string]MFAChallengeModel
+}
+
+func newFakeMFAStorage() *fakeMFAStorage {
+	return &fakeMFAStorage{mfa: map[int]MFAModel{}, codes: map[int]map[string]bool{}, challenges: map[string]MFAChallengeModel{}}
+}
+
+func (f *fakeMFAStorage) GetMFA(ctx context.Context, userID int) (*MFAModel, error) {
+	m, ok := f.mfa[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &m, nil
+}
+
+func (f *fakeMFAStorage) SaveMFA(ctx context.Context, mfa MFAModel) error {
+	f.mfa[mfa.UserID] = mfa
+	return nil
+}
+
+func (f *fakeMFAStorage) EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	m, ok := f.mfa[userID]
+	if !ok || m.EnabledAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	m.EnabledAt = &now
+	m.LastUsedStep = step
+	f.mfa[userID] = m
+	f.codes[userID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		f.codes[userID][hash] = true
+	}
+	return nil
+}
+
+func (f *fakeMFAStorage) UseMFAStep(ctx context.Context, userID int, step int64) error {
+	m, ok := f.mfa[userID]
+	if !ok || m.EnabledAt == nil || m.LastUsedStep >= step {
+		return gorm.ErrRecordNotFound
+	}
+	m.LastUsedStep = step
+	f.mfa[userID] = m
+	return nil
+}
+
+func (f *fakeMFAStorage) DeleteMFA(ctx context.Context, userID int) error {
+	delete(f.mfa, userID)
+	delete(f.codes, userID)
+	return nil
+}
+
+func (f *fakeMFAStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	if !f.codes[userID][codeHash] {
+		return gorm.ErrRecordNotFound
+	}
+	f.codes[userID][codeHash] = false
+	return nil
+}
+
+func (f *fakeMFAStorage) CreateMFAChallenge(ctx context.Context, challenge MFAChallengeModel) error {
+	f.challenges[challenge.TokenHash] = challenge
+	return nil
+}
+
+func (f *fakeMFAStorage) GetMFAChallengeByHash(ctx context.Context, tokenHash string) (*MFAChallengeModel, error) {
+	c, ok := f.challenges[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &c, nil
+}
+
+func (f *fakeMFAStorage) UseMFAChallenge(ctx context.Context, tokenHash string) error {
+	c, ok := f.challenges[tokenHash]
+	if !ok || c.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	f.challenges[tokenHash] = c
+	return nil
+}
+
+// ----------------------------
+
+type fakeMailer struct {
+	sent []mail.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, msg mail.Message) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// ----------------------------
+
 type mockAuthService struct {
 	mock.Mock
 	AuthService
 }
 
-func (m *mockAuthService) Login(req AuthRequest) (*AuthResponse, error) {
-	args := m.Called(req)
+func (m *mockAuthService) Login(ctx context.Context, req AuthRequest) (*LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LoginResponse), args.Error(1)
+}
+
+func (m *mockAuthService) RefreshToken(ctx context.Context, req RefreshTokenRequest) (*AuthResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AuthResponse), args.Error(1)
+}
+
+func (m *mockAuthService) Logout(ctx context.Context, req LogoutRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) UnlockUser(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) VerifyMFA(ctx context.Context, req MFALoginRequest) (*AuthResponse, error) {
+	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*AuthResponse), args.Error(1)
 }
 
+func (m *mockAuthService) EnrollMFAChallenge(ctx context.Context, req MFAChallengeRequest) (*MFAEnrollResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAEnrollResponse), args.Error(1)
+}
+
+func (m *mockAuthService) EnrollMFA(ctx context.Context, userID int) (*MFAEnrollResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAEnrollResponse), args.Error(1)
+}
+
+func (m *mockAuthService) ConfirmMFA(ctx context.Context, userID int, req MFACodeRequest) (*MFAConfirmResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAConfirmResponse), args.Error(1)
+}
+
+func (m *mockAuthService) DisableMFA(ctx context.Context, userID int, req MFACodeRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+type mockSigner struct {
+	mock.Mock
+}
+
+func (m *mockSigner) SigningKey() (*keys.Key, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*keys.Key), args.Error(1)
+}
+
 // ----------------------------
 
 type mockUtils struct {
@@ -69,16 +418,8 @@ func (m *mockUtils) CheckPasswordHash(hashPwd, pwd string) bool {
 	return args.Bool(0)
 }
 
-func (m *mockUtils) GetPrivateKey() (*rsa.PrivateKey, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*rsa.PrivateKey), args.Error(1)
-}
-
-func (m *mockUtils) GetAccessToken(privateKey *rsa.PrivateKey, data app.TokenData, expireHour int) (string, int64, error) {
-	args := m.Called(privateKey, data, expireHour)
+func (m *mockUtils) GetAccessToken(key *keys.Key, data app.TokenData, conf app.Auth) (string, int64, error) {
+	args := m.Called(key, data, conf)
 	if args.Get(0) == nil {
 		return "", 0, args.Error(2)
 	}
@@ -89,3 +430,8 @@ func (m *mockUtils) GetUUID() string {
 	args := m.Called()
 	return args.String(0)
 }
+
+func (m *mockUtils) HashPassword(password string) (string, error) {
+	args := m.Called(password)
+	return args.String(0), args.Error(1)
+}
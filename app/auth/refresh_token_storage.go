@@ -1,10 +1,18 @@
 package auth
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type RefreshTokenStorage interface {
-	GetRefreshTokenByToken(token string) (*RefreshTokenModel, error)
-	UpsertRefreshToken(refreshToken RefreshTokenModel) error
+	GetRefreshTokenByToken(ctx context.Context, token string) (*RefreshTokenModel, error)
+	CreateRefreshToken(ctx context.Context, refreshToken RefreshTokenModel) error
+	RotateRefreshToken(ctx context.Context, oldToken string, refreshToken RefreshTokenModel) error
+	RevokeRefreshToken(ctx context.Context, token string) error
+	RevokeRefreshTokensByUserID(ctx context.Context, userID int) error
 }
 
 type refreshTokenStorage struct {
@@ -17,24 +25,45 @@ func NewRefreshTokenStorage(db *gorm.DB) RefreshTokenStorage {
 	}
 }
 
-func (s *refreshTokenStorage) GetRefreshTokenByToken(token string) (*RefreshTokenModel, error) {
+func (s *refreshTokenStorage) GetRefreshTokenByToken(ctx context.Context, token string) (*RefreshTokenModel, error) {
 	var refreshToken RefreshTokenModel
-	if err := s.db.Debug().Table(RefreshTokenTableName).Where("token = ?", token).First(&refreshToken).Error; err != nil {
+	if err := s.db.WithContext(ctx).Table(RefreshTokenTableName).Where("token = ?", token).First(&refreshToken).Error; err != nil {
 		return nil, err
 	}
 	return &refreshToken, nil
 }
 
-func (s *refreshTokenStorage) UpsertRefreshToken(refreshToken RefreshTokenModel) error {
-	q := s.db.Debug().Table(RefreshTokenTableName).Where("user_id = ?", refreshToken.UserID).Updates(refreshToken)
+func (s *refreshTokenStorage) CreateRefreshToken(ctx context.Context, refreshToken RefreshTokenModel) error {
+	q := s.db.WithContext(ctx).Table(RefreshTokenTableName).Create(&refreshToken)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *refreshTokenStorage) RotateRefreshToken(ctx context.Context, oldToken string, refreshToken RefreshTokenModel) error {
+	q := s.db.WithContext(ctx).Table(RefreshTokenTableName).Where("token = ? AND revoked_at IS NULL", oldToken).Updates(refreshToken)
 	if q.Error != nil {
 		return q.Error
 	}
 	if q.RowsAffected == 0 {
-		q := s.db.Debug().Table(RefreshTokenTableName).Create(&refreshToken)
-		if q.Error != nil {
-			return q.Error
-		}
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *refreshTokenStorage) RevokeRefreshToken(ctx context.Context, token string) error {
+	q := s.db.WithContext(ctx).Table(RefreshTokenTableName).Where("token = ? AND revoked_at IS NULL", token).Update("revoked_at", time.Now())
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *refreshTokenStorage) RevokeRefreshTokensByUserID(ctx context.Context, userID int) error {
+	q := s.db.WithContext(ctx).Table(RefreshTokenTableName).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now())
+	if q.Error != nil {
+		return q.Error
 	}
 	return nil
 }
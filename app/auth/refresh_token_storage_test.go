@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -54,7 +55,7 @@ func (s *testRefreshTokenStorageSuite) TestGetRefreshTokenByToken() {
 				NewRows([]string{"id", "user_id", "token", "expired_at", "created_at", "created_by", "updated_at", "updated_by"}).
 				AddRow(s.data.ID, s.data.UserID, s.data.Token, s.data.ExpiredAt, s.data.CreatedAt, s.data.CreatedBy, s.data.UpdatedAt, s.data.UpdatedBy))
 		storage := NewRefreshTokenStorage(s.gormDB)
-		got, err := storage.GetRefreshTokenByToken(s.data.Token)
+		got, err := storage.GetRefreshTokenByToken(context.Background(), s.data.Token)
 		s.NoError(err)
 		s.Equal(&s.data, got)
 	})
@@ -62,48 +63,106 @@ func (s *testRefreshTokenStorageSuite) TestGetRefreshTokenByToken() {
 	s.Run("Should return error", func() {
 		s.mock.ExpectQuery(`SELECT`).WithArgs(s.data.Token).WillReturnError(sql.ErrNoRows)
 		storage := NewRefreshTokenStorage(s.gormDB)
-		got, err := storage.GetRefreshTokenByToken(s.data.Token)
+		got, err := storage.GetRefreshTokenByToken(context.Background(), s.data.Token)
 		s.Error(err)
 		s.Nil(got)
 	})
 }
 
-func (s *testRefreshTokenStorageSuite) TestUpsertRefreshToken() {
-	defer func() {
-		_ = s.mock.ExpectationsWereMet()
-	}()
+func (s *testRefreshTokenStorageSuite) TestCreateRefreshToken() {
+	s.Run("Should return nil", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("INSERT").WillReturnResult(sqlmock.NewResult(1, 1))
+		s.mock.ExpectCommit()
+
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.CreateRefreshToken(context.Background(), s.data)
+		s.NoError(err)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("INSERT").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.CreateRefreshToken(context.Background(), s.data)
+		s.Error(err)
+	})
+}
 
+func (s *testRefreshTokenStorageSuite) TestRotateRefreshToken() {
 	s.Run("Should return nil", func() {
 		s.mock.ExpectBegin()
 		s.mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
 		s.mock.ExpectCommit()
 
 		storage := NewRefreshTokenStorage(s.gormDB)
-		err := storage.UpsertRefreshToken(s.data)
+		err := storage.RotateRefreshToken(context.Background(), "old-token", s.data)
 		s.NoError(err)
 	})
 
-	s.Run("Should return error when insert", func() {
+	s.Run("Should return record not found when token already rotated", func() {
 		s.mock.ExpectBegin()
-		s.mock.ExpectExec(`UPDATE`).WillReturnResult(sqlmock.NewResult(0, 0))
+		s.mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
 		s.mock.ExpectCommit()
 
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.RotateRefreshToken(context.Background(), "old-token", s.data)
+		s.ErrorIs(err, gorm.ErrRecordNotFound)
+	})
+
+	s.Run("Should return error", func() {
 		s.mock.ExpectBegin()
-		s.mock.ExpectExec(`INSERT`).WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectExec("UPDATE").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.RotateRefreshToken(context.Background(), "old-token", s.data)
+		s.Error(err)
+	})
+}
+
+func (s *testRefreshTokenStorageSuite) TestRevokeRefreshToken() {
+	s.Run("Should return nil", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("UPDATE").WithArgs(sqlmock.AnyArg(), s.data.Token).WillReturnResult(sqlmock.NewResult(0, 1))
 		s.mock.ExpectCommit()
 
 		storage := NewRefreshTokenStorage(s.gormDB)
-		err := storage.UpsertRefreshToken(s.data)
+		err := storage.RevokeRefreshToken(context.Background(), s.data.Token)
+		s.NoError(err)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("UPDATE").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.RevokeRefreshToken(context.Background(), s.data.Token)
 		s.Error(err)
 	})
+}
 
-	s.Run("Should return error when update", func() {
+func (s *testRefreshTokenStorageSuite) TestRevokeRefreshTokensByUserID() {
+	s.Run("Should return nil", func() {
 		s.mock.ExpectBegin()
-		s.mock.ExpectExec(`UPDATE`).WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectExec("UPDATE").WithArgs(sqlmock.AnyArg(), s.data.UserID).WillReturnResult(sqlmock.NewResult(0, 3))
 		s.mock.ExpectCommit()
 
 		storage := NewRefreshTokenStorage(s.gormDB)
-		err := storage.UpsertRefreshToken(s.data)
+		err := storage.RevokeRefreshTokensByUserID(context.Background(), s.data.UserID)
+		s.NoError(err)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("UPDATE").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRefreshTokenStorage(s.gormDB)
+		err := storage.RevokeRefreshTokensByUserID(context.Background(), s.data.UserID)
 		s.Error(err)
 	})
 }
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"go-restapi/app"
+	"go-restapi/mail"
+	"net/url"
+)
+
+const resetMailSubject = "Reset your password"
+
+// resetMessage mails token to the user, as a link to conf.URL when it is set.
+func resetMessage(to, token string, conf app.PasswordReset) (mail.Message, error) {
+	reset := "Use this token to reset your password: " + token
+	if conf.URL != "" {
+		u, err := url.Parse(conf.URL)
+		if err != nil {
+			return mail.Message{}, err
+		}
+		q := u.Query()
+		q.Set("token", token)
+		u.RawQuery = q.Encode()
+		reset = "Open this link to reset your password: " + u.String()
+	}
+
+	body := "We received a request to reset the password of your account.\n\n" +
+		reset + "\n\n" +
+		"It expires in " + conf.TokenTTL.String() + " and works once. If you did not ask for it, you can ignore this mail.\n"
+	return mail.Message{To: to, Subject: resetMailSubject, Body: body}, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ResetTokenStorage interface {
+	CreateResetToken(ctx context.Context, resetToken ResetTokenModel) error
+	GetResetTokenByHash(ctx context.Context, tokenHash string) (*ResetTokenModel, error)
+	UseResetToken(ctx context.Context, tokenHash string) error
+	UseResetTokensByUserID(ctx context.Context, userID int) error
+}
+
+type resetTokenStorage struct {
+	db *gorm.DB
+}
+
+func NewResetTokenStorage(db *gorm.DB) ResetTokenStorage {
+	return &resetTokenStorage{
+		db: db,
+	}
+}
+
+func (s *resetTokenStorage) CreateResetToken(ctx context.Context, resetToken ResetTokenModel) error {
+	q := s.db.WithContext(ctx).Table(ResetTokenTableName).Create(&resetToken)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *resetTokenStorage) GetResetTokenByHash(ctx context.Context, tokenHash string) (*ResetTokenModel, error) {
+	var resetToken ResetTokenModel
+	if err := s.db.WithContext(ctx).Table(ResetTokenTableName).Where("token_hash = ?", tokenHash).First(&resetToken).Error; err != nil {
+		return nil, err
+	}
+	return &resetToken, nil
+}
+
+// UseResetToken marks the token used. It fails with gorm.ErrRecordNotFound
+// when the token is missing or already used, so it succeeds only once.
+func (s *resetTokenStorage) UseResetToken(ctx context.Context, tokenHash string) error {
+	q := s.db.WithContext(ctx).Table(ResetTokenTableName).Where("token_hash = ? AND used_at IS NULL", tokenHash).Update("used_at", time.Now())
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UseResetTokensByUserID marks every unused token of the user used, so no
+// reset link sent before a password change still works.
+func (s *resetTokenStorage) UseResetTokensByUserID(ctx context.Context, userID int) error {
+	q := s.db.WithContext(ctx).Table(ResetTokenTableName).Where("user_id = ? AND used_at IS NULL", userID).Update("used_at", time.Now())
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
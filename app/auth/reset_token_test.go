@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"go-restapi/app"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResetMessage(t *testing.T) {
+	t.Run("Should link to the reset page", func(t *testing.T) {
+		msg, err := resetMessage("a@example.com", "abc", app.PasswordReset{TokenTTL: 30 * time.Minute, URL: "https://app.example.com/reset?lang=en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "a@example.com", msg.To)
+		assert.Equal(t, resetMailSubject, msg.Subject)
+		assert.Contains(t, msg.Body, "https://app.example.com/reset?lang=en&token=abc")
+		assert.Contains(t, msg.Body, "30m0s")
+	})
+
+	t.Run("Should mail the token alone without a reset page", func(t *testing.T) {
+		msg, err := resetMessage("a@example.com", "abc", app.PasswordReset{TokenTTL: time.Hour})
+		assert.NoError(t, err)
+		assert.Contains(t, msg.Body, "Use this token to reset your password: abc\n")
+	})
+}
+
+func TestTokenRevoker(t *testing.T) {
+	refreshTokenStorage := &mockRefreshTokenStorage{}
+	refreshTokenStorage.On("RevokeRefreshTokensByUserID", mock.Anything, 1).Return(nil)
+	resetTokenStorage := newFakeResetTokenStorage()
+	resetTokenStorage.tokens["a"] = ResetTokenModel{UserID: 1, TokenHash: "a", ExpiredAt: time.Now().Add(time.Hour)}
+
+	err := NewTokenRevoker(refreshTokenStorage, resetTokenStorage).RevokeTokensByUserID(context.Background(), 1)
+	assert.NoError(t, err)
+	refreshTokenStorage.AssertExpectations(t)
+	assert.NotNil(t, resetTokenStorage.tokens["a"].UsedAt)
+}
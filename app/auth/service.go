@@ -1,85 +1,745 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"go-restapi/app"
+	"go-restapi/app/keys"
+	"go-restapi/app/role"
 	"go-restapi/app/user"
+	"go-restapi/mail"
+	"go-restapi/metrics"
+	"go-restapi/totp"
 	"go-restapi/utils"
+	"log/slog"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Login(req AuthRequest) (*AuthResponse, error)
+	Login(ctx context.Context, req AuthRequest) (*LoginResponse, error)
+	RefreshToken(ctx context.Context, req RefreshTokenRequest) (*AuthResponse, error)
+	Logout(ctx context.Context, req LogoutRequest) error
+	UnlockUser(ctx context.Context, userID int) error
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) error
+	VerifyMFA(ctx context.Context, req MFALoginRequest) (*AuthResponse, error)
+	EnrollMFAChallenge(ctx context.Context, req MFAChallengeRequest) (*MFAEnrollResponse, error)
+	EnrollMFA(ctx context.Context, userID int) (*MFAEnrollResponse, error)
+	ConfirmMFA(ctx context.Context, userID int, req MFACodeRequest) (*MFAConfirmResponse, error)
+	DisableMFA(ctx context.Context, userID int, req MFACodeRequest) error
 }
 
 type authService struct {
 	userStroage         user.UserStorage
 	refreshTokenStorage RefreshTokenStorage
+	roleStorage         role.RoleStorage
+	resetTokenStorage   ResetTokenStorage
+	mfaStorage          MFAStorage
+	lockout             *lockout
+	signer              keys.Signer
+	mailer              mail.Mailer
 	utils               utils.Utils
+	conf                app.Auth
 }
 
-func NewAuthService(userStroage user.UserStorage, refreshTokenStorage RefreshTokenStorage, utils utils.Utils) AuthService {
+// NewAuthService issues tokens by conf, whose zero values take the defaults
+// of DefaultConfig.
+func NewAuthService(userStroage user.UserStorage, refreshTokenStorage RefreshTokenStorage, roleStorage role.RoleStorage, loginAttemptStorage LoginAttemptStorage, resetTokenStorage ResetTokenStorage, mfaStorage MFAStorage, signer keys.Signer, mailer mail.Mailer, utils utils.Utils, conf app.Auth) AuthService {
+	conf = withDefaults(conf)
 	return &authService{
 		userStroage:         userStroage,
 		refreshTokenStorage: refreshTokenStorage,
+		roleStorage:         roleStorage,
+		resetTokenStorage:   resetTokenStorage,
+		mfaStorage:          mfaStorage,
+		conf:                conf,
+		lockout:             newLockout(loginAttemptStorage, conf.Lockout),
+		signer:              signer,
+		mailer:              mailer,
 		utils:               utils,
 	}
 }
 
-func (s *authService) Login(req AuthRequest) (*AuthResponse, error) {
-	u, err := s.userStroage.GetUserByUsername(req.Username)
+func (s *authService) Login(ctx context.Context, req AuthRequest) (*LoginResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.Login")
+	defer span.End()
+	res, err := s.login(ctx, req)
+	// A login waiting for its second factor is counted once VerifyMFA ends it.
+	if err != nil || res.Challenge == nil {
+		metrics.ObserveLogin(err == nil)
+	}
+	return res, err
+}
+
+func (s *authService) login(ctx context.Context, req AuthRequest) (*LoginResponse, error) {
+	if err := s.lockout.check(ctx, req.Username, req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	u, err := s.authenticate(ctx, req)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if failErr := s.lockout.failed(ctx, req.Username, req.ClientIP); failErr != nil {
+			return nil, errors.Join(err, failErr)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The failures of the username are kept until the second factor is
+	// given, so a known password does not reset the count of guessed codes.
+	purpose, err := s.mfaPurpose(ctx, int(u.ID))
+	if err != nil {
+		return nil, err
+	}
+	if purpose != "" {
+		challenge, err := s.createMFAChallenge(ctx, int(u.ID), purpose)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{Challenge: challenge}, nil
+	}
+
+	if err := s.lockout.reset(ctx, req.Username); err != nil {
+		return nil, err
+	}
+	tokens, err := s.issueTokens(ctx, u, false)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{Tokens: tokens}, nil
+}
+
+// issueTokens returns a new access token and refresh token for u.
+// mfaVerified records on the refresh token that the second factor was given.
+func (s *authService) issueTokens(ctx context.Context, u *user.UserModel, mfaVerified bool) (*AuthResponse, error) {
+	accessToken, accessTokenExpire, err := s.getAccessToken(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := s.utils.GetUUID()
+	refreshTokenExpire := time.Now().Add(s.conf.RefreshTokenTTL).Unix()
+	refreshTokenModel := RefreshTokenModel{
+		UserID:      int(u.ID),
+		Token:       refreshToken,
+		ExpiredAt:   time.Unix(refreshTokenExpire, 0),
+		MFAVerified: mfaVerified,
+		CreatedBy:   u.Username,
+		UpdatedBy:   u.Username,
+	}
+	if err := s.refreshTokenStorage.CreateRefreshToken(ctx, refreshTokenModel); err != nil {
+		return nil, err
+	}
+
+	return newAuthResponse(accessToken, accessTokenExpire, refreshToken, refreshTokenExpire), nil
+}
+
+// authenticate returns the user when the password matches. Unknown usernames
+// fail with the same ErrInvalidCredentials and count as failures too, so they
+// cannot be told apart by the response or the lockout.
+func (s *authService) authenticate(ctx context.Context, req AuthRequest) (*user.UserModel, error) {
+	u, err := s.userStroage.GetUserByUsername(ctx, req.Username)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, ErrUserNotFound
+		return nil, ErrInvalidCredentials
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	if !s.utils.CheckPasswordHash(req.Password, u.Password) {
-		return nil, ErrPasswordNotMatch
+		return nil, ErrInvalidCredentials
 	}
+	return u, nil
+}
 
-	privateKey, err := s.utils.GetPrivateKey()
+func (s *authService) RefreshToken(ctx context.Context, req RefreshTokenRequest) (*AuthResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.RefreshToken")
+	defer span.End()
+	rt, err := s.refreshTokenStorage.GetRefreshTokenByToken(ctx, req.RefreshToken)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRefreshTokenNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	tokenData := app.TokenData{
-		UserID:    int(u.ID),
-		Username:  u.Username,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      "admin",
+	if rt.RevokedAt != nil {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	if !rt.ExpiredAt.After(time.Now()) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	u, err := s.userStroage.GetUserByID(ctx, rt.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A session opened with the password alone ends once the user needs a
+	// second factor, such as after being given a role of MFA.RequiredRoles.
+	if !rt.MFAVerified {
+		purpose, err := s.mfaPurpose(ctx, int(u.ID))
+		if err != nil {
+			return nil, err
+		}
+		if purpose != "" {
+			return nil, ErrMFARequired
+		}
 	}
 
-	accessToken, accessTokenExpire, err := s.utils.GetAccessToken(privateKey, tokenData, AccessTokenExpireHour)
+	accessToken, accessTokenExpire, err := s.getAccessToken(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
+	// The rotated token keeps the expiry of the one it replaces, so a chain of
+	// refreshes ends refreshTokenTTL after the login however often it runs.
 	refreshToken := s.utils.GetUUID()
-	refreshTokenExpire := time.Now().Add(time.Hour * RefreshTokenExpireHour).Unix()
+	refreshTokenExpire := rt.ExpiredAt.Unix()
 	refreshTokenModel := RefreshTokenModel{
 		UserID:    int(u.ID),
 		Token:     refreshToken,
-		ExpiredAt: time.Unix(refreshTokenExpire, 0),
-		CreatedBy: u.Username,
+		ExpiredAt: rt.ExpiredAt,
 		UpdatedBy: u.Username,
 	}
-	if err := s.refreshTokenStorage.UpsertRefreshToken(refreshTokenModel); err != nil {
+	err = s.refreshTokenStorage.RotateRefreshToken(ctx, rt.Token, refreshTokenModel)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Another request rotated this token first.
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	accessTokenExpireAt := time.Unix(accessTokenExpire, 0).Format(FormatDateTime)
-	refreshTokenExpireAt := time.Unix(refreshTokenExpire, 0).Format(FormatDateTime)
-	res := &AuthResponse{
-		AccessToken:          accessToken,
-		AccessTokenExpireAt:  accessTokenExpireAt,
-		RefreshToken:         refreshToken,
-		RefreshTokenExpireAt: refreshTokenExpireAt,
+	return newAuthResponse(accessToken, accessTokenExpire, refreshToken, refreshTokenExpire), nil
+}
+
+func (s *authService) Logout(ctx context.Context, req LogoutRequest) error {
+	ctx, span := app.StartSpan(ctx, "AuthService.Logout")
+	defer span.End()
+	rt, err := s.refreshTokenStorage.GetRefreshTokenByToken(ctx, req.RefreshToken)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if rt.RevokedAt != nil {
+		return ErrRefreshTokenRevoked
+	}
+
+	if req.All {
+		return s.refreshTokenStorage.RevokeRefreshTokensByUserID(ctx, rt.UserID)
+	}
+	return s.refreshTokenStorage.RevokeRefreshToken(ctx, rt.Token)
+}
+
+// UnlockUser clears the failed logins of a user, lifting its lock.
+func (s *authService) UnlockUser(ctx context.Context, userID int) error {
+	ctx, span := app.StartSpan(ctx, "AuthService.UnlockUser")
+	defer span.End()
+	u, err := s.userStroage.GetUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.lockout.reset(ctx, u.Username)
+}
+
+// ForgotPassword mails a reset token to the user of req.Username. Unknown
+// usernames and users without an email get no mail but the same nil error,
+// so callers cannot tell which usernames exist. For the same reason failures
+// are logged rather than returned.
+func (s *authService) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	ctx, span := app.StartSpan(ctx, "AuthService.ForgotPassword")
+	defer span.End()
+	if err := s.forgotPassword(ctx, req); err != nil {
+		slog.ErrorContext(ctx, "forgot password: "+err.Error())
+	}
+	return nil
+}
+
+func (s *authService) forgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	u, err := s.userStroage.GetUserByUsername(ctx, req.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.Email == "" {
+		return nil
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return err
+	}
+	msg, err := resetMessage(u.Email, token, s.conf.PasswordReset)
+	if err != nil {
+		return err
+	}
+	resetToken := ResetTokenModel{
+		UserID:    int(u.ID),
+		TokenHash: tokenHash,
+		ExpiredAt: time.Now().Add(s.conf.PasswordReset.TokenTTL),
+	}
+	if err := s.resetTokenStorage.CreateResetToken(ctx, resetToken); err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token works once; afterwards the user's refresh tokens and other reset
+// tokens are revoked and its login lock is lifted.
+func (s *authService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	ctx, span := app.StartSpan(ctx, "AuthService.ResetPassword")
+	defer span.End()
+	tokenHash := hashToken(req.Token)
+	rt, err := s.resetTokenStorage.GetResetTokenByHash(ctx, tokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	if rt.UsedAt != nil || !rt.ExpiredAt.After(time.Now()) {
+		return ErrResetTokenInvalid
+	}
+
+	u, err := s.userStroage.GetUserByID(ctx, rt.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	hashPassword, err := s.utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	// Use the token before changing the password, so that of two requests
+	// racing with it only one gets through.
+	err = s.resetTokenStorage.UseResetToken(ctx, tokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	u.Password = hashPassword
+	if err := s.userStroage.UpdateUser(ctx, *u); err != nil {
+		return err
+	}
+	if err := s.refreshTokenStorage.RevokeRefreshTokensByUserID(ctx, int(u.ID)); err != nil {
+		return err
+	}
+	// Other links requested before this one must not reset the new password.
+	if err := s.resetTokenStorage.UseResetTokensByUserID(ctx, int(u.ID)); err != nil {
+		return err
+	}
+	return s.lockout.reset(ctx, u.Username)
+}
+
+// VerifyMFA completes a login from a challenge of Login. Verify challenges
+// take a TOTP code or a recovery code; enroll challenges take the first code
+// of the secret from EnrollMFAChallenge, which enables it and returns the
+// recovery codes along with the tokens. Wrong codes count as failed logins.
+func (s *authService) VerifyMFA(ctx context.Context, req MFALoginRequest) (*AuthResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.VerifyMFA")
+	defer span.End()
+	res, err := s.verifyMFA(ctx, req)
+	metrics.ObserveLogin(err == nil)
+	return res, err
+}
+
+func (s *authService) verifyMFA(ctx context.Context, req MFALoginRequest) (*AuthResponse, error) {
+	challenge, err := s.getMFAChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.userStroage.GetUserByID(ctx, challenge.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lockout.check(ctx, u.Username, req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	var recoveryCodes []string
+	switch challenge.Purpose {
+	case MFAChallengeVerify:
+		m, err := s.getEnabledMFA(ctx, challenge.UserID)
+		if errors.Is(err, ErrMFANotEnrolled) {
+			// MFA was disabled after the challenge was handed out.
+			return nil, ErrMFAChallengeInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		ok, err = s.useMFACode(ctx, m, req.Code)
+		if err != nil {
+			return nil, err
+		}
+	case MFAChallengeEnroll:
+		recoveryCodes, ok, err = s.confirmMFA(ctx, challenge.UserID, req.Code)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrMFAChallengeInvalid
+	}
+	if !ok {
+		if failErr := s.lockout.failed(ctx, u.Username, req.ClientIP); failErr != nil {
+			return nil, errors.Join(ErrMFACodeInvalid, failErr)
+		}
+		return nil, ErrMFACodeInvalid
+	}
+
+	err = s.mfaStorage.UseMFAChallenge(ctx, challenge.TokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Another request used this challenge first.
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lockout.reset(ctx, u.Username); err != nil {
+		return nil, err
 	}
 
+	res, err := s.issueTokens(ctx, u, true)
+	if err != nil {
+		return nil, err
+	}
+	res.RecoveryCodes = recoveryCodes
 	return res, nil
 }
+
+// EnrollMFAChallenge hands a new secret to a user who must enroll before its
+// login completes. Calling it again replaces the secret.
+func (s *authService) EnrollMFAChallenge(ctx context.Context, req MFAChallengeRequest) (*MFAEnrollResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.EnrollMFAChallenge")
+	defer span.End()
+	challenge, err := s.getMFAChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != MFAChallengeEnroll {
+		return nil, ErrMFAChallengeInvalid
+	}
+	u, err := s.userStroage.GetUserByID(ctx, challenge.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.enrollMFA(ctx, u)
+}
+
+// EnrollMFA hands a new secret to the user, which ConfirmMFA enables.
+// Calling it again before then replaces the secret.
+func (s *authService) EnrollMFA(ctx context.Context, userID int) (*MFAEnrollResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.EnrollMFA")
+	defer span.End()
+	u, err := s.userStroage.GetUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.enrollMFA(ctx, u)
+}
+
+// ConfirmMFA enables the secret of EnrollMFA with a code of it and returns
+// the recovery codes, shown this once.
+func (s *authService) ConfirmMFA(ctx context.Context, userID int, req MFACodeRequest) (*MFAConfirmResponse, error) {
+	ctx, span := app.StartSpan(ctx, "AuthService.ConfirmMFA")
+	defer span.End()
+	recoveryCodes, ok, err := s.confirmMFA(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+	return &MFAConfirmResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableMFA removes the second factor of the user, given a TOTP code or a
+// recovery code. Users of a role in MFA.RequiredRoles cannot disable it.
+// Wrong codes count as failed logins, so a stolen access token cannot guess
+// its way through.
+func (s *authService) DisableMFA(ctx context.Context, userID int, req MFACodeRequest) error {
+	ctx, span := app.StartSpan(ctx, "AuthService.DisableMFA")
+	defer span.End()
+	u, err := s.userStroage.GetUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	required, err := s.mfaRequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+
+	m, err := s.getEnabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.lockout.check(ctx, u.Username, req.ClientIP); err != nil {
+		return err
+	}
+	ok, err := s.useMFACode(ctx, m, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if failErr := s.lockout.failed(ctx, u.Username, req.ClientIP); failErr != nil {
+			return errors.Join(ErrMFACodeInvalid, failErr)
+		}
+		return ErrMFACodeInvalid
+	}
+	return s.mfaStorage.DeleteMFA(ctx, userID)
+}
+
+// mfaPurpose returns the challenge a login of the user needs, or "" when it
+// needs none.
+func (s *authService) mfaPurpose(ctx context.Context, userID int) (string, error) {
+	m, err := s.mfaStorage.GetMFA(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if err == nil && m.EnabledAt != nil {
+		return MFAChallengeVerify, nil
+	}
+
+	required, err := s.mfaRequired(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return MFAChallengeEnroll, nil
+	}
+	return "", nil
+}
+
+// mfaRequired reports whether the user has a role of MFA.RequiredRoles.
+func (s *authService) mfaRequired(ctx context.Context, userID int) (bool, error) {
+	if len(s.conf.MFA.RequiredRoles) == 0 {
+		return false, nil
+	}
+	roles, err := s.roleStorage.GetRolesByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if slices.Contains(s.conf.MFA.RequiredRoles, r.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *authService) createMFAChallenge(ctx context.Context, userID int, purpose string) (*MFAChallengeResponse, error) {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	challenge := MFAChallengeModel{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+		ExpiredAt: time.Now().Add(s.conf.MFA.ChallengeTTL),
+	}
+	if err := s.mfaStorage.CreateMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return &MFAChallengeResponse{
+		MFARequired:       true,
+		Purpose:           purpose,
+		ChallengeToken:    token,
+		ChallengeExpireAt: challenge.ExpiredAt.Format(FormatDateTime),
+	}, nil
+}
+
+// getMFAChallenge returns the challenge of token while it can be used.
+func (s *authService) getMFAChallenge(ctx context.Context, token string) (*MFAChallengeModel, error) {
+	challenge, err := s.mfaStorage.GetMFAChallengeByHash(ctx, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UsedAt != nil || !challenge.ExpiredAt.After(time.Now()) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	return challenge, nil
+}
+
+func (s *authService) getEnabledMFA(ctx context.Context, userID int) (*MFAModel, error) {
+	m, err := s.mfaStorage.GetMFA(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	return m, nil
+}
+
+func (s *authService) enrollMFA(ctx context.Context, u *user.UserModel) (*MFAEnrollResponse, error) {
+	m, err := s.mfaStorage.GetMFA(ctx, int(u.ID))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && m.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaStorage.SaveMFA(ctx, MFAModel{UserID: int(u.ID), Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	issuer := s.conf.MFA.Issuer
+	if issuer == "" {
+		issuer = s.conf.Issuer
+	}
+	return &MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(issuer, u.Username, secret),
+	}, nil
+}
+
+// confirmMFA enables the pending secret of the user when code is a code of
+// it, and returns the new recovery codes. The user's refresh tokens are
+// revoked, so sessions opened with the password alone end too.
+func (s *authService) confirmMFA(ctx context.Context, userID int, code string) ([]string, bool, error) {
+	m, err := s.mfaStorage.GetMFA(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if m.EnabledAt != nil {
+		return nil, false, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(m.Secret, normalizeCode(code), time.Now(), m.LastUsedStep)
+	if !ok {
+		return nil, false, nil
+	}
+	recoveryCodes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	err = s.mfaStorage.EnableMFA(ctx, userID, step, hashes)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Another request enabled it first.
+		return nil, false, ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.refreshTokenStorage.RevokeRefreshTokensByUserID(ctx, userID); err != nil {
+		return nil, false, err
+	}
+	return recoveryCodes, true, nil
+}
+
+// useMFACode reports whether code is an unused TOTP code or recovery code of
+// m, and uses it up.
+func (s *authService) useMFACode(ctx context.Context, m *MFAModel, code string) (bool, error) {
+	code = normalizeCode(code)
+	if step, ok := totp.Validate(m.Secret, code, time.Now(), m.LastUsedStep); ok {
+		err := s.mfaStorage.UseMFAStep(ctx, m.UserID, step)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := s.mfaStorage.UseRecoveryCode(ctx, m.UserID, hashToken(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *authService) getAccessToken(ctx context.Context, u *user.UserModel) (string, int64, error) {
+	key, err := s.signer.SigningKey()
+	if err != nil {
+		return "", 0, err
+	}
+
+	roles, err := s.roleStorage.GetRolesByUserID(ctx, int(u.ID))
+	if err != nil {
+		return "", 0, err
+	}
+
+	permissions, err := s.roleStorage.GetPermissionsByUserID(ctx, int(u.ID))
+	if err != nil {
+		return "", 0, err
+	}
+
+	tokenData := app.TokenData{
+		UserID:      int(u.ID),
+		Username:    u.Username,
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		Permissions: permissions,
+	}
+	for _, r := range roles {
+		tokenData.Roles = append(tokenData.Roles, r.Name)
+	}
+
+	return s.utils.GetAccessToken(key, tokenData, s.conf)
+}
+
+func newAuthResponse(accessToken string, accessTokenExpire int64, refreshToken string, refreshTokenExpire int64) *AuthResponse {
+	return &AuthResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpireAt:  time.Unix(accessTokenExpire, 0).Format(FormatDateTime),
+		RefreshToken:         refreshToken,
+		RefreshTokenExpireAt: time.Unix(refreshTokenExpire, 0).Format(FormatDateTime),
+	}
+}
@@ -1,11 +1,22 @@
 package auth
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"go-restapi/app"
+	"go-restapi/app/role"
 	"go-restapi/app/user"
+	"go-restapi/mail"
+	"go-restapi/metrics"
+	"go-restapi/totp"
+	"log/slog"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
@@ -18,8 +29,8 @@ type testServiceSuite struct {
 func (s *testServiceSuite) TestLogin() {
 
 	var now = time.Now()
-	var mockAccessTokenExpireAt = now.Add(time.Hour * AccessTokenExpireHour).Unix()
-	var mockRefreshTokenExpireAt = now.Add(time.Hour * RefreshTokenExpireHour).Unix()
+	var mockAccessTokenExpireAt = now.Add(DefaultConfig.AccessTokenTTL).Unix()
+	var mockRefreshTokenExpireAt = now.Add(DefaultConfig.RefreshTokenTTL).Unix()
 
 	var mockReq = AuthRequest{
 		Username: "admin",
@@ -45,108 +56,937 @@ func (s *testServiceSuite) TestLogin() {
 	}
 
 	s.Run("Should return error when user not found", func() {
-		errWant := ErrUserNotFound
+		errWant := ErrInvalidCredentials
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(nil, gorm.ErrRecordNotFound)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(nil, gorm.ErrRecordNotFound)
 		refreshTokenStorage := &mockRefreshTokenStorage{}
 		utils := &mockUtils{}
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		failure := testutil.ToFloat64(metrics.LoginTotal.WithLabelValues("failure"))
+		_, err := service.Login(context.Background(), mockReq)
 		s.ErrorIs(err, errWant)
+		s.Equal(failure+1, testutil.ToFloat64(metrics.LoginTotal.WithLabelValues("failure")))
 	})
 
 	s.Run("Should return error when other error", func() {
 		errWant := errors.New("error")
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(nil, errWant)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(nil, errWant)
 		refreshTokenStorage := &mockRefreshTokenStorage{}
 		utils := &mockUtils{}
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
 		s.ErrorIs(err, errWant)
 	})
 
 	s.Run("Should return error when password not match", func() {
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(&mockUserModel[0], nil)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
 		refreshTokenStorage := &mockRefreshTokenStorage{}
 		utils := &mockUtils{}
 		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(false)
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
-		s.ErrorIs(err, ErrPasswordNotMatch)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
+		s.ErrorIs(err, ErrInvalidCredentials)
 	})
 
 	s.Run("Should return error when get private key", func() {
 		errWant := errors.New("error")
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(&mockUserModel[0], nil)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
 		refreshTokenStorage := &mockRefreshTokenStorage{}
 		utils := &mockUtils{}
 		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
-		utils.On("GetPrivateKey").Return(nil, errWant)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, errWant)
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
 		s.ErrorIs(err, errWant)
 	})
 
 	s.Run("Should return error when get access token", func() {
 		errWant := errors.New("error")
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(&mockUserModel[0], nil)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
 		refreshTokenStorage := &mockRefreshTokenStorage{}
 		utils := &mockUtils{}
 		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
-		utils.On("GetPrivateKey").Return(nil, nil)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
 		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", int64(1), errWant)
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
 		s.ErrorIs(err, errWant)
 	})
 
-	s.Run("Should return error when upsert data", func() {
+	s.Run("Should return error when create refresh token", func() {
 		errWant := errors.New("error")
 
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(&mockUserModel[0], nil)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
 
 		refreshTokenStorage := &mockRefreshTokenStorage{}
-		refreshTokenStorage.On("UpsertRefreshToken", mock.Anything).Return(errWant)
+		refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(errWant)
 
 		utils := &mockUtils{}
 		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
-		utils.On("GetPrivateKey").Return(nil, nil)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
 		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", int64(1), nil)
 		utils.On("GetUUID").Return("xxx")
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		_, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
 		s.ErrorIs(err, errWant)
 	})
 
+	s.Run("Should return error when get roles", func() {
+		errWant := errors.New("error")
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, int(mockUserModel[0].ID)).Return(nil, errWant)
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+
+		service := NewAuthService(userStroage, refreshTokenStorage, roleStorage, newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
+		s.ErrorIs(err, errWant)
+	})
+
+	s.Run("Should return error when get permissions", func() {
+		errWant := errors.New("error")
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, int(mockUserModel[0].ID)).Return([]role.RoleModel{}, nil)
+		roleStorage.On("GetPermissionsByUserID", mock.Anything, int(mockUserModel[0].ID)).Return(nil, errWant)
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+
+		service := NewAuthService(userStroage, refreshTokenStorage, roleStorage, newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), mockReq)
+		s.ErrorIs(err, errWant)
+	})
+
+	s.Run("Should issue tokens with roles, permissions and the configured policy", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
+		conf := app.Auth{AccessTokenTTL: 15 * time.Minute, RefreshTokenTTL: time.Hour, Issuer: "issuer", Audience: "audience", Algorithm: "ES256", PasswordReset: app.PasswordReset{TokenTTL: time.Hour}, MFA: app.MFA{ChallengeTTL: time.Minute}}
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(rt RefreshTokenModel) bool {
+			return time.Until(rt.ExpiredAt) <= conf.RefreshTokenTTL && time.Until(rt.ExpiredAt) > conf.RefreshTokenTTL-time.Minute
+		})).Return(nil)
+		wantTokenData := app.TokenData{
+			UserID:      int(mockUserModel[0].ID),
+			Username:    mockUserModel[0].Username,
+			FirstName:   mockUserModel[0].FirstName,
+			LastName:    mockUserModel[0].LastName,
+			Roles:       []string{"admin"},
+			Permissions: []string{role.PermissionBooksRead},
+		}
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, wantTokenData, conf).Return("xxx", mockAccessTokenExpireAt, nil)
+		utils.On("GetUUID").Return("xxx")
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, conf)
+		_, err := service.Login(context.Background(), mockReq)
+		s.NoError(err)
+		utils.AssertExpectations(s.T())
+		refreshTokenStorage.AssertExpectations(s.T())
+	})
+
 	s.Run("Should return success", func() {
 		userStroage := &mockUserStorage{}
-		userStroage.On("GetUserByUsername", mockReq.Username).Return(&mockUserModel[0], nil)
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel[0], nil)
 
 		refreshTokenStorage := &mockRefreshTokenStorage{}
-		refreshTokenStorage.On("UpsertRefreshToken", mock.Anything).Return(nil)
+		refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
 
 		utils := &mockUtils{}
 		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel[0].Password).Return(true)
-		utils.On("GetPrivateKey").Return(nil, nil)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
 		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return(mockAuthResponseData.AccessToken, mockAccessTokenExpireAt, nil)
 		utils.On("GetUUID").Return(mockAuthResponseData.RefreshToken)
 
-		service := NewAuthService(userStroage, refreshTokenStorage, utils)
-		got, err := service.Login(mockReq)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		success := testutil.ToFloat64(metrics.LoginTotal.WithLabelValues("success"))
+		got, err := service.Login(context.Background(), mockReq)
 		s.NoError(err)
-		s.Equal(mockAuthResponseData, got)
+		s.Equal(&LoginResponse{Tokens: mockAuthResponseData}, got)
+		s.Equal(success+1, testutil.ToFloat64(metrics.LoginTotal.WithLabelValues("success")))
+	})
+}
+
+func (s *testServiceSuite) TestRefreshToken() {
+	var now = time.Now()
+	var mockAccessTokenExpireAt = now.Add(DefaultConfig.AccessTokenTTL).Unix()
+
+	var mockReq = RefreshTokenRequest{
+		RefreshToken: "fcd277b6-562c-49f6-8146-051bb339fb8c",
+	}
+
+	var mockRefreshTokenModel = &RefreshTokenModel{
+		ID:        1,
+		UserID:    1,
+		Token:     mockReq.RefreshToken,
+		ExpiredAt: now.Add(time.Hour),
+	}
+
+	var mockExpiredRefreshTokenModel = &RefreshTokenModel{
+		ID:        1,
+		UserID:    1,
+		Token:     mockReq.RefreshToken,
+		ExpiredAt: now.Add(-time.Hour),
+	}
+
+	var mockUserModel = &user.UserModel{
+		ID:        1,
+		Username:  "admin",
+		Password:  "password",
+		FirstName: "admin",
+		LastName:  "admin",
+		Status:    1,
+	}
+
+	s.Run("Should return error when refresh token not found", func() {
+		userStroage := &mockUserStorage{}
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(nil, gorm.ErrRecordNotFound)
+		utils := &mockUtils{}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrRefreshTokenNotFound)
+	})
+
+	s.Run("Should return error when get refresh token", func() {
+		errWant := errors.New("error")
+		userStroage := &mockUserStorage{}
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(nil, errWant)
+		utils := &mockUtils{}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, errWant)
+	})
+
+	s.Run("Should return error when refresh token expired", func() {
+		userStroage := &mockUserStorage{}
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockExpiredRefreshTokenModel, nil)
+		utils := &mockUtils{}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrRefreshTokenExpired)
+	})
+
+	s.Run("Should return error when refresh token revoked", func() {
+		revokedAt := now.Add(-time.Minute)
+		revoked := *mockRefreshTokenModel
+		revoked.RevokedAt = &revokedAt
+
+		userStroage := &mockUserStorage{}
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(&revoked, nil)
+		utils := &mockUtils{}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrRefreshTokenRevoked)
+	})
+
+	s.Run("Should return error when user not found", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, mockRefreshTokenModel.UserID).Return(nil, gorm.ErrRecordNotFound)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockRefreshTokenModel, nil)
+		utils := &mockUtils{}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrUserNotFound)
+	})
+
+	s.Run("Should return error when token already rotated", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, mockRefreshTokenModel.UserID).Return(mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockRefreshTokenModel, nil)
+		refreshTokenStorage.On("RotateRefreshToken", mock.Anything, mockReq.RefreshToken, mock.Anything).Return(gorm.ErrRecordNotFound)
+		utils := &mockUtils{}
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", mockAccessTokenExpireAt, nil)
+		utils.On("GetUUID").Return("yyy")
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrRefreshTokenNotFound)
+	})
+
+	s.Run("Should return success", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, mockRefreshTokenModel.UserID).Return(mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockRefreshTokenModel, nil)
+		refreshTokenStorage.On("RotateRefreshToken", mock.Anything, mockReq.RefreshToken, mock.Anything).Return(nil)
+		utils := &mockUtils{}
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", mockAccessTokenExpireAt, nil)
+		utils.On("GetUUID").Return("yyy")
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		got, err := service.RefreshToken(context.Background(), mockReq)
+		s.NoError(err)
+		s.Equal("xxx", got.AccessToken)
+		s.Equal("yyy", got.RefreshToken)
+		s.NotEqual(mockReq.RefreshToken, got.RefreshToken)
+	})
+
+	s.Run("Should require mfa for a password-only session of a required role", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, mockRefreshTokenModel.UserID).Return(mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockRefreshTokenModel, nil)
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{MFA: app.MFA{RequiredRoles: []string{"admin"}}})
+		_, err := service.RefreshToken(context.Background(), mockReq)
+		s.ErrorIs(err, ErrMFARequired)
+		refreshTokenStorage.AssertNotCalled(s.T(), "RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	s.Run("Should refresh a session opened with mfa", func() {
+		verified := *mockRefreshTokenModel
+		verified.MFAVerified = true
+		secret, _ := totp.NewSecret()
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, verified.UserID).Return(mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(&verified, nil)
+		refreshTokenStorage.On("RotateRefreshToken", mock.Anything, mockReq.RefreshToken, mock.Anything).Return(nil)
+		utils := &mockUtils{}
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", mockAccessTokenExpireAt, nil)
+		utils.On("GetUUID").Return("yyy")
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), mfaStorage, signer, &fakeMailer{}, utils, app.Auth{MFA: app.MFA{RequiredRoles: []string{"admin"}}})
+		got, err := service.RefreshToken(context.Background(), mockReq)
+		s.NoError(err)
+		s.Equal("yyy", got.RefreshToken)
+	})
+
+	s.Run("Should keep the expiry of the rotated token", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, mockRefreshTokenModel.UserID).Return(mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockReq.RefreshToken).Return(mockRefreshTokenModel, nil)
+		refreshTokenStorage.On("RotateRefreshToken", mock.Anything, mockReq.RefreshToken, mock.MatchedBy(func(rt RefreshTokenModel) bool {
+			return rt.ExpiredAt.Equal(mockRefreshTokenModel.ExpiredAt)
+		})).Return(nil)
+		utils := &mockUtils{}
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", mockAccessTokenExpireAt, nil)
+		utils.On("GetUUID").Return("yyy")
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{RefreshTokenTTL: 24 * time.Hour})
+		got, err := service.RefreshToken(context.Background(), mockReq)
+		s.NoError(err)
+		s.Equal(mockRefreshTokenModel.ExpiredAt.Format(FormatDateTime), got.RefreshTokenExpireAt)
+		refreshTokenStorage.AssertExpectations(s.T())
+	})
+}
+
+func (s *testServiceSuite) TestLogout() {
+	var mockRefreshTokenModel = &RefreshTokenModel{
+		ID:        1,
+		UserID:    1,
+		Token:     "fcd277b6-562c-49f6-8146-051bb339fb8c",
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+
+	s.Run("Should return error when refresh token not found", func() {
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockRefreshTokenModel.Token).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewAuthService(&mockUserStorage{}, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.Logout(context.Background(), LogoutRequest{RefreshToken: mockRefreshTokenModel.Token})
+		s.ErrorIs(err, ErrRefreshTokenNotFound)
+	})
+
+	s.Run("Should return error when refresh token already revoked", func() {
+		revokedAt := time.Now()
+		revoked := *mockRefreshTokenModel
+		revoked.RevokedAt = &revokedAt
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockRefreshTokenModel.Token).Return(&revoked, nil)
+
+		service := NewAuthService(&mockUserStorage{}, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.Logout(context.Background(), LogoutRequest{RefreshToken: mockRefreshTokenModel.Token, All: true})
+		s.ErrorIs(err, ErrRefreshTokenRevoked)
+	})
+
+	s.Run("Should revoke only the given refresh token", func() {
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockRefreshTokenModel.Token).Return(mockRefreshTokenModel, nil)
+		refreshTokenStorage.On("RevokeRefreshToken", mock.Anything, mockRefreshTokenModel.Token).Return(nil)
+
+		service := NewAuthService(&mockUserStorage{}, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.Logout(context.Background(), LogoutRequest{RefreshToken: mockRefreshTokenModel.Token})
+		s.NoError(err)
+		refreshTokenStorage.AssertNotCalled(s.T(), "RevokeRefreshTokensByUserID", mock.Anything, mock.Anything)
+	})
+
+	s.Run("Should revoke every refresh token of the user", func() {
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("GetRefreshTokenByToken", mock.Anything, mockRefreshTokenModel.Token).Return(mockRefreshTokenModel, nil)
+		refreshTokenStorage.On("RevokeRefreshTokensByUserID", mock.Anything, mockRefreshTokenModel.UserID).Return(nil)
+
+		service := NewAuthService(&mockUserStorage{}, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.Logout(context.Background(), LogoutRequest{RefreshToken: mockRefreshTokenModel.Token, All: true})
+		s.NoError(err)
+		refreshTokenStorage.AssertNotCalled(s.T(), "RevokeRefreshToken", mock.Anything, mock.Anything)
+	})
+}
+
+func (s *testServiceSuite) TestLoginLockout() {
+	var mockReq = AuthRequest{
+		Username: "admin",
+		Password: "wrong",
+		ClientIP: "10.0.0.1",
+	}
+	var mockUserModel = user.UserModel{ID: 1, Username: "admin", Password: "password", Status: 1}
+
+	s.Run("Should lock the account after too many failures", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel, nil)
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", mockReq.Password, mockUserModel.Password).Return(false)
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{Lockout: app.Lockout{MaxFailures: 3}})
+		for i := 0; i < 3; i++ {
+			_, err := service.Login(context.Background(), mockReq)
+			s.ErrorIs(err, ErrInvalidCredentials)
+		}
+
+		_, err := service.Login(context.Background(), mockReq)
+		s.ErrorIs(err, ErrAccountLocked)
+		var locked *LockedError
+		s.ErrorAs(err, &locked)
+		s.True(locked.RetryAt().After(time.Now()))
+		utils.AssertNumberOfCalls(s.T(), "CheckPasswordHash", 3)
+	})
+
+	s.Run("Should count unknown usernames", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "nobody").Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{Lockout: app.Lockout{MaxFailures: 2}})
+		req := AuthRequest{Username: "nobody", Password: "x"}
+		for i := 0; i < 2; i++ {
+			_, err := service.Login(context.Background(), req)
+			s.ErrorIs(err, ErrInvalidCredentials)
+		}
+		_, err := service.Login(context.Background(), req)
+		s.ErrorIs(err, ErrAccountLocked)
+	})
+
+	s.Run("Should lock the client IP across usernames", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{Lockout: app.Lockout{IPMaxFailures: 2}})
+		for _, username := range []string{"a", "b"} {
+			_, err := service.Login(context.Background(), AuthRequest{Username: username, Password: "x", ClientIP: "10.0.0.1"})
+			s.ErrorIs(err, ErrInvalidCredentials)
+		}
+		_, err := service.Login(context.Background(), AuthRequest{Username: "c", Password: "x", ClientIP: "10.0.0.1"})
+		s.ErrorIs(err, ErrAccountLocked)
+		_, err = service.Login(context.Background(), AuthRequest{Username: "c", Password: "x", ClientIP: "10.0.0.2"})
+		s.ErrorIs(err, ErrInvalidCredentials)
 	})
+
+	s.Run("Should reset the failures after a successful login", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, mockReq.Username).Return(&mockUserModel, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "wrong", mockUserModel.Password).Return(false)
+		utils.On("CheckPasswordHash", "password", mockUserModel.Password).Return(true)
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("xxx", int64(1), nil)
+		utils.On("GetUUID").Return("uuid")
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), loginAttemptStorage, newFakeResetTokenStorage(), newFakeMFAStorage(), signer, &fakeMailer{}, utils, app.Auth{})
+		_, err := service.Login(context.Background(), AuthRequest{Username: "admin", Password: "wrong"})
+		s.ErrorIs(err, ErrInvalidCredentials)
+		s.Contains(loginAttemptStorage.attempts, userSubject("admin"))
+
+		_, err = service.Login(context.Background(), AuthRequest{Username: "admin", Password: "password"})
+		s.NoError(err)
+		s.NotContains(loginAttemptStorage.attempts, userSubject("admin"))
+	})
+}
+
+func (s *testServiceSuite) TestUnlockUser() {
+	s.Run("Should return error when user not found", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.UnlockUser(context.Background(), 1)
+		s.ErrorIs(err, ErrUserNotFound)
+	})
+
+	s.Run("Should clear the failures of the user", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(&user.UserModel{ID: 1, Username: "Admin"}, nil)
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+		lockedUntil := time.Now().Add(time.Hour)
+		loginAttemptStorage.attempts[userSubject("admin")] = LoginAttemptModel{Subject: userSubject("admin"), Failures: 5, LockedUntil: &lockedUntil}
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), loginAttemptStorage, newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.UnlockUser(context.Background(), 1)
+		s.NoError(err)
+		s.Empty(loginAttemptStorage.attempts)
+	})
+}
+
+func (s *testServiceSuite) TestForgotPassword() {
+	conf := app.Auth{PasswordReset: app.PasswordReset{TokenTTL: time.Hour, URL: "https://app.example.com/reset"}}
+
+	s.Run("Should store a hashed token and mail it", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "admin").Return(&user.UserModel{ID: 1, Username: "admin", Email: "admin@example.com"}, nil)
+		resetTokenStorage := newFakeResetTokenStorage()
+		mailer := &fakeMailer{}
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), resetTokenStorage, newFakeMFAStorage(), &mockSigner{}, mailer, &mockUtils{}, conf)
+		err := service.ForgotPassword(context.Background(), ForgotPasswordRequest{Username: "admin"})
+		s.NoError(err)
+
+		if s.Len(mailer.sent, 1) {
+			s.Equal("admin@example.com", mailer.sent[0].To)
+			token := mailedToken(s.T(), mailer.sent[0])
+			s.NotContains(resetTokenStorage.tokens, token)
+			if s.Contains(resetTokenStorage.tokens, hashToken(token)) {
+				rt := resetTokenStorage.tokens[hashToken(token)]
+				s.Equal(1, rt.UserID)
+				s.WithinDuration(time.Now().Add(time.Hour), rt.ExpiredAt, time.Minute)
+			}
+		}
+	})
+
+	s.Run("Should answer the same for an unknown username", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "nobody").Return(nil, gorm.ErrRecordNotFound)
+		resetTokenStorage := newFakeResetTokenStorage()
+		mailer := &fakeMailer{}
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), resetTokenStorage, newFakeMFAStorage(), &mockSigner{}, mailer, &mockUtils{}, conf)
+		err := service.ForgotPassword(context.Background(), ForgotPasswordRequest{Username: "nobody"})
+		s.NoError(err)
+		s.Empty(mailer.sent)
+		s.Empty(resetTokenStorage.tokens)
+	})
+
+	s.Run("Should mail nothing to a user without email", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "admin").Return(&user.UserModel{ID: 1, Username: "admin"}, nil)
+		mailer := &fakeMailer{}
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), newFakeMFAStorage(), &mockSigner{}, mailer, &mockUtils{}, conf)
+		err := service.ForgotPassword(context.Background(), ForgotPasswordRequest{Username: "admin"})
+		s.NoError(err)
+		s.Empty(mailer.sent)
+	})
+
+	s.Run("Should log and hide the error when storing the token fails", func() {
+		var out bytes.Buffer
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&out, nil)))
+		defer slog.SetDefault(defaultLogger)
+
+		errWant := errors.New("store is down")
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "admin").Return(&user.UserModel{ID: 1, Username: "admin", Email: "admin@example.com"}, nil)
+		resetTokenStorage := newFakeResetTokenStorage()
+		resetTokenStorage.err = errWant
+		mailer := &fakeMailer{}
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), resetTokenStorage, newFakeMFAStorage(), &mockSigner{}, mailer, &mockUtils{}, conf)
+		err := service.ForgotPassword(context.Background(), ForgotPasswordRequest{Username: "admin"})
+		s.NoError(err)
+		s.Empty(mailer.sent)
+		s.Contains(out.String(), "store is down")
+	})
+}
+
+func (s *testServiceSuite) TestResetPassword() {
+	const token = "reset-token"
+	newStorage := func(rt ResetTokenModel) *fakeResetTokenStorage {
+		storage := newFakeResetTokenStorage()
+		rt.TokenHash = hashToken(token)
+		storage.tokens[rt.TokenHash] = rt
+		return storage
+	}
+	req := ResetPasswordRequest{Token: token, NewPassword: "new-password"}
+
+	s.Run("Should set the password, use the token and end the sessions", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(&user.UserModel{ID: 1, Username: "admin", Password: "old-hash"}, nil)
+		userStroage.On("UpdateUser", mock.Anything, user.UserModel{ID: 1, Username: "admin", Password: "new-hash"}).Return(nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("RevokeRefreshTokensByUserID", mock.Anything, 1).Return(nil)
+		utils := &mockUtils{}
+		utils.On("HashPassword", "new-password").Return("new-hash", nil)
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+		lockedUntil := time.Now().Add(time.Hour)
+		loginAttemptStorage.attempts[userSubject("admin")] = LoginAttemptModel{Subject: userSubject("admin"), Failures: 5, LockedUntil: &lockedUntil}
+		resetTokenStorage := newStorage(ResetTokenModel{UserID: 1, ExpiredAt: time.Now().Add(time.Hour)})
+		resetTokenStorage.tokens["other"] = ResetTokenModel{UserID: 1, TokenHash: "other", ExpiredAt: time.Now().Add(time.Hour)}
+		resetTokenStorage.tokens["another-user"] = ResetTokenModel{UserID: 2, TokenHash: "another-user", ExpiredAt: time.Now().Add(time.Hour)}
+
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), loginAttemptStorage, resetTokenStorage, newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, utils, app.Auth{})
+		err := service.ResetPassword(context.Background(), req)
+		s.NoError(err)
+		userStroage.AssertExpectations(s.T())
+		refreshTokenStorage.AssertExpectations(s.T())
+		s.NotNil(resetTokenStorage.tokens[hashToken(token)].UsedAt)
+		s.NotNil(resetTokenStorage.tokens["other"].UsedAt)
+		s.Nil(resetTokenStorage.tokens["another-user"].UsedAt)
+		s.Empty(loginAttemptStorage.attempts)
+
+		err = service.ResetPassword(context.Background(), req)
+		s.ErrorIs(err, ErrResetTokenInvalid)
+	})
+
+	usedAt := time.Now().Add(-time.Minute)
+	testCases := []struct {
+		name    string
+		storage *fakeResetTokenStorage
+	}{
+		{"Should reject an unknown token", newFakeResetTokenStorage()},
+		{"Should reject an expired token", newStorage(ResetTokenModel{UserID: 1, ExpiredAt: time.Now().Add(-time.Minute)})},
+		{"Should reject a used token", newStorage(ResetTokenModel{UserID: 1, ExpiredAt: time.Now().Add(time.Hour), UsedAt: &usedAt})},
+	}
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			userStroage := &mockUserStorage{}
+
+			service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), tc.storage, newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+			err := service.ResetPassword(context.Background(), req)
+			s.ErrorIs(err, ErrResetTokenInvalid)
+			userStroage.AssertNotCalled(s.T(), "UpdateUser", mock.Anything, mock.Anything)
+		})
+	}
+
+	s.Run("Should reject the token of a deleted user", func() {
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewAuthService(userStroage, &mockRefreshTokenStorage{}, newMockRoleStorage(), newFakeLoginAttemptStorage(), newStorage(ResetTokenModel{UserID: 1, ExpiredAt: time.Now().Add(time.Hour)}), newFakeMFAStorage(), &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+		err := service.ResetPassword(context.Background(), req)
+		s.ErrorIs(err, ErrResetTokenInvalid)
+	})
+}
+
+// newMFAService returns a service whose user admin, ID 1, logs in with
+// "password" and is issued tokens without further expectations.
+func newMFAService(mfaStorage *fakeMFAStorage, loginAttemptStorage *fakeLoginAttemptStorage, conf app.Auth) AuthService {
+	u := &user.UserModel{ID: 1, Username: "admin", Password: "hash"}
+	userStroage := &mockUserStorage{}
+	userStroage.On("GetUserByUsername", mock.Anything, "admin").Return(u, nil)
+	userStroage.On("GetUserByID", mock.Anything, 1).Return(u, nil)
+	refreshTokenStorage := &mockRefreshTokenStorage{}
+	refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil)
+	refreshTokenStorage.On("RevokeRefreshTokensByUserID", mock.Anything, 1).Return(nil)
+	utils := &mockUtils{}
+	utils.On("CheckPasswordHash", "password", "hash").Return(true)
+	utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("access", int64(0), nil)
+	utils.On("GetUUID").Return("refresh")
+	signer := &mockSigner{}
+	signer.On("SigningKey").Return(nil, nil)
+	return NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), loginAttemptStorage, newFakeResetTokenStorage(), mfaStorage, signer, &fakeMailer{}, utils, conf)
+}
+
+// loginChallenge logs admin in and returns the challenge it answers with.
+func loginChallenge(s *testServiceSuite, service AuthService) MFAChallengeResponse {
+	res, err := service.Login(context.Background(), AuthRequest{Username: "admin", Password: "password"})
+	s.NoError(err)
+	if res == nil || res.Challenge == nil {
+		s.FailNow("no challenge")
+	}
+	s.Nil(res.Tokens)
+	return *res.Challenge
+}
+
+func enabledMFA(secret string) MFAModel {
+	enabledAt := time.Now()
+	return MFAModel{UserID: 1, Secret: secret, EnabledAt: &enabledAt}
+}
+
+func (s *testServiceSuite) TestLoginMFA() {
+	secret, _ := totp.NewSecret()
+
+	s.Run("Should answer with a verify challenge when MFA is enabled", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+		loginAttemptStorage.attempts[userSubject("admin")] = LoginAttemptModel{Subject: userSubject("admin"), Failures: 2}
+
+		service := newMFAService(mfaStorage, loginAttemptStorage, app.Auth{})
+		challenge := loginChallenge(s, service)
+		s.True(challenge.MFARequired)
+		s.Equal(MFAChallengeVerify, challenge.Purpose)
+		s.Contains(mfaStorage.challenges, hashToken(challenge.ChallengeToken))
+		// The password alone does not clear the failures.
+		s.Equal(2, loginAttemptStorage.attempts[userSubject("admin")].Failures)
+	})
+
+	s.Run("Should make users of a required role enroll", func() {
+		service := newMFAService(newFakeMFAStorage(), newFakeLoginAttemptStorage(), app.Auth{MFA: app.MFA{RequiredRoles: []string{"admin"}}})
+		challenge := loginChallenge(s, service)
+		s.Equal(MFAChallengeEnroll, challenge.Purpose)
+	})
+
+	s.Run("Should not challenge users of other roles", func() {
+		service := newMFAService(newFakeMFAStorage(), newFakeLoginAttemptStorage(), app.Auth{MFA: app.MFA{RequiredRoles: []string{"auditor"}}})
+		res, err := service.Login(context.Background(), AuthRequest{Username: "admin", Password: "password"})
+		s.NoError(err)
+		s.Nil(res.Challenge)
+		s.Equal("access", res.Tokens.AccessToken)
+	})
+}
+
+func (s *testServiceSuite) TestVerifyMFA() {
+	secret, _ := totp.NewSecret()
+
+	s.Run("Should issue tokens for a valid code, once", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+		loginAttemptStorage.attempts[userSubject("admin")] = LoginAttemptModel{Subject: userSubject("admin"), Failures: 2}
+		service := newMFAService(mfaStorage, loginAttemptStorage, app.Auth{})
+		code, _ := totp.Code(secret, time.Now())
+
+		challenge := loginChallenge(s, service)
+		res, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		s.NoError(err)
+		s.Equal("access", res.AccessToken)
+		s.Empty(res.RecoveryCodes)
+		s.Empty(loginAttemptStorage.attempts)
+
+		_, err = service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		s.ErrorIs(err, ErrMFAChallengeInvalid)
+
+		// A code works once, even with a new challenge.
+		challenge = loginChallenge(s, service)
+		_, err = service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		s.ErrorIs(err, ErrMFACodeInvalid)
+	})
+
+	s.Run("Should mark the refresh token as verified with mfa", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		u := &user.UserModel{ID: 1, Username: "admin", Password: "hash"}
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByUsername", mock.Anything, "admin").Return(u, nil)
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(u, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(rt RefreshTokenModel) bool { return rt.MFAVerified })).Return(nil)
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(true)
+		utils.On("GetAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("access", int64(0), nil)
+		utils.On("GetUUID").Return("refresh")
+		signer := &mockSigner{}
+		signer.On("SigningKey").Return(nil, nil)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), mfaStorage, signer, &fakeMailer{}, utils, app.Auth{})
+		code, _ := totp.Code(secret, time.Now())
+
+		challenge := loginChallenge(s, service)
+		_, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		s.NoError(err)
+		refreshTokenStorage.AssertExpectations(s.T())
+	})
+
+	s.Run("Should accept a recovery code once", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		mfaStorage.codes[1] = map[string]bool{hashToken("abcdefghij"): true}
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{})
+
+		challenge := loginChallenge(s, service)
+		_, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: "ABCDE-FGHIJ"})
+		s.NoError(err)
+
+		challenge = loginChallenge(s, service)
+		_, err = service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: "abcde-fghij"})
+		s.ErrorIs(err, ErrMFACodeInvalid)
+	})
+
+	s.Run("Should count wrong codes as failed logins", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		loginAttemptStorage := newFakeLoginAttemptStorage()
+		service := newMFAService(mfaStorage, loginAttemptStorage, app.Auth{Lockout: app.Lockout{MaxFailures: 2}})
+
+		challenge := loginChallenge(s, service)
+		req := MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: "000000", ClientIP: "10.0.0.1"}
+		_, err := service.VerifyMFA(context.Background(), req)
+		s.ErrorIs(err, ErrMFACodeInvalid)
+		s.Equal(1, loginAttemptStorage.attempts[userSubject("admin")].Failures)
+
+		_, err = service.VerifyMFA(context.Background(), req)
+		s.ErrorIs(err, ErrMFACodeInvalid)
+		_, err = service.VerifyMFA(context.Background(), req)
+		s.ErrorIs(err, ErrAccountLocked)
+	})
+
+	s.Run("Should reject an expired challenge", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		token, tokenHash, _ := newToken()
+		mfaStorage.challenges[tokenHash] = MFAChallengeModel{UserID: 1, TokenHash: tokenHash, Purpose: MFAChallengeVerify, ExpiredAt: time.Now().Add(-time.Second)}
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{})
+		code, _ := totp.Code(secret, time.Now())
+
+		_, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: token, Code: code})
+		s.ErrorIs(err, ErrMFAChallengeInvalid)
+	})
+
+	s.Run("Should enroll a user of a required role", func() {
+		mfaStorage := newFakeMFAStorage()
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{MFA: app.MFA{RequiredRoles: []string{"admin"}}})
+
+		challenge := loginChallenge(s, service)
+		_, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: "123456"})
+		s.ErrorIs(err, ErrMFANotEnrolled)
+
+		enroll, err := service.EnrollMFAChallenge(context.Background(), MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken})
+		s.NoError(err)
+		code, _ := totp.Code(enroll.Secret, time.Now())
+		res, err := service.VerifyMFA(context.Background(), MFALoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		s.NoError(err)
+		s.Len(res.RecoveryCodes, RecoveryCodeCount)
+		s.NotNil(mfaStorage.mfa[1].EnabledAt)
+
+		// From now on the login asks for a code instead.
+		s.Equal(MFAChallengeVerify, loginChallenge(s, service).Purpose)
+	})
+
+	s.Run("Should not hand a secret to a verify challenge", func() {
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{})
+
+		challenge := loginChallenge(s, service)
+		_, err := service.EnrollMFAChallenge(context.Background(), MFAChallengeRequest{ChallengeToken: challenge.ChallengeToken})
+		s.ErrorIs(err, ErrMFAChallengeInvalid)
+		s.Equal(secret, mfaStorage.mfa[1].Secret)
+	})
+}
+
+func (s *testServiceSuite) TestMFAEnrollment() {
+	s.Run("Should enroll, confirm and disable", func() {
+		mfaStorage := newFakeMFAStorage()
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{Issuer: "go-restapi"})
+
+		_, err := service.ConfirmMFA(context.Background(), 1, MFACodeRequest{Code: "123456"})
+		s.ErrorIs(err, ErrMFANotEnrolled)
+
+		enroll, err := service.EnrollMFA(context.Background(), 1)
+		s.NoError(err)
+		s.Equal(totp.URI("go-restapi", "admin", enroll.Secret), enroll.OTPAuthURI)
+
+		_, err = service.ConfirmMFA(context.Background(), 1, MFACodeRequest{Code: "not-a-code"})
+		s.ErrorIs(err, ErrMFACodeInvalid)
+		code, _ := totp.Code(enroll.Secret, time.Now())
+		confirm, err := service.ConfirmMFA(context.Background(), 1, MFACodeRequest{Code: code})
+		s.NoError(err)
+		s.Len(confirm.RecoveryCodes, RecoveryCodeCount)
+
+		_, err = service.EnrollMFA(context.Background(), 1)
+		s.ErrorIs(err, ErrMFAAlreadyEnabled)
+
+		err = service.DisableMFA(context.Background(), 1, MFACodeRequest{Code: "000000"})
+		s.ErrorIs(err, ErrMFACodeInvalid)
+		err = service.DisableMFA(context.Background(), 1, MFACodeRequest{Code: confirm.RecoveryCodes[0]})
+		s.NoError(err)
+		s.Empty(mfaStorage.mfa)
+	})
+
+	s.Run("Should revoke the refresh tokens when MFA is enabled", func() {
+		mfaStorage := newFakeMFAStorage()
+		userStroage := &mockUserStorage{}
+		userStroage.On("GetUserByID", mock.Anything, 1).Return(&user.UserModel{ID: 1, Username: "admin"}, nil)
+		refreshTokenStorage := &mockRefreshTokenStorage{}
+		refreshTokenStorage.On("RevokeRefreshTokensByUserID", mock.Anything, 1).Return(nil)
+		service := NewAuthService(userStroage, refreshTokenStorage, newMockRoleStorage(), newFakeLoginAttemptStorage(), newFakeResetTokenStorage(), mfaStorage, &mockSigner{}, &fakeMailer{}, &mockUtils{}, app.Auth{})
+
+		enroll, err := service.EnrollMFA(context.Background(), 1)
+		s.NoError(err)
+		refreshTokenStorage.AssertNotCalled(s.T(), "RevokeRefreshTokensByUserID", mock.Anything, 1)
+		code, _ := totp.Code(enroll.Secret, time.Now())
+		_, err = service.ConfirmMFA(context.Background(), 1, MFACodeRequest{Code: code})
+		s.NoError(err)
+		refreshTokenStorage.AssertCalled(s.T(), "RevokeRefreshTokensByUserID", mock.Anything, 1)
+	})
+
+	s.Run("Should name the account after the configured issuer", func() {
+		service := newMFAService(newFakeMFAStorage(), newFakeLoginAttemptStorage(), app.Auth{Issuer: "go-restapi", MFA: app.MFA{Issuer: "Books"}})
+		enroll, err := service.EnrollMFA(context.Background(), 1)
+		s.NoError(err)
+		s.Equal(totp.URI("Books", "admin", enroll.Secret), enroll.OTPAuthURI)
+	})
+
+	s.Run("Should not disable MFA of a required role", func() {
+		secret, _ := totp.NewSecret()
+		mfaStorage := newFakeMFAStorage()
+		mfaStorage.mfa[1] = enabledMFA(secret)
+		service := newMFAService(mfaStorage, newFakeLoginAttemptStorage(), app.Auth{MFA: app.MFA{RequiredRoles: []string{"admin"}}})
+		code, _ := totp.Code(secret, time.Now())
+
+		err := service.DisableMFA(context.Background(), 1, MFACodeRequest{Code: code})
+		s.ErrorIs(err, ErrMFARequired)
+		s.Contains(mfaStorage.mfa, 1)
+	})
+}
+
+// mailedToken returns the token of the reset link in msg.
+func mailedToken(t *testing.T, msg mail.Message) string {
+	for _, field := range strings.Fields(msg.Body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Has("token") {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no reset link in %q", msg.Body)
+	return ""
 }
 
 func TestAuthService(t *testing.T) {
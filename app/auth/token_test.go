@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewToken(t *testing.T) {
+	token, tokenHash, err := newToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.Equal(t, hashToken(token), tokenHash)
+	assert.Len(t, tokenHash, 64)
+
+	other, _, err := newToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
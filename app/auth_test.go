@@ -0,0 +1,196 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"go-restapi/logger"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verify := func(token string) (*TokenData, error) {
+		if token != "valid" {
+			return nil, errors.New("invalid token")
+		}
+		return &TokenData{UserID: 1, Username: "admin"}, nil
+	}
+
+	r := &Router{Engine: gin.New(), logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+	g := r.Group("/api")
+	g.Authenticate(verify)
+	g.GET("/me", func(ctx Context) {
+		ctx.OK(ctx.GetTokenData())
+	})
+
+	testCases := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Should return 401 when header is missing",
+			authorization:  "",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should return 401 when scheme is not bearer",
+			authorization:  "Basic valid",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should return 401 when token is invalid",
+			authorization:  "Bearer invalid",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should expose token data to handler",
+			authorization:  "Bearer valid",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":"","data":{"userId":1,"username":"admin","firstname":"","lastname":"","roles":null,"permissions":null}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verify := func(token string) (*TokenData, error) {
+		return &TokenData{UserID: 1, Permissions: []string{"books:read"}}, nil
+	}
+
+	r := &Router{Engine: gin.New(), logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+	handler := func(ctx Context) { ctx.OK(nil) }
+	r.Group("/public").Require("books:read").GET("/books", handler)
+	g := r.Group("/api")
+	g.Authenticate(verify)
+	g.Require("books:read").GET("/books", handler)
+	g.Require("books:write").POST("/books", handler)
+
+	testCases := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Should return 200 when token has permission",
+			method:         http.MethodGet,
+			url:            "/api/books",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":""}`,
+		},
+		{
+			name:           "Should return 403 when token lacks permission",
+			method:         http.MethodPost,
+			url:            "/api/books",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"status":"ERROR","message":"` + ForbiddenMsg + `"}`,
+		},
+		{
+			name:           "Should return 401 when group is not authenticated",
+			method:         http.MethodGet,
+			url:            "/public/books",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + UnauthorizedMsg + `"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestAllowDebugLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verify := func(token string) (*TokenData, error) {
+		permissions := []string{}
+		if token == "admin" {
+			permissions = []string{"logs:write"}
+		}
+		return &TokenData{UserID: 1, Permissions: permissions}, nil
+	}
+
+	// The logger stays at info level; only flagged requests write debug
+	// records.
+	var logs bytes.Buffer
+	handler := logger.NewLevelHandler(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := &Router{Engine: gin.New(), logger: slog.New(handler)}
+	g := r.Group("/api")
+	g.Authenticate(verify)
+	g.AllowDebugLog("logs:write")
+	g.GET("/debug", func(ctx Context) {
+		ctx.Debugf("debug record")
+		ctx.OK(logger.DebugEnabled(ctx))
+	})
+
+	testCases := []struct {
+		name          string
+		token         string
+		debugHeader   string
+		expectedDebug bool
+	}{
+		{name: "Should enable debug logs for an authorized caller", token: "admin", debugHeader: "true", expectedDebug: true},
+		{name: "Should ignore the header from other callers", token: "user", debugHeader: "true", expectedDebug: false},
+		{name: "Should leave requests without the header alone", token: "admin", debugHeader: "", expectedDebug: false},
+		{name: "Should ignore a false header", token: "admin", debugHeader: "false", expectedDebug: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/api/debug", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			if tc.debugHeader != "" {
+				req.Header.Set(DebugLogHeader, tc.debugHeader)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			if tc.expectedDebug {
+				assert.Contains(t, rec.Body.String(), `"data":true`)
+				assert.Contains(t, logs.String(), `"level":"DEBUG","msg":"debug record"`)
+			} else {
+				assert.Contains(t, rec.Body.String(), `"data":false`)
+				assert.NotContains(t, logs.String(), "debug record")
+			}
+		})
+	}
+}
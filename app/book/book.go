@@ -1,5 +1,11 @@
 package book
 
+import (
+	"go-restapi/app"
+	"go-restapi/utils"
+	"net/http"
+)
+
 type Book struct {
 	ID     int64  `json:"id"`
 	Title  string `json:"title"`
@@ -11,6 +17,11 @@ type BookRequest struct {
 	Author string `json:"author" validate:"required"`
 }
 
+type PatchBookRequest struct {
+	Title  *string `json:"title" validate:"omitempty,min=1"`
+	Author *string `json:"author" validate:"omitempty,min=1"`
+}
+
 type BookModel struct {
 	ID     int64  `db:"id" gorm:"primaryKey" `
 	Title  string `db:"title"`
@@ -19,6 +30,8 @@ type BookModel struct {
 
 var BookTableName = "books"
 
+var ErrBookNotFound = app.NewCodedError("BOOK_NOT_FOUND", http.StatusNotFound, "book not found")
+
 func New(bookStorage BookStorage) BookHandler {
-	return NewHandler(NewBookService(bookStorage))
+	return NewHandler(NewBookService(bookStorage), utils.NewUtils())
 }
@@ -0,0 +1,12 @@
+package book
+
+import (
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	handler := New(&bookStorageMockSuccess{})
+	if handler == nil {
+		t.Errorf("Handler should be not nil")
+	}
+}
@@ -2,30 +2,83 @@ package book
 
 import (
 	"go-restapi/app"
+	"go-restapi/utils"
+	"strconv"
 )
 
 type bookHandler struct {
 	bookSvc BookService
+	utils   utils.Utils
 }
 
 type BookHandler interface {
-	GetAllBook(ctx app.Context)
+	GetListBook(ctx app.Context)
+	GetBookByID(ctx app.Context)
 	CreateBook(ctx app.Context)
+	UpdateBook(ctx app.Context)
+	PatchBook(ctx app.Context)
+	DeleteBook(ctx app.Context)
 }
 
-func NewHandler(bookSvc BookService) BookHandler {
+func NewHandler(bookSvc BookService, utils utils.Utils) BookHandler {
 	return &bookHandler{
 		bookSvc: bookSvc,
+		utils:   utils,
 	}
 }
 
-func (h *bookHandler) GetAllBook(ctx app.Context) {
-	books, err := h.bookSvc.GetAllBook()
+func (h *bookHandler) GetListBook(ctx app.Context) {
+	page, err := h.utils.GetPage(ctx)
 	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
+	pageSize, err := h.utils.GetPageSize(ctx)
+	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
+
+	books, err := h.bookSvc.GetListBook(ctx, page, pageSize)
+	if err != nil {
+		ctx.StoreError(err)
+		return
+	}
+
+	totalRecord, err := h.bookSvc.CountListBook(ctx)
+	if err != nil {
+		ctx.StoreError(err)
+		return
+	}
+
+	paging := app.Paging{
+		CurrentRecord: len(books),
+		CurrentPage:   page,
+		TotalRecord:   totalRecord,
+		TotalPage:     h.utils.GetTotalPage(totalRecord, pageSize),
+	}
+
+	ctx.OKWithPaging(books, paging)
+}
+
+func (h *bookHandler) GetBookByID(ctx app.Context) {
+	id, err := strconv.Atoi(ctx.GetParam("id"))
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	book, err := h.bookSvc.GetBookByID(ctx, id)
+	if err != nil {
+		if err == ErrBookNotFound {
+			ctx.HandleError(err)
+			return
+		}
 		ctx.StoreError(err)
 		return
 	}
-	ctx.OK(books)
+
+	ctx.OK(book)
 }
 
 func (h *bookHandler) CreateBook(ctx app.Context) {
@@ -35,12 +88,91 @@ func (h *bookHandler) CreateBook(ctx app.Context) {
 		return
 	}
 
-	if _, err := ctx.Validate(&book); err != nil {
+	if fields, err := ctx.Validate(&book); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.bookSvc.CreateBook(ctx, book); err != nil {
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *bookHandler) UpdateBook(ctx app.Context) {
+	id, err := strconv.Atoi(ctx.GetParam("id"))
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	var book BookRequest
+	if err := ctx.Bind(&book); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&book); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.bookSvc.UpdateBook(ctx, id, book); err != nil {
+		if err == ErrBookNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *bookHandler) PatchBook(ctx app.Context) {
+	id, err := strconv.Atoi(ctx.GetParam("id"))
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	var book PatchBookRequest
+	if err := ctx.Bind(&book); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&book); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.bookSvc.PatchBook(ctx, id, book); err != nil {
+		if err == ErrBookNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *bookHandler) DeleteBook(ctx app.Context) {
+	id, err := strconv.Atoi(ctx.GetParam("id"))
+	if err != nil {
 		ctx.BadRequest(err)
 		return
 	}
 
-	if err := h.bookSvc.CreateBook(book); err != nil {
+	if err := h.bookSvc.DeleteBook(ctx, id); err != nil {
+		if err == ErrBookNotFound {
+			ctx.HandleError(err)
+			return
+		}
 		ctx.StoreError(err)
 		return
 	}
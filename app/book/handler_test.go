@@ -2,9 +2,11 @@ package book
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"go-restapi/app"
 	"go-restapi/logger"
+	"go-restapi/utils"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -16,7 +18,7 @@ type bookServiceMockSuccess struct {
 	BookService
 }
 
-func (m *bookServiceMockSuccess) GetAllBook() ([]Book, error) {
+func (m *bookServiceMockSuccess) GetListBook(ctx context.Context, page, pageSize int) ([]Book, error) {
 	return []Book{
 		{
 			ID:     1,
@@ -26,53 +28,60 @@ func (m *bookServiceMockSuccess) GetAllBook() ([]Book, error) {
 	}, nil
 }
 
-func (m *bookServiceMockSuccess) CreateBook(book BookRequest) error {
+func (m *bookServiceMockSuccess) CountListBook(ctx context.Context) (int, error) {
+	return 21, nil
+}
+
+func (m *bookServiceMockSuccess) GetBookByID(ctx context.Context, id int) (*Book, error) {
+	return &Book{ID: int64(id), Title: "test", Author: "test"}, nil
+}
+
+func (m *bookServiceMockSuccess) CreateBook(ctx context.Context, book BookRequest) error {
+	return nil
+}
+
+func (m *bookServiceMockSuccess) UpdateBook(ctx context.Context, id int, book BookRequest) error {
+	return nil
+}
+
+func (m *bookServiceMockSuccess) PatchBook(ctx context.Context, id int, book PatchBookRequest) error {
+	return nil
+}
+
+func (m *bookServiceMockSuccess) DeleteBook(ctx context.Context, id int) error {
 	return nil
 }
 
 func toGinHandlerFunc(f func(ctx app.Context)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		l := logger.New()
+		l, _ := logger.New("debug")
 		ctx := app.NewContext(c, l.Handler())
 		f(ctx)
 	}
 }
 
-var testSuccessCases = []struct {
+type testCase struct {
 	name           string
 	url            string
 	method         string
 	reqBody        string
 	expectedStatus int
 	expectedBody   string
-}{
-	{
-		name:           "GetAllBook: Should return array of book and success message",
-		url:            "/books",
-		method:         "GET",
-		reqBody:        ``,
-		expectedStatus: http.StatusOK,
-		expectedBody:   `{"status":"SUCCESS","message":"","data":[{"id":1,"title":"test","author":"test"}]}`,
-	},
-	{
-		name:           "CreateBook: Should return success message",
-		url:            "/books",
-		method:         "POST",
-		reqBody:        `{"title":"test","author":"test"}`,
-		expectedStatus: http.StatusOK,
-		expectedBody:   `{"status":"SUCCESS","message":""}`,
-	},
 }
 
-func TestBookHandlerSuccessCase(t *testing.T) {
+func runTestCases(t *testing.T, service BookService, testCases []testCase) {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
-	bookHandler := NewHandler(&bookServiceMockSuccess{})
-	r.GET("/books", toGinHandlerFunc(bookHandler.GetAllBook))
+	bookHandler := NewHandler(service, utils.NewUtils())
+	r.GET("/books", toGinHandlerFunc(bookHandler.GetListBook))
+	r.GET("/books/:id", toGinHandlerFunc(bookHandler.GetBookByID))
 	r.POST("/books", toGinHandlerFunc(bookHandler.CreateBook))
+	r.PUT("/books/:id", toGinHandlerFunc(bookHandler.UpdateBook))
+	r.PATCH("/books/:id", toGinHandlerFunc(bookHandler.PatchBook))
+	r.DELETE("/books/:id", toGinHandlerFunc(bookHandler.DeleteBook))
 
-	for _, tc := range testSuccessCases {
+	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			body := bytes.NewBufferString(tc.reqBody)
 
@@ -92,36 +101,116 @@ func TestBookHandlerSuccessCase(t *testing.T) {
 	}
 }
 
+var testSuccessCases = []testCase{
+	{
+		name:           "GetListBook: Should return array of book and paging",
+		url:            "/books?page=2&pageSize=10",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":"","currentRecord":1,"currentPage":2,"totalRecord":21,"totalPage":3,"data":[{"id":1,"title":"test","author":"test"}]}`,
+	},
+	{
+		name:           "GetBookByID: Should return book",
+		url:            "/books/1",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":"","data":{"id":1,"title":"test","author":"test"}}`,
+	},
+	{
+		name:           "CreateBook: Should return success message",
+		url:            "/books",
+		method:         "POST",
+		reqBody:        `{"title":"test","author":"test"}`,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+	{
+		name:           "UpdateBook: Should return success message",
+		url:            "/books/1",
+		method:         "PUT",
+		reqBody:        `{"title":"test","author":"test"}`,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+	{
+		name:           "PatchBook: Should return success message",
+		url:            "/books/1",
+		method:         "PATCH",
+		reqBody:        `{"title":"test"}`,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+	{
+		name:           "DeleteBook: Should return success message",
+		url:            "/books/1",
+		method:         "DELETE",
+		reqBody:        ``,
+		expectedStatus: http.StatusOK,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+}
+
+func TestBookHandlerSuccessCase(t *testing.T) {
+	runTestCases(t, &bookServiceMockSuccess{}, testSuccessCases)
+}
+
 // --------------------------------------------------------------------------------------------
 
 type bookServiceMockError struct {
 	BookService
 }
 
-func (m *bookServiceMockError) GetAllBook() ([]Book, error) {
+func (m *bookServiceMockError) GetListBook(ctx context.Context, page, pageSize int) ([]Book, error) {
 	return []Book{}, errors.New("error")
 }
 
-func (m *bookServiceMockError) CreateBook(book BookRequest) error {
+func (m *bookServiceMockError) GetBookByID(ctx context.Context, id int) (*Book, error) {
+	return nil, errors.New("error")
+}
+
+func (m *bookServiceMockError) CreateBook(ctx context.Context, book BookRequest) error {
 	return errors.New("error")
 }
 
-var testErrorCases = []struct {
-	name           string
-	url            string
-	method         string
-	reqBody        string
-	expectedStatus int
-	expectedBody   string
-}{
+func (m *bookServiceMockError) UpdateBook(ctx context.Context, id int, book BookRequest) error {
+	return errors.New("error")
+}
+
+func (m *bookServiceMockError) PatchBook(ctx context.Context, id int, book PatchBookRequest) error {
+	return errors.New("error")
+}
+
+func (m *bookServiceMockError) DeleteBook(ctx context.Context, id int) error {
+	return errors.New("error")
+}
+
+var testErrorCases = []testCase{
 	{
-		name:           "GetAllBook: Should return store error message",
+		name:           "GetListBook: Should return store error message",
 		url:            "/books",
 		method:         "GET",
 		reqBody:        ``,
 		expectedStatus: 507,
 		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
 	},
+	{
+		name:           "GetBookByID: Should return BadRequest error message (ID invalid)",
+		url:            "/books/abc",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+	},
+	{
+		name:           "GetBookByID: Should return store error message",
+		url:            "/books/1",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: 507,
+		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
+	},
 	{
 		name:           "CreateBook: Should return BadRequest error message (Bind error)",
 		url:            "/books",
@@ -136,7 +225,7 @@ var testErrorCases = []struct {
 		method:         "POST",
 		reqBody:        `{"title":"test"}`,
 		expectedStatus: http.StatusBadRequest,
-		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `","errors":[{"field":"author","value":"","tag":"required"}]}`,
 	},
 	{
 		name:           "CreateBook: Should return error message (Store error)",
@@ -146,32 +235,215 @@ var testErrorCases = []struct {
 		expectedStatus: 507,
 		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
 	},
+	{
+		name:           "UpdateBook: Should return BadRequest error message (ID invalid)",
+		url:            "/books/abc",
+		method:         "PUT",
+		reqBody:        `{"title":"test","author":"test"}`,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+	},
+	{
+		name:           "UpdateBook: Should return BadRequest error message (Validate error)",
+		url:            "/books/1",
+		method:         "PUT",
+		reqBody:        `{"title":"test"}`,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `","errors":[{"field":"author","value":"","tag":"required"}]}`,
+	},
+	{
+		name:           "UpdateBook: Should return error message (Store error)",
+		url:            "/books/1",
+		method:         "PUT",
+		reqBody:        `{"title":"test","author":"test"}`,
+		expectedStatus: 507,
+		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
+	},
+	{
+		name:           "PatchBook: Should return BadRequest error message (Bind error)",
+		url:            "/books/1",
+		method:         "PATCH",
+		reqBody:        `{"title":"test"`,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+	},
+	{
+		name:           "PatchBook: Should return BadRequest error message (Validate error)",
+		url:            "/books/1",
+		method:         "PATCH",
+		reqBody:        `{"title":""}`,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `","errors":[{"field":"title","value":"","tag":"min","param":"1"}]}`,
+	},
+	{
+		name:           "PatchBook: Should return error message (Store error)",
+		url:            "/books/1",
+		method:         "PATCH",
+		reqBody:        `{"author":"test"}`,
+		expectedStatus: 507,
+		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
+	},
+	{
+		name:           "DeleteBook: Should return BadRequest error message (ID invalid)",
+		url:            "/books/abc",
+		method:         "DELETE",
+		reqBody:        ``,
+		expectedStatus: http.StatusBadRequest,
+		expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+	},
+	{
+		name:           "DeleteBook: Should return error message (Store error)",
+		url:            "/books/1",
+		method:         "DELETE",
+		reqBody:        ``,
+		expectedStatus: 507,
+		expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
+	},
 }
 
 func TestBookHandlerErrorCase(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	r := gin.Default()
+	runTestCases(t, &bookServiceMockError{}, testErrorCases)
+}
 
-	bookHandler := NewHandler(&bookServiceMockError{})
-	r.GET("/books", toGinHandlerFunc(bookHandler.GetAllBook))
-	r.POST("/books", toGinHandlerFunc(bookHandler.CreateBook))
+// --------------------------------------------------------------------------------------------
 
-	for _, tc := range testErrorCases {
-		t.Run(tc.name, func(t *testing.T) {
-			body := bytes.NewBufferString(tc.reqBody)
+type bookServiceMockCountError struct {
+	bookServiceMockSuccess
+}
 
-			req := httptest.NewRequest(tc.method, tc.url, body)
-			req.Header.Set("Content-Type", "application/json")
-			rec := httptest.NewRecorder()
-			r.ServeHTTP(rec, req)
+func (m *bookServiceMockCountError) CountListBook(ctx context.Context) (int, error) {
+	return 0, errors.New("error")
+}
 
-			if rec.Code != tc.expectedStatus {
-				t.Errorf("expected status %d but got %d", tc.expectedStatus, rec.Code)
-			}
+type bookServiceMockNotFound struct {
+	BookService
+}
 
-			if rec.Body.String() != tc.expectedBody {
-				t.Errorf("expected body %s but got %s", tc.expectedBody, rec.Body.String())
-			}
-		})
-	}
+func (m *bookServiceMockNotFound) GetBookByID(ctx context.Context, id int) (*Book, error) {
+	return nil, ErrBookNotFound
+}
+
+func (m *bookServiceMockNotFound) UpdateBook(ctx context.Context, id int, book BookRequest) error {
+	return ErrBookNotFound
+}
+
+func (m *bookServiceMockNotFound) PatchBook(ctx context.Context, id int, book PatchBookRequest) error {
+	return ErrBookNotFound
+}
+
+func (m *bookServiceMockNotFound) DeleteBook(ctx context.Context, id int) error {
+	return ErrBookNotFound
+}
+
+var testNotFoundCases = []testCase{
+	{
+		name:           "GetBookByID: Should return not found message",
+		url:            "/books/1",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: http.StatusNotFound,
+		expectedBody:   `{"status":"ERROR","message":"` + app.NotFoundMsg + `"}`,
+	},
+	{
+		name:           "UpdateBook: Should return not found message",
+		url:            "/books/1",
+		method:         "PUT",
+		reqBody:        `{"title":"test","author":"test"}`,
+		expectedStatus: http.StatusNotFound,
+		expectedBody:   `{"status":"ERROR","message":"` + app.NotFoundMsg + `"}`,
+	},
+	{
+		name:           "PatchBook: Should return not found message",
+		url:            "/books/1",
+		method:         "PATCH",
+		reqBody:        `{"title":"test"}`,
+		expectedStatus: http.StatusNotFound,
+		expectedBody:   `{"status":"ERROR","message":"` + app.NotFoundMsg + `"}`,
+	},
+	{
+		name:           "DeleteBook: Should return not found message",
+		url:            "/books/1",
+		method:         "DELETE",
+		reqBody:        ``,
+		expectedStatus: http.StatusNotFound,
+		expectedBody:   `{"status":"ERROR","message":"` + app.NotFoundMsg + `"}`,
+	},
+}
+
+func TestBookHandlerNotFoundCase(t *testing.T) {
+	runTestCases(t, &bookServiceMockNotFound{}, testNotFoundCases)
+}
+
+func TestBookHandlerCountErrorCase(t *testing.T) {
+	runTestCases(t, &bookServiceMockCountError{}, []testCase{
+		{
+			name:           "GetListBook: Should return store error message when count fails",
+			url:            "/books",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: 507,
+			expectedBody:   `{"status":"ERROR","message":"` + app.StoreErrorMsg + `"}`,
+		},
+	})
+}
+
+func TestBookHandlerInvalidPagingCase(t *testing.T) {
+	runTestCases(t, &bookServiceMockSuccess{}, []testCase{
+		{
+			name:           "GetListBook: Should return bad request when pageSize is 0",
+			url:            "/books?pageSize=0",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when pageSize is negative",
+			url:            "/books?pageSize=-1",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when pageSize is not a number",
+			url:            "/books?pageSize=abc",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when pageSize is above the maximum",
+			url:            "/books?pageSize=101",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when page is not a number",
+			url:            "/books?page=abc",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when page is above the maximum",
+			url:            "/books?page=21474837",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "GetListBook: Should return bad request when page overflows",
+			url:            "/books?page=9223372036854775807",
+			method:         "GET",
+			reqBody:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+	})
 }
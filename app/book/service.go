@@ -1,20 +1,37 @@
 package book
 
+import (
+	"context"
+	"errors"
+	"go-restapi/app"
+
+	"gorm.io/gorm"
+)
+
 type bookService struct {
 	bookStorage BookStorage
 }
 
 type BookService interface {
-	GetAllBook() ([]Book, error)
-	CreateBook(BookRequest) error
+	GetListBook(context.Context, int, int) ([]Book, error)
+	CountListBook(context.Context) (int, error)
+	GetBookByID(context.Context, int) (*Book, error)
+	CreateBook(context.Context, BookRequest) error
+	UpdateBook(context.Context, int, BookRequest) error
+	PatchBook(context.Context, int, PatchBookRequest) error
+	DeleteBook(context.Context, int) error
 }
 
 func NewBookService(bookStorage BookStorage) BookService {
 	return &bookService{bookStorage: bookStorage}
 }
 
-func (s *bookService) GetAllBook() ([]Book, error) {
-	books, err := s.bookStorage.GetAllBook()
+func (s *bookService) GetListBook(ctx context.Context, page int, pageSize int) ([]Book, error) {
+	ctx, span := app.StartSpan(ctx, "BookService.GetListBook")
+	defer span.End()
+	limit := pageSize
+	offset := (page - 1) * pageSize
+	books, err := s.bookStorage.GetListBook(ctx, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -22,8 +39,81 @@ func (s *bookService) GetAllBook() ([]Book, error) {
 	return result, nil
 }
 
-func (s *bookService) CreateBook(book BookRequest) error {
-	return s.bookStorage.CreateBook(book)
+func (s *bookService) CountListBook(ctx context.Context) (int, error) {
+	ctx, span := app.StartSpan(ctx, "BookService.CountListBook")
+	defer span.End()
+	count, err := s.bookStorage.CountListBook(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (s *bookService) GetBookByID(ctx context.Context, id int) (*Book, error) {
+	ctx, span := app.StartSpan(ctx, "BookService.GetBookByID")
+	defer span.End()
+	book, err := s.getBookModel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := Book(*book)
+	return &result, nil
+}
+
+func (s *bookService) CreateBook(ctx context.Context, book BookRequest) error {
+	ctx, span := app.StartSpan(ctx, "BookService.CreateBook")
+	defer span.End()
+	return s.bookStorage.CreateBook(ctx, book)
+}
+
+func (s *bookService) UpdateBook(ctx context.Context, id int, req BookRequest) error {
+	ctx, span := app.StartSpan(ctx, "BookService.UpdateBook")
+	defer span.End()
+	book, err := s.getBookModel(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	book.Title = req.Title
+	book.Author = req.Author
+	return s.bookStorage.UpdateBook(ctx, *book)
+}
+
+func (s *bookService) PatchBook(ctx context.Context, id int, req PatchBookRequest) error {
+	ctx, span := app.StartSpan(ctx, "BookService.PatchBook")
+	defer span.End()
+	book, err := s.getBookModel(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if req.Title != nil {
+		book.Title = *req.Title
+	}
+	if req.Author != nil {
+		book.Author = *req.Author
+	}
+	return s.bookStorage.UpdateBook(ctx, *book)
+}
+
+func (s *bookService) DeleteBook(ctx context.Context, id int) error {
+	ctx, span := app.StartSpan(ctx, "BookService.DeleteBook")
+	defer span.End()
+	if _, err := s.getBookModel(ctx, id); err != nil {
+		return err
+	}
+	return s.bookStorage.DeleteBook(ctx, id)
+}
+
+func (s *bookService) getBookModel(ctx context.Context, id int) (*BookModel, error) {
+	book, err := s.bookStorage.GetBookByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
 }
 
 func (s *bookService) mappingBookModelToBook(books []BookModel) []Book {
@@ -1,8 +1,11 @@
 package book
 
 import (
+	"context"
 	"errors"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 var bookModelMock = []BookModel{
@@ -20,22 +23,41 @@ var bookModelMock = []BookModel{
 
 type bookStorageMockSuccess struct {
 	BookStorage
+	updated BookModel
 }
 
-func (m *bookStorageMockSuccess) GetAllBook() ([]BookModel, error) {
+func (m *bookStorageMockSuccess) GetListBook(ctx context.Context, limit, offset int) ([]BookModel, error) {
 	return bookModelMock, nil
 }
 
-func (m *bookStorageMockSuccess) CreateBook(book BookRequest) error {
+func (m *bookStorageMockSuccess) CountListBook(ctx context.Context) (int64, error) {
+	return int64(len(bookModelMock)), nil
+}
+
+func (m *bookStorageMockSuccess) GetBookByID(ctx context.Context, id int) (*BookModel, error) {
+	book := bookModelMock[0]
+	return &book, nil
+}
+
+func (m *bookStorageMockSuccess) CreateBook(ctx context.Context, book BookRequest) error {
+	return nil
+}
+
+func (m *bookStorageMockSuccess) UpdateBook(ctx context.Context, book BookModel) error {
+	m.updated = book
+	return nil
+}
+
+func (m *bookStorageMockSuccess) DeleteBook(ctx context.Context, id int) error {
 	return nil
 }
 
 func TestBookServiceSuccessCase(t *testing.T) {
-	t.Run("GetAllBook: Should return array", func(t *testing.T) {
+	t.Run("GetListBook: Should return array", func(t *testing.T) {
 		storage := &bookStorageMockSuccess{}
 		svc := NewBookService(storage)
 
-		books, err := svc.GetAllBook()
+		books, err := svc.GetListBook(context.Background(), 1, 20)
 		if err != nil {
 			t.Errorf("Error should be nil, got: %v", err)
 		}
@@ -45,11 +67,80 @@ func TestBookServiceSuccessCase(t *testing.T) {
 		}
 	})
 
+	t.Run("CountListBook: Should return count", func(t *testing.T) {
+		storage := &bookStorageMockSuccess{}
+		svc := NewBookService(storage)
+
+		count, err := svc.CountListBook(context.Background())
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("Count should be 2, got: %d", count)
+		}
+	})
+
+	t.Run("GetBookByID: Should return book", func(t *testing.T) {
+		storage := &bookStorageMockSuccess{}
+		svc := NewBookService(storage)
+
+		book, err := svc.GetBookByID(context.Background(), 1)
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+
+		if book.ID != 1 {
+			t.Errorf("ID should be 1, got: %d", book.ID)
+		}
+	})
+
 	t.Run("CreateBook: Should return nil", func(t *testing.T) {
 		storage := &bookStorageMockSuccess{}
 		svc := NewBookService(storage)
 
-		err := svc.CreateBook(BookRequest{})
+		err := svc.CreateBook(context.Background(), BookRequest{})
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+	})
+
+	t.Run("UpdateBook: Should replace every field", func(t *testing.T) {
+		storage := &bookStorageMockSuccess{}
+		svc := NewBookService(storage)
+
+		err := svc.UpdateBook(context.Background(), 1, BookRequest{Title: "new title", Author: "new author"})
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+
+		want := BookModel{ID: 1, Title: "new title", Author: "new author"}
+		if storage.updated != want {
+			t.Errorf("Updated book should be %v, got: %v", want, storage.updated)
+		}
+	})
+
+	t.Run("PatchBook: Should only change given fields", func(t *testing.T) {
+		storage := &bookStorageMockSuccess{}
+		svc := NewBookService(storage)
+
+		title := "new title"
+		err := svc.PatchBook(context.Background(), 1, PatchBookRequest{Title: &title})
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+
+		want := BookModel{ID: 1, Title: "new title", Author: "test"}
+		if storage.updated != want {
+			t.Errorf("Updated book should be %v, got: %v", want, storage.updated)
+		}
+	})
+
+	t.Run("DeleteBook: Should return nil", func(t *testing.T) {
+		storage := &bookStorageMockSuccess{}
+		svc := NewBookService(storage)
+
+		err := svc.DeleteBook(context.Background(), 1)
 		if err != nil {
 			t.Errorf("Error should be nil, got: %v", err)
 		}
@@ -62,32 +153,98 @@ type bookStorageMockError struct {
 	BookStorage
 }
 
-func (m *bookStorageMockError) GetAllBook() ([]BookModel, error) {
+func (m *bookStorageMockError) GetListBook(ctx context.Context, limit, offset int) ([]BookModel, error) {
 	return []BookModel{}, errors.New("error")
 }
 
-func (m *bookStorageMockError) CreateBook(book BookRequest) error {
+func (m *bookStorageMockError) CountListBook(ctx context.Context) (int64, error) {
+	return 0, errors.New("error")
+}
+
+func (m *bookStorageMockError) GetBookByID(ctx context.Context, id int) (*BookModel, error) {
+	return nil, errors.New("error")
+}
+
+func (m *bookStorageMockError) CreateBook(ctx context.Context, book BookRequest) error {
 	return errors.New("error")
 }
 
 func TestBookServiceErrorsCase(t *testing.T) {
-	t.Run("GetAllBook: Should return error", func(t *testing.T) {
+	t.Run("GetListBook: Should return error", func(t *testing.T) {
+		storage := &bookStorageMockError{}
+		svc := NewBookService(storage)
+
+		_, err := svc.GetListBook(context.Background(), 1, 20)
+		if err == nil {
+			t.Errorf("Error should be not nil, got: %v", err)
+		}
+	})
+
+	t.Run("CountListBook: Should return error", func(t *testing.T) {
 		storage := &bookStorageMockError{}
 		svc := NewBookService(storage)
 
-		_, err := svc.GetAllBook()
+		_, err := svc.CountListBook(context.Background())
 		if err == nil {
 			t.Errorf("Error should be not nil, got: %v", err)
 		}
 	})
 
+	t.Run("GetBookByID: Should return error", func(t *testing.T) {
+		storage := &bookStorageMockError{}
+		svc := NewBookService(storage)
+
+		_, err := svc.GetBookByID(context.Background(), 1)
+		if err == nil || errors.Is(err, ErrBookNotFound) {
+			t.Errorf("Error should be storage error, got: %v", err)
+		}
+	})
+
 	t.Run("CreateBook: Should return error", func(t *testing.T) {
 		storage := &bookStorageMockError{}
 		svc := NewBookService(storage)
 
-		err := svc.CreateBook(BookRequest{})
+		err := svc.CreateBook(context.Background(), BookRequest{})
 		if err == nil {
 			t.Errorf("Error should be not nil, got: %v", err)
 		}
 	})
 }
+
+// --------------------
+
+type bookStorageMockNotFound struct {
+	BookStorage
+}
+
+func (m *bookStorageMockNotFound) GetBookByID(ctx context.Context, id int) (*BookModel, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestBookServiceNotFoundCase(t *testing.T) {
+	svc := NewBookService(&bookStorageMockNotFound{})
+
+	t.Run("GetBookByID: Should return ErrBookNotFound", func(t *testing.T) {
+		if _, err := svc.GetBookByID(context.Background(), 1); !errors.Is(err, ErrBookNotFound) {
+			t.Errorf("Error should be ErrBookNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("UpdateBook: Should return ErrBookNotFound", func(t *testing.T) {
+		if err := svc.UpdateBook(context.Background(), 1, BookRequest{}); !errors.Is(err, ErrBookNotFound) {
+			t.Errorf("Error should be ErrBookNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("PatchBook: Should return ErrBookNotFound", func(t *testing.T) {
+		if err := svc.PatchBook(context.Background(), 1, PatchBookRequest{}); !errors.Is(err, ErrBookNotFound) {
+			t.Errorf("Error should be ErrBookNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("DeleteBook: Should return ErrBookNotFound", func(t *testing.T) {
+		if err := svc.DeleteBook(context.Background(), 1); !errors.Is(err, ErrBookNotFound) {
+			t.Errorf("Error should be ErrBookNotFound, got: %v", err)
+		}
+	})
+}
@@ -1,35 +1,77 @@
 package book
 
-import "gorm.io/gorm"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 type bookStorage struct {
 	db *gorm.DB
 }
 
 type BookStorage interface {
-	GetAllBook() ([]BookModel, error)
-	CreateBook(BookRequest) error
+	GetListBook(context.Context, int, int) ([]BookModel, error)
+	CountListBook(context.Context) (int64, error)
+	GetBookByID(context.Context, int) (*BookModel, error)
+	CreateBook(context.Context, BookRequest) error
+	UpdateBook(context.Context, BookModel) error
+	DeleteBook(context.Context, int) error
 }
 
 func NewBookStorage(db *gorm.DB) BookStorage {
 	return &bookStorage{db: db}
 }
 
-func (s *bookStorage) GetAllBook() ([]BookModel, error) {
+func (s *bookStorage) GetListBook(ctx context.Context, limit, offset int) ([]BookModel, error) {
 	books := []BookModel{}
-	q := s.db.Table(BookTableName).Find(&books)
+	q := s.db.WithContext(ctx).Table(BookTableName).Limit(limit).Offset(offset).Find(&books)
 	if q.Error != nil {
 		return nil, q.Error
 	}
 	return books, nil
 }
 
-func (s *bookStorage) CreateBook(book BookRequest) error {
+func (s *bookStorage) CountListBook(ctx context.Context) (int64, error) {
+	var count int64
+	q := s.db.WithContext(ctx).Table(BookTableName).Count(&count)
+	if q.Error != nil {
+		return 0, q.Error
+	}
+	return count, nil
+}
+
+func (s *bookStorage) GetBookByID(ctx context.Context, id int) (*BookModel, error) {
+	var book BookModel
+	q := s.db.WithContext(ctx).Table(BookTableName).Where("id = ?", id).First(&book)
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	return &book, nil
+}
+
+func (s *bookStorage) CreateBook(ctx context.Context, book BookRequest) error {
 	bookModel := BookModel{
 		Title:  book.Title,
 		Author: book.Author,
 	}
-	q := s.db.Table(BookTableName).Create(&bookModel)
+	q := s.db.WithContext(ctx).Table(BookTableName).Create(&bookModel)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *bookStorage) UpdateBook(ctx context.Context, book BookModel) error {
+	q := s.db.WithContext(ctx).Table(BookTableName).Save(&book)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *bookStorage) DeleteBook(ctx context.Context, id int) error {
+	q := s.db.WithContext(ctx).Table(BookTableName).Where("id = ?", id).Delete(&BookModel{})
 	if q.Error != nil {
 		return q.Error
 	}
@@ -1,6 +1,7 @@
 package book
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -19,12 +20,12 @@ func TestStorage(t *testing.T) {
 		t.Errorf("Error should be nil, got: %v", err)
 	}
 
-	t.Run("GetAllBook: Should return array", func(t *testing.T) {
+	t.Run("GetListBook: Should return array", func(t *testing.T) {
 		data := sqlmock.NewRows([]string{"id", "title", "author"}).AddRow(1, "test1", "test2").AddRow(2, "test1", "test2")
-		mock.ExpectQuery("SELECT").WillReturnRows(data)
+		mock.ExpectQuery("SELECT \\* FROM `books` LIMIT 10 OFFSET 10").WillReturnRows(data)
 
 		storage := NewBookStorage(gormDB)
-		got, err := storage.GetAllBook()
+		got, err := storage.GetListBook(context.Background(), 10, 10)
 		if err != nil {
 			t.Errorf("Error should be nil, got: %v", err)
 		}
@@ -33,23 +34,70 @@ func TestStorage(t *testing.T) {
 		}
 	})
 
-	t.Run("GetAllBook: Should return error", func(t *testing.T) {
+	t.Run("GetListBook: Should return error", func(t *testing.T) {
 		mock.ExpectQuery("SELECT").WillReturnError(errors.New("Should return error"))
 
 		storage := NewBookStorage(gormDB)
-		_, err := storage.GetAllBook()
+		_, err := storage.GetListBook(context.Background(), 10, 0)
 		if err == nil {
 			t.Errorf("Error should be not nil")
 		}
 	})
 
+	t.Run("CountListBook: Should return count", func(t *testing.T) {
+		mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		storage := NewBookStorage(gormDB)
+		got, err := storage.CountListBook(context.Background())
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+		if got != 2 {
+			t.Errorf("Count should be 2, got: %d", got)
+		}
+	})
+
+	t.Run("CountListBook: Should return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT count").WillReturnError(errors.New("Should return error"))
+
+		storage := NewBookStorage(gormDB)
+		_, err := storage.CountListBook(context.Background())
+		if err == nil {
+			t.Errorf("Error should be not nil")
+		}
+	})
+
+	t.Run("GetBookByID: Should return book", func(t *testing.T) {
+		data := sqlmock.NewRows([]string{"id", "title", "author"}).AddRow(1, "test1", "test2")
+		mock.ExpectQuery("SELECT").WithArgs(1).WillReturnRows(data)
+
+		storage := NewBookStorage(gormDB)
+		got, err := storage.GetBookByID(context.Background(), 1)
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+		if got.ID != 1 {
+			t.Errorf("ID should be 1, got: %d", got.ID)
+		}
+	})
+
+	t.Run("GetBookByID: Should return error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT").WithArgs(1).WillReturnError(gorm.ErrRecordNotFound)
+
+		storage := NewBookStorage(gormDB)
+		_, err := storage.GetBookByID(context.Background(), 1)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Error should be ErrRecordNotFound, got: %v", err)
+		}
+	})
+
 	t.Run("CreateBook: Should return nil", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
 		storage := NewBookStorage(gormDB)
-		err := storage.CreateBook(BookRequest{})
+		err := storage.CreateBook(context.Background(), BookRequest{})
 		if err != nil {
 			t.Errorf("Error should be nil, got: %v", err)
 		}
@@ -61,7 +109,55 @@ func TestStorage(t *testing.T) {
 		mock.ExpectRollback()
 
 		storage := NewBookStorage(gormDB)
-		err := storage.CreateBook(BookRequest{})
+		err := storage.CreateBook(context.Background(), BookRequest{})
+		if err == nil {
+			t.Errorf("Error should be not nil")
+		}
+	})
+
+	t.Run("UpdateBook: Should return nil", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		storage := NewBookStorage(gormDB)
+		err := storage.UpdateBook(context.Background(), BookModel{ID: 1, Title: "test", Author: "test"})
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+	})
+
+	t.Run("UpdateBook: Should return error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE").WillReturnError(errors.New("Should return error"))
+		mock.ExpectRollback()
+
+		storage := NewBookStorage(gormDB)
+		err := storage.UpdateBook(context.Background(), BookModel{ID: 1, Title: "test", Author: "test"})
+		if err == nil {
+			t.Errorf("Error should be not nil")
+		}
+	})
+
+	t.Run("DeleteBook: Should return nil", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		storage := NewBookStorage(gormDB)
+		err := storage.DeleteBook(context.Background(), 1)
+		if err != nil {
+			t.Errorf("Error should be nil, got: %v", err)
+		}
+	})
+
+	t.Run("DeleteBook: Should return error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE").WillReturnError(errors.New("Should return error"))
+		mock.ExpectRollback()
+
+		storage := NewBookStorage(gormDB)
+		err := storage.DeleteBook(context.Background(), 1)
 		if err == nil {
 			t.Errorf("Error should be not nil")
 		}
@@ -1,9 +1,15 @@
 package app
 
 import (
+	gocontext "context"
+	"errors"
+	"fmt"
 	"go-restapi/logger"
+	"go-restapi/metrics"
 	"log/slog"
 	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -12,20 +18,36 @@ import (
 	"github.com/google/uuid"
 )
 
+// Context is both the request/response helper for handlers and the request's
+// context.Context, so handlers can pass it straight to services and storages.
 type Context interface {
+	gocontext.Context
 	Bind(any) error
 	Validate(any) ([]ErrorField, error)
+	// Debugf logs a debug record of the request, written when the logger is
+	// at debug level or the request turned on debug logs, see AllowDebugLog.
+	Debugf(format string, args ...any)
 	OK(any)
 	OKWithPaging(any, Paging)
+	// JSON writes data as is, without the Response envelope, for endpoints
+	// with a format of their own such as health checks.
+	JSON(status int, data any)
 	BadRequest(err error)
+	ValidationError(fields []ErrorField)
+	Unauthorized(err error)
+	Forbidden(err error)
 	StoreError(err error)
 	InternalServerError(err error)
+	HandleError(err error)
 	Conflict(err error)
 	NotFound()
 	GetAllHeader() http.Header
 	GetHeader(string) string
 	GetQuery(string) string
 	GetParam(string) string
+	ClientIP() string
+	GetTokenData() *TokenData
+	SetTokenData(*TokenData)
 }
 
 type context struct {
@@ -36,6 +58,7 @@ type context struct {
 
 func NewContext(c *gin.Context, logHandler slog.Handler) Context {
 	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
 	return &context{
 		Context:    c,
 		logHandler: logHandler,
@@ -43,18 +66,51 @@ func NewContext(c *gin.Context, logHandler slog.Handler) Context {
 	}
 }
 
+func (c *context) requestContext() gocontext.Context {
+	if c.Request == nil {
+		return gocontext.Background()
+	}
+	return c.Request.Context()
+}
+
+// Deadline, Done, Err and Value use the request's context, which is canceled
+// when the client goes away or the request timeout passes.
+func (c *context) Deadline() (time.Time, bool) {
+	return c.requestContext().Deadline()
+}
+
+func (c *context) Done() <-chan struct{} {
+	return c.requestContext().Done()
+}
+
+func (c *context) Err() error {
+	return c.requestContext().Err()
+}
+
+func (c *context) Value(key any) any {
+	return c.requestContext().Value(key)
+}
+
 func (c *context) Bind(v any) error {
 	return c.Context.ShouldBindJSON(v)
 }
 
 func (c *context) Validate(v any) ([]ErrorField, error) {
 	if err := c.validator.Struct(v); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return nil, err
+		}
 		var fields []ErrorField
-		for _, v := range err.(validator.ValidationErrors) {
+		for _, v := range validationErrors {
 			errField := ErrorField{
-				Value: v.Param(),
-				Field: v.Field(),
+				Field: fieldPath(v.Namespace()),
+				Value: v.Value(),
 				Tag:   v.Tag(),
+				Param: v.Param(),
+			}
+			if isSecretField(v.Field()) {
+				errField.Value = logger.RedactedValue
 			}
 			fields = append(fields, errField)
 		}
@@ -63,6 +119,40 @@ func (c *context) Validate(v any) ([]ErrorField, error) {
 	return nil, nil
 }
 
+// jsonFieldName makes validation errors use the JSON name of a field, so the
+// client sees the same name it sent.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// isSecretField reports whether the value of the field must not be echoed
+// back, e.g. "password", "newPassword" or "refreshToken".
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range []string{"password", "secret", "token"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldPath drops the top-level struct name from a validator namespace,
+// e.g. "CreateUserRequest.username" becomes "username".
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
 func (c *context) GetQuery(key string) string {
 	return c.Context.Query(key)
 }
@@ -79,6 +169,19 @@ func (c *context) GetHeader(key string) string {
 	return c.Context.GetHeader(key)
 }
 
+func (c *context) GetTokenData() *TokenData {
+	v, ok := c.Context.Get(tokenDataKey)
+	if !ok {
+		return nil
+	}
+	data, _ := v.(*TokenData)
+	return data
+}
+
+func (c *context) SetTokenData(data *TokenData) {
+	c.Context.Set(tokenDataKey, data)
+}
+
 func (c *context) OK(data any) { // 200
 	c.Context.JSON(http.StatusOK, Response{
 		Status: Success,
@@ -95,99 +198,177 @@ func (c *context) OKWithPaging(data any, paging Paging) { // 200
 }
 
 func (c *context) BadRequest(err error) { // 400
-	logger.AppErrorf(c.logHandler, "%s", err)
-	c.Context.JSON(http.StatusBadRequest, Response{
-		Status:  Fail,
-		Message: BadRequestMsg,
-	})
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	code, title := errorCode(err, CodeBadRequest)
+	c.fail(http.StatusBadRequest, code, title, "", nil)
+}
+
+func (c *context) Unauthorized(err error) { // 401
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	code, title := errorCode(err, CodeUnauthorized)
+	c.fail(http.StatusUnauthorized, code, title, "", nil)
+}
+
+func (c *context) Forbidden(err error) { // 403
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	code, title := errorCode(err, CodeForbidden)
+	c.fail(http.StatusForbidden, code, title, "", nil)
+}
+
+func (c *context) Debugf(format string, args ...any) {
+	logger.AppDebugf(c, c.logHandler, format, args...)
+}
+
+func (c *context) ValidationError(fields []ErrorField) { // 400
+	// Validate fails without fields only when it cannot validate at all, e.g.
+	// for a nil or non-struct value, which is a bug rather than a bad request.
+	if len(fields) == 0 {
+		c.InternalServerError(errors.New("validation failed without field errors"))
+		return
+	}
+	// Only names and tags are logged; values may hold secrets such as passwords.
+	failed := make([]string, 0, len(fields))
+	for _, f := range fields {
+		failed = append(failed, fmt.Sprintf("%s(%s)", f.Field, f.Tag))
+	}
+	logger.AppErrorf(c, c.logHandler, "validation failed: %s", strings.Join(failed, ", "))
+	c.fail(http.StatusBadRequest, CodeValidationFailed, "", "", fields)
+}
+
+// HandleError responds with the status of a CodedError in err's chain, or
+// 500 for any other error.
+func (c *context) HandleError(err error) {
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	var codedErr *CodedError
+	if errors.As(err, &codedErr) {
+		var detail string
+		var retryable RetryableError
+		if errors.As(err, &retryable) {
+			c.Context.Header("Retry-After", retryable.RetryAt().UTC().Format(http.TimeFormat))
+			detail = retryable.Error()
+		}
+		c.fail(codedErr.Status, codedErr.Code, codedErr.Title, detail, nil)
+		return
+	}
+	c.failWithError(http.StatusInternalServerError, CodeInternalServerError, err)
 }
 
 func (c *context) NotFound() { // 404
-	// logger.AppErrorf(c.logHandler, "%s", err)
-	c.Context.JSON(http.StatusNotFound, Response{
-		Status:  Fail,
-		Message: NotFoundMsg,
-	})
+	// logger.AppErrorf(c, c.logHandler, "%s", err)
+	c.fail(http.StatusNotFound, CodeNotFound, "", "", nil)
 }
 
 func (c *context) Conflict(err error) { // 409
-	logger.AppErrorf(c.logHandler, "%s", err)
-	c.Context.JSON(http.StatusConflict, Response{
-		Status:  Fail,
-		Message: ConflictMsg,
-	})
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	code, title := errorCode(err, CodeConflict)
+	c.fail(http.StatusConflict, code, title, "", nil)
 }
 
 func (c *context) StoreError(err error) { // 450
-	logger.AppErrorf(c.logHandler, "%s", err)
-	c.Context.JSON(http.StatusInsufficientStorage, Response{
-		Status:  Fail,
-		Message: StoreErrorMsg,
-	})
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	c.failWithError(http.StatusInsufficientStorage, CodeStoreError, err)
 }
 
 func (c *context) InternalServerError(err error) { // 500
-	logger.AppErrorf(c.logHandler, "%s", err)
-	c.Context.JSON(http.StatusInternalServerError, Response{
-		Status:  Fail,
-		Message: InternalServerErrorMsg,
-	})
+	logger.AppErrorf(c, c.logHandler, "%s", err)
+	c.failWithError(http.StatusInternalServerError, CodeInternalServerError, err)
 }
 
 func NewGinHandler(handler func(Context), logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		transationId := c.Request.Header.Get("transaction-id")
-		if transationId == "" {
-			transationId = uuid.NewString()
-			c.Request.Header.Set("transaction-id", transationId)
-		}
-		handler(NewContext(c, logger.Handler().WithAttrs([]slog.Attr{slog.String("transaction-id", transationId)})))
+		withServerSpan(c, func() {
+			handler(newContextWithTransactionID(c, logger))
+		})
 		// handler(NewContext(c, logger.With(zap.String("transaction-id", c.Request.Header.Get("transaction-id")))))
 	}
 }
 
+func newContextWithTransactionID(c *gin.Context, logger *slog.Logger) Context {
+	return NewContext(c, logger.Handler().WithAttrs([]slog.Attr{slog.String("transaction-id", transactionID(c))}))
+}
+
+// transactionID returns the transaction-id header of the request, generating
+// one when the client sent none. It is also put in the request's context, so
+// records logged outside the handler, such as queries, carry it too.
+func transactionID(c *gin.Context) string {
+	if transationId := logger.TransactionID(c.Request.Context()); transationId != "" {
+		return transationId
+	}
+	transationId := c.Request.Header.Get("transaction-id")
+	if transationId == "" {
+		transationId = uuid.NewString()
+		c.Request.Header.Set("transaction-id", transationId)
+	}
+	c.Request = c.Request.WithContext(logger.WithTransactionID(c.Request.Context(), transationId))
+	return transationId
+}
+
 type Router struct {
 	*gin.Engine
 	logger *slog.Logger
 }
 
-func NewRouter(logger *slog.Logger, conf Config) *Router {
+// NewRouter fails when server.trustedProxies holds something that is not an
+// IP or a CIDR.
+func NewRouter(logger *slog.Logger, conf Config) (*Router, error) {
 	if conf.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	r := gin.Default()
+	r := gin.New()
+	// The client IP keys the login lockout, so X-Forwarded-For is only
+	// believed from the configured proxies, not from any client.
+	if err := r.SetTrustedProxies(conf.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("server.trustedProxies: %w", err)
+	}
+	r.Use(accessLog(logger, conf.Log.AccessLog), gin.Recovery())
+	r.Use(metrics.GinMiddleware())
+	if conf.Server.RequestTimeout > 0 {
+		r.Use(requestTimeout(conf.Server.RequestTimeout))
+	}
 
 	config := cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"X-Requested-With", "Authorization", "Origin", "Content-Length", "Content-Type", "TransactionID"},
+		AllowHeaders:     []string{"X-Requested-With", "Authorization", "Origin", "Content-Length", "Content-Type", "TransactionID", "traceparent", "tracestate", DebugLogHeader},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}
 
 	r.Use(cors.New(config))
 
-	return &Router{Engine: r, logger: logger}
+	return &Router{Engine: r, logger: logger}, nil
 }
 
-func (r *Router) GET(path string, handler func(Context)) {
-	r.Engine.GET(path, NewGinHandler(handler, r.logger))
+func (r *Router) GET(path string, handler func(Context), middlewares ...Middleware) {
+	r.Engine.GET(path, newGinHandlers(handler, middlewares, r.logger)...)
 }
 
-func (r *Router) POST(path string, handler func(Context)) {
-	r.Engine.POST(path, NewGinHandler(handler, r.logger))
+func (r *Router) POST(path string, handler func(Context), middlewares ...Middleware) {
+	r.Engine.POST(path, newGinHandlers(handler, middlewares, r.logger)...)
 }
 
-func (r *Router) PUT(path string, handler func(Context)) {
-	r.Engine.PUT(path, NewGinHandler(handler, r.logger))
+func (r *Router) PUT(path string, handler func(Context), middlewares ...Middleware) {
+	r.Engine.PUT(path, newGinHandlers(handler, middlewares, r.logger)...)
 }
 
-func (r *Router) PATCH(path string, handler func(Context)) {
-	r.Engine.PATCH(path, NewGinHandler(handler, r.logger))
+func (r *Router) PATCH(path string, handler func(Context), middlewares ...Middleware) {
+	r.Engine.PATCH(path, newGinHandlers(handler, middlewares, r.logger)...)
 }
 
-func (r *Router) DELETE(path string, handler func(Context)) {
-	r.Engine.DELETE(path, NewGinHandler(handler, r.logger))
+func (r *Router) DELETE(path string, handler func(Context), middlewares ...Middleware) {
+	r.Engine.DELETE(path, newGinHandlers(handler, middlewares, r.logger)...)
+}
+
+// Use adds middlewares to every route registered on the router afterwards.
+func (r *Router) Use(middlewares ...Middleware) {
+	for _, m := range middlewares {
+		r.Engine.Use(NewGinMiddleware(m, r.logger))
+	}
+}
+
+// Metrics serves the Prometheus metrics at /metrics.
+func (r *Router) Metrics() {
+	r.Engine.GET("/metrics", gin.WrapH(metrics.Handler()))
 }
 
 func (r *Router) NoRoute() {
@@ -207,22 +388,55 @@ func (r *Router) Group(path string) *RouterGroup {
 	}
 }
 
-func (rg *RouterGroup) GET(path string, handler func(Context)) {
-	rg.RouterGroup.GET(path, NewGinHandler(handler, rg.logger))
+func (rg *RouterGroup) Group(path string) *RouterGroup {
+	return &RouterGroup{
+		RouterGroup: rg.RouterGroup.Group(path),
+		logger:      rg.logger,
+	}
+}
+
+// Use adds middlewares to every route registered on the group afterwards.
+func (rg *RouterGroup) Use(middlewares ...Middleware) {
+	for _, m := range middlewares {
+		rg.RouterGroup.Use(NewGinMiddleware(m, rg.logger))
+	}
+}
+
+func (rg *RouterGroup) Authenticate(verify TokenVerifier) {
+	rg.Use(AuthMiddleware(verify))
+}
+
+// AllowDebugLog lets callers whose token grants permission turn on debug
+// logs for their request with the X-Debug-Log header. It must be used under
+// an authenticated group.
+func (rg *RouterGroup) AllowDebugLog(permission string) {
+	rg.RouterGroup.Use(debugLog(permission))
+}
+
+// Require returns a sub-group whose routes need the given permission in the
+// caller's access token. It must be used under an authenticated group.
+func (rg *RouterGroup) Require(permission string) *RouterGroup {
+	g := rg.Group("")
+	g.Use(PermissionMiddleware(permission))
+	return g
+}
+
+func (rg *RouterGroup) GET(path string, handler func(Context), middlewares ...Middleware) {
+	rg.RouterGroup.GET(path, newGinHandlers(handler, middlewares, rg.logger)...)
 }
 
-func (rg *RouterGroup) POST(path string, handler func(Context)) {
-	rg.RouterGroup.POST(path, NewGinHandler(handler, rg.logger))
+func (rg *RouterGroup) POST(path string, handler func(Context), middlewares ...Middleware) {
+	rg.RouterGroup.POST(path, newGinHandlers(handler, middlewares, rg.logger)...)
 }
 
-func (rg *RouterGroup) PUT(path string, handler func(Context)) {
-	rg.RouterGroup.PUT(path, NewGinHandler(handler, rg.logger))
+func (rg *RouterGroup) PUT(path string, handler func(Context), middlewares ...Middleware) {
+	rg.RouterGroup.PUT(path, newGinHandlers(handler, middlewares, rg.logger)...)
 }
 
-func (rg *RouterGroup) PATCH(path string, handler func(Context)) {
-	rg.RouterGroup.PATCH(path, NewGinHandler(handler, rg.logger))
+func (rg *RouterGroup) PATCH(path string, handler func(Context), middlewares ...Middleware) {
+	rg.RouterGroup.PATCH(path, newGinHandlers(handler, middlewares, rg.logger)...)
 }
 
-func (rg *RouterGroup) DELETE(path string, handler func(Context)) {
-	rg.RouterGroup.DELETE(path, NewGinHandler(handler, rg.logger))
+func (rg *RouterGroup) DELETE(path string, handler func(Context), middlewares ...Middleware) {
+	rg.RouterGroup.DELETE(path, newGinHandlers(handler, middlewares, rg.logger)...)
 }
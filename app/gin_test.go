@@ -0,0 +1,127 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type request struct {
+		Name    string  `json:"name,omitempty" validate:"min=3"`
+		Age     int     `validate:"gte=18"`
+		Address address `json:"address"`
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	ctx := NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+
+	fields, err := ctx.Validate(&request{Name: "ab", Age: 17})
+	assert.Error(t, err)
+	assert.Equal(t, []ErrorField{
+		{Field: "name", Value: "ab", Tag: "min", Param: "3"},
+		{Field: "Age", Value: 17, Tag: "gte", Param: "18"},
+		{Field: "address.city", Value: "", Tag: "required"},
+	}, fields)
+
+	ctx.ValidationError(fields)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, `{"status":"ERROR","message":"`+BadRequestMsg+`","errors":[{"field":"name","value":"ab","tag":"min","param":"3"},{"field":"Age","value":17,"tag":"gte","param":"18"},{"field":"address.city","value":"","tag":"required"}]}`, rec.Body.String())
+}
+
+func TestValidateSuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+
+	fields, err := ctx.Validate(&struct {
+		Name string `json:"name" validate:"required"`
+	}{Name: "test"})
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+}
+
+func TestValidateHidesSecretValues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+
+	fields, err := ctx.Validate(&struct {
+		Password    string `json:"password" validate:"min=8"`
+		NewPassword string `json:"newPassword" validate:"min=8"`
+		Name        string `json:"name" validate:"min=8"`
+	}{Password: "short", NewPassword: "short", Name: "short"})
+	assert.Error(t, err)
+	assert.Equal(t, []ErrorField{
+		{Field: "password", Value: "[REDACTED]", Tag: "min", Param: "8"},
+		{Field: "newPassword", Value: "[REDACTED]", Tag: "min", Param: "8"},
+		{Field: "name", Value: "short", Tag: "min", Param: "8"},
+	}, fields)
+}
+
+func TestValidateInvalidValue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	ctx := NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+
+	fields, err := ctx.Validate(nil)
+	assert.Error(t, err)
+	assert.Nil(t, fields)
+
+	ctx.ValidationError(fields)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestNewRouterTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		expectedIP     string
+	}{
+		{name: "Should ignore X-Forwarded-For without trusted proxies", trustedProxies: nil, expectedIP: "192.0.2.1"},
+		{name: "Should ignore X-Forwarded-For from other proxies", trustedProxies: []string{"10.0.0.0/8"}, expectedIP: "192.0.2.1"},
+		{name: "Should use X-Forwarded-For from a trusted proxy", trustedProxies: []string{"192.0.2.1"}, expectedIP: "203.0.113.7"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewRouter(logger, Config{Server: Server{TrustedProxies: tc.trustedProxies}})
+			if !assert.NoError(t, err) {
+				return
+			}
+			r.GET("/ip", func(ctx Context) {
+				ctx.OK(ctx.ClientIP())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Contains(t, rec.Body.String(), `"data":"`+tc.expectedIP+`"`)
+		})
+	}
+
+	_, err := NewRouter(logger, Config{Server: Server{TrustedProxies: []string{"not-an-ip"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package health
+
+import (
+	"context"
+	"go-restapi/app/keys"
+
+	"gorm.io/gorm"
+)
+
+// DatabaseCheck pings the database behind db.
+func DatabaseCheck(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// SigningKeyCheck makes sure there is a key to sign JWTs with, since login
+// and token refresh fail without it.
+func SigningKeyCheck(signer keys.Signer) Check {
+	return func(ctx context.Context) error {
+		_, err := signer.SigningKey()
+		return err
+	}
+}
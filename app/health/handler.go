@@ -0,0 +1,35 @@
+package health
+
+import (
+	"go-restapi/app"
+	"net/http"
+)
+
+type HealthHandler interface {
+	Liveness(ctx app.Context)
+	Readiness(ctx app.Context)
+}
+
+type healthHandler struct {
+	checker *Checker
+}
+
+func NewHealthHandler(checker *Checker) HealthHandler {
+	return &healthHandler{checker: checker}
+}
+
+// Liveness only tells that the process is up and serving requests.
+func (h *healthHandler) Liveness(ctx app.Context) {
+	ctx.JSON(http.StatusOK, Report{Status: StatusUp, Checks: map[string]CheckResult{}})
+}
+
+// Readiness responds 200 when every check passes and 503 otherwise, with the
+// result of each check in the body.
+func (h *healthHandler) Readiness(ctx app.Context) {
+	report := h.checker.Check(ctx)
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
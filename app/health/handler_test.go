@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-restapi/app"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func toGinHandlerFunc(f func(ctx app.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := app.NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+		f(ctx)
+	}
+}
+
+func serve(checker *Checker, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := NewHealthHandler(checker)
+	r.GET("/healthz", toGinHandlerFunc(handler.Liveness))
+	r.GET("/readyz", toGinHandlerFunc(handler.Readiness))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Run("Liveness: Should return 200 even when checks fail", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error { return errors.New("down") })
+
+		rec := serve(checker, "/healthz")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"UP","checks":{}}`, rec.Body.String())
+	})
+
+	t.Run("Readiness: Should return 200 when ready", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error { return nil })
+
+		rec := serve(checker, "/readyz")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report Report
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	})
+
+	t.Run("Readiness: Should return 503 when a check fails", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error { return errors.New("down") })
+
+		rec := serve(checker, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		var report Report
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, "down", report.Checks["database"].Error)
+	})
+
+	t.Run("Readiness: Should return 503 after Drain", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Drain()
+
+		rec := serve(checker, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
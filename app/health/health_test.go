@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	t.Run("Should be up when every check passes", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", up)
+		checker.Add("privateKey", up)
+
+		report := checker.Check(context.Background())
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Len(t, report.Checks, 2)
+		assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	})
+
+	t.Run("Should be down when a check fails", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", down)
+		checker.Add("privateKey", up)
+
+		report := checker.Check(context.Background())
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, CheckResult{Status: StatusDown, LatencyMs: report.Checks["database"].LatencyMs, Error: "connection refused"}, report.Checks["database"])
+		assert.Equal(t, StatusUp, report.Checks["privateKey"].Status)
+	})
+
+	t.Run("Should time out a slow check", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := checker.Check(ctx)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, context.Canceled.Error(), report.Checks["slow"].Error)
+	})
+
+	t.Run("Should be down once draining", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", up)
+		checker.Drain()
+
+		report := checker.Check(context.Background())
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+	})
+}
@@ -0,0 +1,24 @@
+package keys
+
+import (
+	"go-restapi/app"
+	"net/http"
+)
+
+type KeysHandler interface {
+	JWKS(ctx app.Context)
+}
+
+type keysHandler struct {
+	manager *Manager
+}
+
+func NewKeysHandler(manager *Manager) KeysHandler {
+	return &keysHandler{manager: manager}
+}
+
+// JWKS serves the public keys as a JSON Web Key Set, so other services can
+// verify access tokens.
+func (h *keysHandler) JWKS(ctx app.Context) {
+	ctx.JSON(http.StatusOK, h.manager.JWKS())
+}
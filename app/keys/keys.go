@@ -0,0 +1,192 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms lists the signing algorithms access tokens may use.
+var Algorithms = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodES384.Alg(),
+	jwt.SigningMethodES512.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	ErrWeakKey        = errors.New("rsa key shorter than 2048 bits")
+	ErrNoPEMBlock     = errors.New("no pem block")
+)
+
+// Key is a key tokens are signed or verified with, named by its kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	// Private is nil for keys that only verify, such as a retired key kept
+	// as its public half.
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// NewKey wraps an RSA, ECDSA or Ed25519 key, private or public. RSA keys
+// sign with RS256, ECDSA keys with the ES algorithm of their curve and
+// Ed25519 keys with EdDSA.
+func NewKey(id string, key any) (*Key, error) {
+	k := &Key{ID: id}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.Private, k.Public = key, &key.PublicKey
+	case *ecdsa.PrivateKey:
+		k.Private, k.Public = key, &key.PublicKey
+	case ed25519.PrivateKey:
+		k.Private, k.Public = key, key.Public()
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		k.Public = key
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, ErrWeakKey
+		}
+		k.Algorithm = jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			k.Algorithm = jwt.SigningMethodES256.Alg()
+		case elliptic.P384():
+			k.Algorithm = jwt.SigningMethodES384.Alg()
+		case elliptic.P521():
+			k.Algorithm = jwt.SigningMethodES512.Alg()
+		default:
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		k.Algorithm = jwt.SigningMethodEdDSA.Alg()
+	}
+	return k, nil
+}
+
+// ParseKey reads a PEM encoded key: PKCS#1, SEC 1 or PKCS#8 for private
+// keys, PKIX or PKCS#1 for public ones.
+func ParseKey(id string, data []byte) (*Key, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrNoPEMBlock
+		}
+
+		var key any
+		var err error
+		switch block.Type {
+		case "EC PARAMETERS":
+			// Written by openssl ecparam -genkey before the key itself.
+			continue
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			return nil, fmt.Errorf("%w: pem block %q", ErrUnsupportedKey, block.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(id, key)
+	}
+}
+
+// Method returns the JWT signing method of the key.
+func (k *Key) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// JWK is the public half of a key as a JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public half of the key.
+func (k *Key) JWK() JWK {
+	jwk := JWK{ID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(pub.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		x, y := ecCoordinates(pub)
+		jwk.KeyType = "EC"
+		jwk.Curve = pub.Curve.Params().Name
+		jwk.X = encode(x)
+		jwk.Y = encode(y)
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(pub)
+	}
+	return jwk
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the key, used as its kid
+// when it is not named.
+func (k *Key) Thumbprint() string {
+	jwk := k.JWK()
+	var members string
+	switch jwk.KeyType {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Curve, jwk.X, jwk.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return encode(sum[:])
+}
+
+// ecCoordinates returns x and y padded to the size of the curve.
+func ecCoordinates(pub *ecdsa.PublicKey) ([]byte, []byte) {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return pub.X.FillBytes(make([]byte, size)), pub.Y.FillBytes(make([]byte, size))
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
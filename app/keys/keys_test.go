@@ -0,0 +1,111 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	assert.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	assert.NoError(t, err)
+	pkix, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+
+	encode := func(blocks ...*pem.Block) []byte {
+		var data []byte
+		for _, b := range blocks {
+			data = append(data, pem.EncodeToMemory(b)...)
+		}
+		return data
+	}
+
+	testCases := []struct {
+		name          string
+		data          []byte
+		wantAlgorithm string
+		wantPrivate   bool
+	}{
+		{"Should parse PKCS#1 RSA key", encode(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), "RS256", true},
+		{"Should parse PKCS#8 key", encode(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), "RS256", true},
+		{"Should parse EC key after its parameters", encode(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0}}, &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), "ES384", true},
+		{"Should parse public key", encode(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}), "ES384", false},
+		{"Should parse Ed25519 key", encode(&pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8}), "EdDSA", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k, err := ParseKey("kid", tc.data)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "kid", k.ID)
+				assert.Equal(t, tc.wantAlgorithm, k.Algorithm)
+				assert.Equal(t, tc.wantPrivate, k.Private != nil)
+			}
+		})
+	}
+
+	t.Run("Should reject short RSA keys", func(t *testing.T) {
+		short, err := rsa.GenerateKey(rand.Reader, 1024)
+		assert.NoError(t, err)
+		_, err = ParseKey("kid", encode(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(short)}))
+		assert.ErrorIs(t, err, ErrWeakKey)
+	})
+
+	t.Run("Should reject data without key", func(t *testing.T) {
+		_, err := ParseKey("kid", []byte("not a key"))
+		assert.ErrorIs(t, err, ErrNoPEMBlock)
+	})
+}
+
+func TestJWK(t *testing.T) {
+	t.Run("Should match the RFC 7638 thumbprint", func(t *testing.T) {
+		n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+		assert.NoError(t, err)
+		k, err := NewKey("", &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537})
+		assert.NoError(t, err)
+
+		assert.Equal(t, "AQAB", k.JWK().E)
+		assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", k.Thumbprint())
+	})
+
+	t.Run("Should encode Ed25519 keys as OKP", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		k, err := NewKey("kid", pub)
+		assert.NoError(t, err)
+
+		assert.Equal(t, JWK{KeyType: "OKP", ID: "kid", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}, k.JWK())
+	})
+
+	t.Run("Should pad EC coordinates to the curve size", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		k, err := NewKey("kid", ecKey)
+		assert.NoError(t, err)
+
+		jwk := k.JWK()
+		assert.Equal(t, JWK{KeyType: "EC", ID: "kid", Use: "sig", Algorithm: "ES256", Curve: "P-256", X: jwk.X, Y: jwk.Y}, jwk)
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		assert.NoError(t, err)
+		assert.Len(t, x, 32)
+		assert.Zero(t, new(big.Int).SetBytes(x).Cmp(ecKey.X))
+	})
+}
@@ -0,0 +1,237 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restapi/app"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyFile is loaded as the only key when no key directory is set.
+const LegacyKeyFile = "./config/private.pem"
+
+var (
+	ErrNoSigningKey = errors.New("no signing key")
+	ErrUnknownKey   = errors.New("unknown key id")
+	ErrKeyAlgorithm = errors.New("token algorithm does not match its key")
+)
+
+// Signer hands out the key new tokens are signed with.
+type Signer interface {
+	SigningKey() (*Key, error)
+}
+
+// Manager holds the keys access tokens are signed and verified with. The
+// active key signs; every other key only verifies, so tokens signed before a
+// rotation stay valid until they expire.
+type Manager struct {
+	conf      app.Keys
+	algorithm string
+	retain    time.Duration
+	now       func() time.Time
+
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+	// removed records when keys left the directory. They keep verifying for
+	// retain, the lifetime of the tokens they signed.
+	removed map[string]time.Time
+}
+
+// NewManager loads the keys of conf.Dir. Only keys of algorithm sign, any
+// when it is empty. retain is how long a key removed from the directory keeps
+// verifying, which should be the lifetime of an access token.
+func NewManager(conf app.Keys, algorithm string, retain time.Duration) (*Manager, error) {
+	m := &Manager{conf: conf, algorithm: algorithm, retain: retain, now: time.Now, removed: map[string]time.Time{}}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewStaticManager returns a Manager signing with active and verifying with
+// active and others, for tests and fixed key sets.
+func NewStaticManager(active *Key, others ...*Key) *Manager {
+	m := &Manager{now: time.Now, active: active, keys: map[string]*Key{active.ID: active}, removed: map[string]time.Time{}}
+	for _, k := range others {
+		m.keys[k.ID] = k
+	}
+	return m
+}
+
+// Reload reads the key directory again. Keys gone from it are kept until
+// retain has passed. On error the keys loaded before stay in use.
+func (m *Manager) Reload() error {
+	loaded, err := m.load()
+	if err != nil {
+		return err
+	}
+	active, err := m.pickActive(loaded)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, k := range m.keys {
+		if _, ok := loaded[id]; ok {
+			delete(m.removed, id)
+			continue
+		}
+		if _, ok := m.removed[id]; !ok {
+			m.removed[id] = now
+		}
+		if now.Sub(m.removed[id]) < m.retain {
+			loaded[id] = k
+		} else {
+			delete(m.removed, id)
+		}
+	}
+	m.active, m.keys = active, loaded
+	return nil
+}
+
+func (m *Manager) load() (map[string]*Key, error) {
+	if m.conf.Dir == "" {
+		data, err := os.ReadFile(LegacyKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParseKey("", data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", LegacyKeyFile, err)
+		}
+		k.ID = k.Thumbprint()
+		return map[string]*Key{k.ID: k}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.conf.Dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]*Key, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParseKey(strings.TrimSuffix(filepath.Base(file), ".pem"), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		loaded[k.ID] = k
+	}
+	return loaded, nil
+}
+
+// pickActive returns the configured active key, or else the private key of
+// the algorithm whose kid sorts last, so naming keys by date rotates to the
+// newest.
+func (m *Manager) pickActive(loaded map[string]*Key) (*Key, error) {
+	if m.conf.Active != "" {
+		k, ok := loaded[m.conf.Active]
+		if !ok || !m.canSign(k) {
+			return nil, fmt.Errorf("%w: %q", ErrNoSigningKey, m.conf.Active)
+		}
+		return k, nil
+	}
+
+	var active *Key
+	for _, k := range loaded {
+		if m.canSign(k) && (active == nil || k.ID > active.ID) {
+			active = k
+		}
+	}
+	if active == nil {
+		if m.algorithm != "" {
+			return nil, fmt.Errorf("%w: no %s private key", ErrNoSigningKey, m.algorithm)
+		}
+		return nil, ErrNoSigningKey
+	}
+	return active, nil
+}
+
+func (m *Manager) canSign(k *Key) bool {
+	return k.Private != nil && (m.algorithm == "" || k.Algorithm == m.algorithm)
+}
+
+// Watch reloads the keys every interval until ctx is done. Failed reloads
+// are logged and keep the keys loaded before.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(); err != nil {
+				logger.ErrorContext(ctx, "reload signing keys: "+err.Error())
+			}
+		}
+	}
+}
+
+// SigningKey returns the active key.
+func (m *Manager) SigningKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.active == nil {
+		return nil, ErrNoSigningKey
+	}
+	return m.active, nil
+}
+
+// Keyfunc finds the key of a token by its kid header. Tokens without kid,
+// signed before keys had one, are checked against the active key.
+func (m *Manager) Keyfunc(t *jwt.Token) (any, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	k := m.active
+	if kid, ok := t.Header["kid"]; ok {
+		id, _ := kid.(string)
+		if k, ok = m.keys[id]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+		}
+	}
+	if k == nil {
+		return nil, ErrNoSigningKey
+	}
+	if t.Method.Alg() != k.Algorithm {
+		return nil, ErrKeyAlgorithm
+	}
+	return k.Public, nil
+}
+
+// JWKS returns the public keys, the active one first.
+func (m *Manager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(m.keys))}
+	for _, k := range m.keys {
+		jwks.Keys = append(jwks.Keys, k.JWK())
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		a, b := jwks.Keys[i], jwks.Keys[j]
+		if m.active != nil && (a.ID == m.active.ID) != (b.ID == m.active.ID) {
+			return a.ID == m.active.ID
+		}
+		return a.ID < b.ID
+	})
+	return jwks
+}
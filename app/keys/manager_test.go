@@ -0,0 +1,170 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"go-restapi/app"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRSAKey(t *testing.T, dir, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+	return key
+}
+
+func writePublicKey(t *testing.T, dir, kid string, pub any) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func tokenWithKid(method jwt.SigningMethod, kid string) *jwt.Token {
+	t := jwt.New(method)
+	if kid != "" {
+		t.Header["kid"] = kid
+	}
+	return t
+}
+
+func TestManager(t *testing.T) {
+	t.Run("Should sign with the key whose kid sorts last", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "2023-09-01")
+		newest := writeRSAKey(t, dir, "2023-10-01")
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		writePublicKey(t, dir, "2023-12-01", &ecKey.PublicKey)
+
+		m, err := NewManager(app.Keys{Dir: dir}, "", time.Hour)
+		if !assert.NoError(t, err) {
+			return
+		}
+		k, err := m.SigningKey()
+		assert.NoError(t, err)
+		assert.Equal(t, "2023-10-01", k.ID)
+		assert.Equal(t, newest, k.Private)
+
+		jwks := m.JWKS()
+		if assert.Len(t, jwks.Keys, 3) {
+			assert.Equal(t, []string{"2023-10-01", "2023-09-01", "2023-12-01"}, []string{jwks.Keys[0].ID, jwks.Keys[1].ID, jwks.Keys[2].ID})
+		}
+	})
+
+	t.Run("Should sign with the configured key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "a")
+		writeRSAKey(t, dir, "b")
+
+		m, err := NewManager(app.Keys{Dir: dir, Active: "a"}, "", time.Hour)
+		if assert.NoError(t, err) {
+			k, _ := m.SigningKey()
+			assert.Equal(t, "a", k.ID)
+		}
+
+		_, err = NewManager(app.Keys{Dir: dir, Active: "c"}, "", time.Hour)
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+
+	t.Run("Should only sign with keys of the algorithm", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "b")
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+
+		m, err := NewManager(app.Keys{Dir: dir}, "ES256", time.Hour)
+		if assert.NoError(t, err) {
+			k, _ := m.SigningKey()
+			assert.Equal(t, "a", k.ID)
+		}
+
+		_, err = NewManager(app.Keys{Dir: dir}, "EdDSA", time.Hour)
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+
+	t.Run("Should fail without a private key", func(t *testing.T) {
+		_, err := NewManager(app.Keys{Dir: t.TempDir()}, "", time.Hour)
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+
+	t.Run("Should keep a removed key until its tokens expire", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "old")
+		m, err := NewManager(app.Keys{Dir: dir}, "", time.Hour)
+		if !assert.NoError(t, err) {
+			return
+		}
+		now := time.Now()
+		m.now = func() time.Time { return now }
+
+		writeRSAKey(t, dir, "new")
+		assert.NoError(t, os.Remove(filepath.Join(dir, "old.pem")))
+		assert.NoError(t, m.Reload())
+		k, _ := m.SigningKey()
+		assert.Equal(t, "new", k.ID)
+		_, err = m.Keyfunc(tokenWithKid(jwt.SigningMethodRS256, "old"))
+		assert.NoError(t, err)
+
+		now = now.Add(time.Hour)
+		assert.NoError(t, m.Reload())
+		_, err = m.Keyfunc(tokenWithKid(jwt.SigningMethodRS256, "old"))
+		assert.ErrorIs(t, err, ErrUnknownKey)
+		assert.Len(t, m.JWKS().Keys, 1)
+	})
+
+	t.Run("Should keep the keys when a reload fails", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRSAKey(t, dir, "a")
+		m, err := NewManager(app.Keys{Dir: dir}, "", time.Hour)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.pem"), []byte("broken"), 0o600))
+		assert.Error(t, m.Reload())
+		k, err := m.SigningKey()
+		assert.NoError(t, err)
+		assert.Equal(t, "a", k.ID)
+	})
+}
+
+func TestManagerKeyfunc(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	active, err := NewKey("active", rsaKey)
+	assert.NoError(t, err)
+	m := NewStaticManager(active)
+
+	t.Run("Should return the key named by kid", func(t *testing.T) {
+		got, err := m.Keyfunc(tokenWithKid(jwt.SigningMethodRS256, "active"))
+		assert.NoError(t, err)
+		assert.Equal(t, &rsaKey.PublicKey, got)
+	})
+
+	t.Run("Should use the active key without kid", func(t *testing.T) {
+		got, err := m.Keyfunc(tokenWithKid(jwt.SigningMethodRS256, ""))
+		assert.NoError(t, err)
+		assert.Equal(t, &rsaKey.PublicKey, got)
+	})
+
+	t.Run("Should reject an algorithm other than the key's", func(t *testing.T) {
+		_, err := m.Keyfunc(tokenWithKid(jwt.SigningMethodES256, "active"))
+		assert.ErrorIs(t, err, ErrKeyAlgorithm)
+	})
+}
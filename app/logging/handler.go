@@ -0,0 +1,56 @@
+// Package logging lets admins read and change the log level at runtime.
+package logging
+
+import (
+	"fmt"
+	"go-restapi/app"
+	"go-restapi/logger"
+	"net/http"
+)
+
+var ErrInvalidLogLevel = app.NewCodedError("INVALID_LOG_LEVEL", http.StatusBadRequest, "invalid log level")
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+type SetLogLevelRequest struct {
+	Level string `json:"level" validate:"required"`
+}
+
+type LoggingHandler interface {
+	GetLogLevel(ctx app.Context)
+	SetLogLevel(ctx app.Context)
+}
+
+type loggingHandler struct{}
+
+func NewLoggingHandler() LoggingHandler {
+	return &loggingHandler{}
+}
+
+func (h *loggingHandler) GetLogLevel(ctx app.Context) {
+	ctx.OK(LogLevelResponse{Level: logger.Level().String()})
+}
+
+// SetLogLevel changes the level of every logger sharing the process-wide
+// level, effective from the next record.
+func (h *loggingHandler) SetLogLevel(ctx app.Context) {
+	var req SetLogLevelRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := logger.SetLevel(req.Level); err != nil {
+		ctx.HandleError(fmt.Errorf("%w: %s", ErrInvalidLogLevel, err))
+		return
+	}
+
+	ctx.OK(LogLevelResponse{Level: logger.Level().String()})
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"go-restapi/app"
+	"go-restapi/logger"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func toGinHandlerFunc(f func(ctx app.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := app.NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+		f(ctx)
+	}
+}
+
+func TestLoggingHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := NewLoggingHandler()
+	r.GET("/log-level", toGinHandlerFunc(handler.GetLogLevel))
+	r.PUT("/log-level", toGinHandlerFunc(handler.SetLogLevel))
+
+	assert.NoError(t, logger.SetLevel("info"))
+	t.Cleanup(func() { _ = logger.SetLevel("info") })
+
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Should return the current level",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":"","data":{"level":"INFO"}}`,
+		},
+		{
+			name:           "Should change the level",
+			method:         http.MethodPut,
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":"","data":{"level":"DEBUG"}}`,
+		},
+		{
+			name:           "Should keep the changed level",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":"","data":{"level":"DEBUG"}}`,
+		},
+		{
+			name:           "Should reject an unknown level",
+			method:         http.MethodPut,
+			body:           `{"level":"loud"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "Should require a level",
+			method:         http.MethodPut,
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/log-level", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+	assert.Equal(t, slog.LevelDebug, logger.Level())
+}
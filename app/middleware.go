@@ -0,0 +1,113 @@
+package app
+
+import (
+	gocontext "context"
+	"go-restapi/metrics"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Next runs the rest of the handler chain.
+type Next func()
+
+// Middleware runs before a handler. Returning without calling next stops the
+// chain, so the middleware must write the response itself.
+type Middleware func(ctx Context, next Next)
+
+func NewGinMiddleware(middleware Middleware, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withServerSpan(c, func() {
+			called := false
+			middleware(newContextWithTransactionID(c, logger), func() {
+				called = true
+				c.Next()
+			})
+			if !called {
+				c.Abort()
+			}
+		})
+	}
+}
+
+func newGinHandlers(handler func(Context), middlewares []Middleware, logger *slog.Logger) []gin.HandlerFunc {
+	handlers := make([]gin.HandlerFunc, 0, len(middlewares)+1)
+	for _, m := range middlewares {
+		handlers = append(handlers, NewGinMiddleware(m, logger))
+	}
+	return append(handlers, NewGinHandler(handler, logger))
+}
+
+// requestTimeout gives every request a deadline, so queries still running
+// when it passes are canceled.
+func requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := gocontext.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// accessLog writes one record per request through logger: Info below 400,
+// Warn below 500 and Error above.
+func accessLog(logger *slog.Logger, conf AccessLog) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(conf.Exclude))
+	for _, path := range conf.Exclude {
+		excluded[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		transactionID := transactionID(c)
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+		if excluded[c.Request.URL.Path] || excluded[route] {
+			return
+		}
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && conf.SuccessSampleRatio > 0 && rand.Float64() >= conf.SuccessSampleRatio {
+			return
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		ctx := c.Request.Context()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("transaction-id", transactionID),
+		}
+		if v, ok := c.Get(tokenDataKey); ok {
+			if data, ok := v.(*TokenData); ok && data != nil {
+				attrs = append(attrs, slog.Int("user_id", data.UserID))
+			}
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+		}
+		logger.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
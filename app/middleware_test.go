@@ -0,0 +1,185 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(ctx Context, next Next) {
+			calls = append(calls, name)
+			next()
+		}
+	}
+	block := func(ctx Context, next Next) {
+		calls = append(calls, "block")
+		ctx.BadRequest(errors.New("blocked"))
+	}
+	handler := func(ctx Context) {
+		calls = append(calls, "handler")
+		ctx.OK(nil)
+	}
+
+	r := &Router{Engine: gin.New(), logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+	r.Use(trace("router"))
+	g := r.Group("/api")
+	g.Use(trace("group"))
+	g.GET("/ok", handler, trace("route"))
+	g.GET("/blocked", handler, block, trace("route"))
+	r.GET("/root", handler)
+
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedCalls  []string
+	}{
+		{
+			name:           "Should run router, group and route middleware in order",
+			url:            "/api/ok",
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []string{"router", "group", "route", "handler"},
+		},
+		{
+			name:           "Should stop the chain when next is not called",
+			url:            "/api/blocked",
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []string{"router", "group", "block"},
+		},
+		{
+			name:           "Should not run group middleware outside the group",
+			url:            "/root",
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []string{"router", "handler"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestMiddlewareWithContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Should be callable directly with a Context", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx := NewContext(c, slog.NewJSONHandler(os.Stdout, nil))
+
+		called := false
+		PermissionMiddleware("books:read")(ctx, func() { called = true })
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := &Router{Engine: gin.New(), logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+	r.Engine.Use(requestTimeout(time.Millisecond))
+	r.GET("/slow", func(ctx Context) {
+		<-ctx.Done()
+		ctx.StoreError(ctx.Err())
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.JSONEq(t, `{"status":"ERROR","message":"`+TimeoutMsg+`"}`, rec.Body.String())
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(conf AccessLog) (*Router, *bytes.Buffer) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		r := &Router{Engine: gin.New(), logger: logger}
+		r.Engine.Use(accessLog(logger, conf))
+		r.GET("/books/:id", func(ctx Context) { ctx.OK("book") }, func(ctx Context, next Next) {
+			ctx.SetTokenData(&TokenData{UserID: 7})
+			next()
+		})
+		r.GET("/healthz", func(ctx Context) { ctx.OK(nil) })
+		r.NoRoute()
+		return r, &logs
+	}
+
+	serve := func(r *Router, path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("transaction-id", "tx-1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("Should log one record per request", func(t *testing.T) {
+		r, logs := newRouter(AccessLog{})
+		serve(r, "/books/1")
+
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "request", record["msg"])
+		assert.Equal(t, "GET", record["method"])
+		assert.Equal(t, "/books/:id", record["route"])
+		assert.Equal(t, float64(http.StatusOK), record["status"])
+		assert.Greater(t, record["bytes"], float64(0))
+		assert.Equal(t, float64(7), record["user_id"])
+		assert.Equal(t, "tx-1", record["transaction-id"])
+		assert.Contains(t, record, "latency")
+		assert.Contains(t, record, "client_ip")
+	})
+
+	t.Run("Should log failed requests as warnings", func(t *testing.T) {
+		r, logs := newRouter(AccessLog{})
+		serve(r, "/no/such/path")
+
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))[0], &record))
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "unmatched", record["route"])
+		assert.NotContains(t, record, "user_id")
+	})
+
+	t.Run("Should skip excluded paths", func(t *testing.T) {
+		r, logs := newRouter(AccessLog{Exclude: []string{"/healthz"}})
+		serve(r, "/healthz")
+
+		assert.Empty(t, logs.String())
+	})
+
+	t.Run("Should sample successful requests only", func(t *testing.T) {
+		r, logs := newRouter(AccessLog{SuccessSampleRatio: 1e-9})
+		serve(r, "/books/1")
+		assert.Empty(t, logs.String())
+
+		serve(r, "/no/such/path")
+		assert.Contains(t, logs.String(), `"route":"unmatched"`)
+	})
+}
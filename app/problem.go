@@ -0,0 +1,149 @@
+package app
+
+import (
+	gocontext "context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeURI prefixes the error code to build the RFC 7807 "type" member.
+const ProblemTypeURI = "urn:go-restapi:problem:"
+
+const (
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeConflict            = "CONFLICT"
+	CodeStoreError          = "STORE_ERROR"
+	CodeInternalServerError = "INTERNAL_SERVER_ERROR"
+)
+
+// ErrRequestTimeout is reported when the request's deadline passed before a
+// query or other work could finish.
+var ErrRequestTimeout = NewCodedError("REQUEST_TIMEOUT", http.StatusGatewayTimeout, "request timed out")
+
+// CodedError is a domain error with a stable, machine-readable code and the
+// HTTP status it maps to.
+type CodedError struct {
+	Code   string
+	Status int
+	Title  string
+}
+
+func NewCodedError(code string, status int, title string) *CodedError {
+	return &CodedError{
+		Code:   code,
+		Status: status,
+		Title:  title,
+	}
+}
+
+func (e *CodedError) Error() string {
+	return e.Title
+}
+
+// RetryableError is an error that passes at RetryAt, such as a temporary
+// lock. HandleError tells the client when with the Retry-After header and
+// uses the error's message as the problem detail.
+type RetryableError interface {
+	error
+	RetryAt() time.Time
+}
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []ErrorField `json:"errors,omitempty"`
+}
+
+func statusMessage(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return BadRequestMsg
+	case http.StatusUnauthorized:
+		return UnauthorizedMsg
+	case http.StatusForbidden:
+		return ForbiddenMsg
+	case http.StatusNotFound:
+		return NotFoundMsg
+	case http.StatusConflict:
+		return ConflictMsg
+	case http.StatusTooManyRequests:
+		return TooManyRequestsMsg
+	case http.StatusInsufficientStorage:
+		return StoreErrorMsg
+	case http.StatusGatewayTimeout:
+		return TimeoutMsg
+	default:
+		return InternalServerErrorMsg
+	}
+}
+
+// errorCode returns the code and title of a CodedError in err's chain, or
+// fallback and no title.
+func errorCode(err error, fallback string) (string, string) {
+	var codedErr *CodedError
+	if errors.As(err, &codedErr) {
+		return codedErr.Code, codedErr.Title
+	}
+	return fallback, ""
+}
+
+// failWithError is fail for an unexpected error: a passed request deadline
+// becomes ErrRequestTimeout, anything else gets the given status.
+func (c *context) failWithError(status int, fallback string, err error) {
+	if errors.Is(err, gocontext.DeadlineExceeded) {
+		c.fail(ErrRequestTimeout.Status, ErrRequestTimeout.Code, ErrRequestTimeout.Title, "", nil)
+		return
+	}
+	code, title := errorCode(err, fallback)
+	c.fail(status, code, title, "", nil)
+}
+
+func (c *context) wantsProblem() bool {
+	return strings.Contains(c.Context.GetHeader("Accept"), ProblemContentType)
+}
+
+// fail writes an error response, as problem+json when the client accepts it
+// and as the Response envelope otherwise. The problem title is the title of
+// the error code, or the status text when there is none; detail explains
+// this occurrence, or falls back to the generic message of the status.
+func (c *context) fail(status int, code string, title string, detail string, fields []ErrorField) {
+	message := statusMessage(status)
+	if !c.wantsProblem() {
+		c.Context.JSON(status, Response{
+			Status:  Fail,
+			Message: message,
+			Errors:  fields,
+		})
+		return
+	}
+
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	if detail == "" {
+		detail = message
+	}
+	c.Context.Header("Content-Type", ProblemContentType)
+	c.Context.JSON(status, Problem{
+		Type:     ProblemTypeURI + strings.ToLower(code),
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Context.GetHeader("transaction-id"),
+		Code:     code,
+		Errors:   fields,
+	})
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestNotFound = NewCodedError("THING_NOT_FOUND", http.StatusNotFound, "thing not found")
+
+var errTestLocked = NewCodedError("THING_LOCKED", http.StatusTooManyRequests, "thing locked")
+
+type testRetryableError struct{}
+
+func (testRetryableError) Error() string { return "thing locked until 00:00" }
+
+func (testRetryableError) Unwrap() error { return errTestLocked }
+
+func (testRetryableError) RetryAt() time.Time { return time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC) }
+
+func TestProblemResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name                string
+		accept              string
+		respond             func(ctx Context)
+		expectedStatus      int
+		expectedContentType string
+		expectedRetryAfter  string
+		expectedBody        string
+	}{
+		{
+			name:                "Should render coded error as envelope by default",
+			respond:             func(ctx Context) { ctx.HandleError(errTestNotFound) },
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"status":"ERROR","message":"` + NotFoundMsg + `"}`,
+		},
+		{
+			name:                "Should render coded error as problem",
+			accept:              ProblemContentType,
+			respond:             func(ctx Context) { ctx.HandleError(fmt.Errorf("wrapped: %w", errTestNotFound)) },
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: ProblemContentType,
+			expectedBody:        `{"type":"urn:go-restapi:problem:thing_not_found","title":"thing not found","status":404,"detail":"` + NotFoundMsg + `","instance":"tx-1","code":"THING_NOT_FOUND"}`,
+		},
+		{
+			name:                "Should hide detail of unknown errors",
+			accept:              "application/json, " + ProblemContentType,
+			respond:             func(ctx Context) { ctx.HandleError(errors.New("dial tcp: connection refused")) },
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: ProblemContentType,
+			expectedBody:        `{"type":"urn:go-restapi:problem:internal_server_error","title":"Internal Server Error","status":500,"detail":"` + InternalServerErrorMsg + `","instance":"tx-1","code":"INTERNAL_SERVER_ERROR"}`,
+		},
+		{
+			name:                "Should use code of error passed to status helper",
+			accept:              ProblemContentType,
+			respond:             func(ctx Context) { ctx.Unauthorized(ErrMissingBearerToken) },
+			expectedStatus:      http.StatusUnauthorized,
+			expectedContentType: ProblemContentType,
+			expectedBody:        `{"type":"urn:go-restapi:problem:missing_bearer_token","title":"missing bearer token","status":401,"detail":"` + UnauthorizedMsg + `","instance":"tx-1","code":"MISSING_BEARER_TOKEN"}`,
+		},
+		{
+			name:                "Should include validation errors in problem",
+			accept:              ProblemContentType,
+			respond:             func(ctx Context) { ctx.ValidationError([]ErrorField{{Field: "name", Value: "", Tag: "required"}}) },
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: ProblemContentType,
+			expectedBody:        `{"type":"urn:go-restapi:problem:validation_failed","title":"Bad Request","status":400,"detail":"` + BadRequestMsg + `","instance":"tx-1","code":"VALIDATION_FAILED","errors":[{"field":"name","value":"","tag":"required"}]}`,
+		},
+		{
+			name:                "Should tell when to retry",
+			accept:              ProblemContentType,
+			respond:             func(ctx Context) { ctx.HandleError(testRetryableError{}) },
+			expectedStatus:      http.StatusTooManyRequests,
+			expectedContentType: ProblemContentType,
+			expectedRetryAfter:  "Sun, 01 Oct 2023 00:00:00 GMT",
+			expectedBody:        `{"type":"urn:go-restapi:problem:thing_locked","title":"thing locked","status":429,"detail":"thing locked until 00:00","instance":"tx-1","code":"THING_LOCKED"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Router{Engine: gin.New(), logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+			r.GET("/", tc.respond)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("transaction-id", "tx-1")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedContentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedRetryAfter, rec.Header().Get("Retry-After"))
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restapi/app"
+	"go-restapi/app/user"
+	"go-restapi/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidAdmin = errors.New("invalid admin config")
+
+type defaultRoleAssigner struct {
+	roleStorage RoleStorage
+}
+
+// NewDefaultRoleAssigner gives new users DefaultRole.
+func NewDefaultRoleAssigner(roleStorage RoleStorage) user.RoleAssigner {
+	return &defaultRoleAssigner{roleStorage: roleStorage}
+}
+
+func (a *defaultRoleAssigner) AssignDefaultRole(ctx context.Context, userID int) error {
+	return assignRoleByName(ctx, a.roleStorage, userID, DefaultRole)
+}
+
+// EnsureAdmin makes sure the user of conf exists and holds AdminRole. A
+// missing user is created with conf.Password; an existing one keeps its
+// password. It does nothing when conf.Username is empty, and can run on
+// every start.
+func EnsureAdmin(ctx context.Context, userStorage user.UserStorage, roleStorage RoleStorage, conf app.Admin) error {
+	if conf.Username == "" {
+		return nil
+	}
+
+	u, err := userStorage.GetUserByUsername(ctx, conf.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		u, err = createAdmin(ctx, userStorage, conf)
+	}
+	if err != nil {
+		return err
+	}
+	return assignRoleByName(ctx, roleStorage, int(u.ID), AdminRole)
+}
+
+func createAdmin(ctx context.Context, userStorage user.UserStorage, conf app.Admin) (*user.UserModel, error) {
+	if len(conf.Password) < 8 {
+		return nil, fmt.Errorf("%w: admin.password must be at least 8 characters to create %q", ErrInvalidAdmin, conf.Username)
+	}
+	hashPassword, err := utils.NewUtils().HashPassword(conf.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := user.UserModel{
+		Username:  conf.Username,
+		Password:  hashPassword,
+		FirstName: "Admin",
+		LastName:  "Admin",
+		Email:     conf.Email,
+		Status:    1,
+	}
+	// Another instance starting at the same time may create it first.
+	if err := userStorage.CreateUser(ctx, admin); err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+	return userStorage.GetUserByUsername(ctx, conf.Username)
+}
+
+func assignRoleByName(ctx context.Context, roleStorage RoleStorage, userID int, name string) error {
+	r, err := roleStorage.GetRoleByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("role %q: %w", name, err)
+	}
+	return roleStorage.AssignRole(ctx, userID, r.ID)
+}
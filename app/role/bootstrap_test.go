@@ -0,0 +1,70 @@
+package role
+
+import (
+	"context"
+	"go-restapi/app"
+	"go-restapi/app/user"
+	"go-restapi/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestEnsureAdmin(t *testing.T) {
+	conf := app.Admin{Username: "root", Password: "password", Email: "root@example.com"}
+	admin := &user.UserModel{ID: 7, Username: "root"}
+
+	t.Run("Should create the admin and assign the admin role", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByUsername", mock.Anything, "root").Return(nil, gorm.ErrRecordNotFound).Once()
+		userStorage.On("CreateUser", mock.Anything, mock.MatchedBy(func(u user.UserModel) bool {
+			return u.Username == "root" && u.Email == "root@example.com" && utils.NewUtils().CheckPasswordHash("password", u.Password)
+		})).Return(nil)
+		userStorage.On("GetUserByUsername", mock.Anything, "root").Return(admin, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, AdminRole).Return(&RoleModel{ID: 1, Name: AdminRole}, nil)
+		roleStorage.On("AssignRole", mock.Anything, 7, int64(1)).Return(nil)
+
+		err := EnsureAdmin(context.Background(), userStorage, roleStorage, conf)
+		assert.NoError(t, err)
+		userStorage.AssertExpectations(t)
+		roleStorage.AssertExpectations(t)
+	})
+
+	t.Run("Should keep the password of an existing admin", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByUsername", mock.Anything, "root").Return(admin, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, AdminRole).Return(&RoleModel{ID: 1, Name: AdminRole}, nil)
+		roleStorage.On("AssignRole", mock.Anything, 7, int64(1)).Return(nil)
+
+		err := EnsureAdmin(context.Background(), userStorage, roleStorage, app.Admin{Username: "root"})
+		assert.NoError(t, err)
+		userStorage.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should require a password to create the admin", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByUsername", mock.Anything, "root").Return(nil, gorm.ErrRecordNotFound)
+
+		err := EnsureAdmin(context.Background(), userStorage, &mockRoleStorage{}, app.Admin{Username: "root", Password: "short"})
+		assert.ErrorIs(t, err, ErrInvalidAdmin)
+	})
+
+	t.Run("Should do nothing without a username", func(t *testing.T) {
+		err := EnsureAdmin(context.Background(), &mockUserStorage{}, &mockRoleStorage{}, app.Admin{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestDefaultRoleAssigner(t *testing.T) {
+	roleStorage := &mockRoleStorage{}
+	roleStorage.On("GetRoleByName", mock.Anything, DefaultRole).Return(&RoleModel{ID: 2, Name: DefaultRole}, nil)
+	roleStorage.On("AssignRole", mock.Anything, 7, int64(2)).Return(nil)
+
+	err := NewDefaultRoleAssigner(roleStorage).AssignDefaultRole(context.Background(), 7)
+	assert.NoError(t, err)
+	roleStorage.AssertExpectations(t)
+}
@@ -0,0 +1,94 @@
+package role
+
+import (
+	"go-restapi/app"
+	"strconv"
+)
+
+type RoleHandler interface {
+	GetUserRoles(ctx app.Context)
+	AssignRole(ctx app.Context)
+	UnassignRole(ctx app.Context)
+}
+
+type roleHandler struct {
+	roleSvc RoleService
+}
+
+func NewRoleHandler(roleSvc RoleService) RoleHandler {
+	return &roleHandler{
+		roleSvc: roleSvc,
+	}
+}
+
+func (h *roleHandler) GetUserRoles(ctx app.Context) {
+	paramId := ctx.GetParam("id")
+	id, err := strconv.Atoi(paramId)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	res, err := h.roleSvc.GetUserRoles(ctx, id)
+	if err != nil {
+		if err == ErrUserNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(res)
+}
+
+func (h *roleHandler) AssignRole(ctx app.Context) {
+	paramId := ctx.GetParam("id")
+	id, err := strconv.Atoi(paramId)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	if err := h.roleSvc.AssignRole(ctx, id, req); err != nil {
+		if err == ErrUserNotFound || err == ErrRoleNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+func (h *roleHandler) UnassignRole(ctx app.Context) {
+	paramId := ctx.GetParam("id")
+	id, err := strconv.Atoi(paramId)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if err := h.roleSvc.UnassignRole(ctx, id, ctx.GetParam("role")); err != nil {
+		if err == ErrUserNotFound || err == ErrRoleNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
@@ -0,0 +1,222 @@
+package role
+
+import (
+	"bytes"
+	"errors"
+	"go-restapi/app"
+	"go-restapi/logger"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type TestCases struct {
+	name           string
+	url            string
+	method         string
+	reqBody        string
+	expectedStatus int
+	expectedBody   string
+}
+
+func TestGetUserRolesHandler(t *testing.T) {
+	mockData := &GetUserRolesResponse{
+		Roles:       []string{"admin"},
+		Permissions: []string{PermissionBooksRead, PermissionBooksWrite},
+	}
+
+	serviceSuccess := &mockRoleService{}
+	serviceSuccess.On("GetUserRoles", mock.Anything, 1).Return(mockData, nil)
+
+	serviceFail := &mockRoleService{}
+	serviceFail.On("GetUserRoles", mock.Anything, 1).Return(nil, errors.New("error"))
+
+	serviceNotFound := &mockRoleService{}
+	serviceNotFound.On("GetUserRoles", mock.Anything, 1).Return(nil, ErrUserNotFound)
+
+	t.Run("Success Case", RunTest(serviceSuccess, []TestCases{
+		{
+			name:           "GetUserRoles: Should return roles and permissions",
+			url:            "/users/1/roles",
+			method:         "GET",
+			expectedStatus: 200,
+			expectedBody:   `{"status":"SUCCESS","message":"","data":{"roles":["admin"],"permissions":["books:read","books:write"]}}`,
+		},
+	}))
+	t.Run("Fail Case", RunTest(serviceFail, []TestCases{
+		{
+			name:           "GetUserRoles: Should return error (ID invalid)",
+			url:            "/users/abc/roles",
+			method:         "GET",
+			expectedStatus: 400,
+			expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		},
+		{
+			name:           "GetUserRoles: Should return error (Service error)",
+			url:            "/users/1/roles",
+			method:         "GET",
+			expectedStatus: 507,
+			expectedBody:   `{"status":"ERROR","message":"The server encountered an unexpected condition which prevented it from fulfilling the request."}`,
+		},
+	}))
+	t.Run("Fail Case Not found", RunTest(serviceNotFound, []TestCases{
+		{
+			name:           "GetUserRoles: Should return error (Not found)",
+			url:            "/users/1/roles",
+			method:         "GET",
+			expectedStatus: 404,
+			expectedBody:   `{"status":"ERROR","message":"The requested resource could not be found but may be available in the future."}`,
+		},
+	}))
+}
+
+func TestAssignRoleHandler(t *testing.T) {
+	serviceSuccess := &mockRoleService{}
+	serviceSuccess.On("AssignRole", mock.Anything, 1, AssignRoleRequest{Role: "admin"}).Return(nil)
+
+	serviceFail := &mockRoleService{}
+	serviceFail.On("AssignRole", mock.Anything, 1, mock.Anything).Return(errors.New("error"))
+
+	serviceNotFound := &mockRoleService{}
+	serviceNotFound.On("AssignRole", mock.Anything, 1, mock.Anything).Return(ErrRoleNotFound)
+
+	t.Run("Success Case", RunTest(serviceSuccess, []TestCases{
+		{
+			name:           "AssignRole: Should return success message",
+			url:            "/users/1/roles",
+			method:         "POST",
+			reqBody:        `{"role":"admin"}`,
+			expectedStatus: 200,
+			expectedBody:   `{"status":"SUCCESS","message":""}`,
+		},
+	}))
+	t.Run("Fail Case", RunTest(serviceFail, []TestCases{
+		{
+			name:           "AssignRole: Should return error (ID invalid)",
+			url:            "/users/abc/roles",
+			method:         "POST",
+			reqBody:        `{"role":"admin"}`,
+			expectedStatus: 400,
+			expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		},
+		{
+			name:           "AssignRole: Should return error (Bind error)",
+			url:            "/users/1/roles",
+			method:         "POST",
+			reqBody:        `{"role":"admin"`,
+			expectedStatus: 400,
+			expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		},
+		{
+			name:           "AssignRole: Should return error (Validate error)",
+			url:            "/users/1/roles",
+			method:         "POST",
+			reqBody:        `{"role":""}`,
+			expectedStatus: 400,
+			expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"role","value":"","tag":"required"}]}`,
+		},
+		{
+			name:           "AssignRole: Should return error (Service error)",
+			url:            "/users/1/roles",
+			method:         "POST",
+			reqBody:        `{"role":"admin"}`,
+			expectedStatus: 507,
+			expectedBody:   `{"status":"ERROR","message":"The server encountered an unexpected condition which prevented it from fulfilling the request."}`,
+		},
+	}))
+	t.Run("Fail Case Not found", RunTest(serviceNotFound, []TestCases{
+		{
+			name:           "AssignRole: Should return error (Not found)",
+			url:            "/users/1/roles",
+			method:         "POST",
+			reqBody:        `{"role":"unknown"}`,
+			expectedStatus: 404,
+			expectedBody:   `{"status":"ERROR","message":"The requested resource could not be found but may be available in the future."}`,
+		},
+	}))
+}
+
+func TestUnassignRoleHandler(t *testing.T) {
+	serviceSuccess := &mockRoleService{}
+	serviceSuccess.On("UnassignRole", mock.Anything, 1, "admin").Return(nil)
+
+	serviceFail := &mockRoleService{}
+	serviceFail.On("UnassignRole", mock.Anything, 1, "admin").Return(errors.New("error"))
+
+	serviceNotFound := &mockRoleService{}
+	serviceNotFound.On("UnassignRole", mock.Anything, 1, "admin").Return(ErrUserNotFound)
+
+	t.Run("Success Case", RunTest(serviceSuccess, []TestCases{
+		{
+			name:           "UnassignRole: Should return success message",
+			url:            "/users/1/roles/admin",
+			method:         "DELETE",
+			expectedStatus: 200,
+			expectedBody:   `{"status":"SUCCESS","message":""}`,
+		},
+	}))
+	t.Run("Fail Case", RunTest(serviceFail, []TestCases{
+		{
+			name:           "UnassignRole: Should return error (ID invalid)",
+			url:            "/users/abc/roles/admin",
+			method:         "DELETE",
+			expectedStatus: 400,
+			expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		},
+		{
+			name:           "UnassignRole: Should return error (Service error)",
+			url:            "/users/1/roles/admin",
+			method:         "DELETE",
+			expectedStatus: 507,
+			expectedBody:   `{"status":"ERROR","message":"The server encountered an unexpected condition which prevented it from fulfilling the request."}`,
+		},
+	}))
+	t.Run("Fail Case Not found", RunTest(serviceNotFound, []TestCases{
+		{
+			name:           "UnassignRole: Should return error (Not found)",
+			url:            "/users/1/roles/admin",
+			method:         "DELETE",
+			expectedStatus: 404,
+			expectedBody:   `{"status":"ERROR","message":"The requested resource could not be found but may be available in the future."}`,
+		},
+	}))
+}
+
+// ----------------------------
+
+func RunTest(service RoleService, testCases []TestCases) func(t *testing.T) {
+	return func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.Default()
+
+		h := NewRoleHandler(service)
+		r.GET("/users/:id/roles", toGinHandlerFunc(h.GetUserRoles))
+		r.POST("/users/:id/roles", toGinHandlerFunc(h.AssignRole))
+		r.DELETE("/users/:id/roles/:role", toGinHandlerFunc(h.UnassignRole))
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				body := bytes.NewBufferString(tc.reqBody)
+
+				req := httptest.NewRequest(tc.method, tc.url, body)
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+
+				assert.Equal(t, tc.expectedStatus, rec.Code)
+				assert.Equal(t, tc.expectedBody, rec.Body.String())
+			})
+		}
+	}
+}
+
+func toGinHandlerFunc(f func(ctx app.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l, _ := logger.New("debug")
+		ctx := app.NewContext(c, l.Handler())
+		f(ctx)
+	}
+}
@@ -0,0 +1,109 @@
+package role
+
+import (
+	"context"
+	"go-restapi/app/user"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRoleService struct {
+	mock.Mock
+}
+
+func (m *mockRoleService) GetUserRoles(ctx context.Context, userID int) (*GetUserRolesResponse, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*GetUserRolesResponse), args.Error(1)
+}
+
+func (m *mockRoleService) AssignRole(ctx context.Context, userID int, req AssignRoleRequest) error {
+	args := m.Called(ctx, userID, req)
+	return args.Error(0)
+}
+
+func (m *mockRoleService) UnassignRole(ctx context.Context, userID int, name string) error {
+	args := m.Called(ctx, userID, name)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+type mockRoleStorage struct {
+	mock.Mock
+}
+
+func (m *mockRoleStorage) GetRoleByName(ctx context.Context, name string) (*RoleModel, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RoleModel), args.Error(1)
+}
+
+func (m *mockRoleStorage) GetRolesByUserID(ctx context.Context, userID int) ([]RoleModel, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]RoleModel), args.Error(1)
+}
+
+func (m *mockRoleStorage) GetPermissionsByUserID(ctx context.Context, userID int) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockRoleStorage) AssignRole(ctx context.Context, userID int, roleID int64) error {
+	args := m.Called(ctx, userID, roleID)
+	return args.Error(0)
+}
+
+func (m *mockRoleStorage) UnassignRole(ctx context.Context, userID int, roleID int64) error {
+	args := m.Called(ctx, userID, roleID)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+type mockUserStorage struct {
+	mock.Mock
+	user.UserStorage
+}
+
+func (m *mockUserStorage) GetUserByID(ctx context.Context, id int) (*user.UserModel, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserModel), args.Error(1)
+}
+
+func (m *mockUserStorage) GetUserByUsername(ctx context.Context, username string) (*user.UserModel, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserModel), args.Error(1)
+}
+
+func (m *mockUserStorage) CreateUser(ctx context.Context, model user.UserModel) error {
+	args := m.Called(ctx, model)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+type mockTokenRevoker struct {
+	mock.Mock
+}
+
+func (m *mockTokenRevoker) RevokeTokensByUserID(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
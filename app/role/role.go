@@ -0,0 +1,64 @@
+package role
+
+import (
+	"go-restapi/app"
+	"go-restapi/app/user"
+	"net/http"
+)
+
+const (
+	RoleTableName           = "roles"
+	RolePermissionTableName = "role_permissions"
+	UserRoleTableName       = "user_roles"
+)
+
+// Roles created by the migrations. AdminRole holds every permission and
+// DefaultRole is given to each new user.
+const (
+	AdminRole   = "admin"
+	DefaultRole = "user"
+)
+
+const (
+	PermissionBooksRead   = "books:read"
+	PermissionBooksWrite  = "books:write"
+	PermissionBooksDelete = "books:delete"
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionUsersDelete = "users:delete"
+	PermissionRolesRead   = "roles:read"
+	PermissionRolesWrite  = "roles:write"
+	PermissionLogsRead    = "logs:read"
+	PermissionLogsWrite   = "logs:write"
+)
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required,max=50"`
+}
+
+type GetUserRolesResponse struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleModel struct {
+	ID   int64  `db:"id" gorm:"primaryKey" `
+	Name string `db:"name" gorm:"unique"`
+}
+
+type RolePermissionModel struct {
+	RoleID     int64  `db:"role_id" gorm:"primaryKey"`
+	Permission string `db:"permission" gorm:"primaryKey"`
+}
+
+type UserRoleModel struct {
+	UserID int   `db:"user_id" gorm:"primaryKey"`
+	RoleID int64 `db:"role_id" gorm:"primaryKey"`
+}
+
+var ErrRoleNotFound = app.NewCodedError("ROLE_NOT_FOUND", http.StatusNotFound, "role not found")
+var ErrUserNotFound = app.NewCodedError("USER_NOT_FOUND", http.StatusNotFound, "user not found")
+
+func New(roleStorage RoleStorage, userStorage user.UserStorage, tokenRevoker user.TokenRevoker, mfaRequiredRoles []string) RoleHandler {
+	return NewRoleHandler(NewRoleService(roleStorage, userStorage, tokenRevoker, mfaRequiredRoles))
+}
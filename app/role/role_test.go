@@ -0,0 +1,12 @@
+package role
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	handler := New(&mockRoleStorage{}, &mockUserStorage{}, &mockTokenRevoker{}, nil)
+	assert.NotNil(t, handler)
+}
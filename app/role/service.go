@@ -0,0 +1,119 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"go-restapi/app"
+	"go-restapi/app/user"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type RoleService interface {
+	GetUserRoles(context.Context, int) (*GetUserRolesResponse, error)
+	AssignRole(context.Context, int, AssignRoleRequest) error
+	UnassignRole(context.Context, int, string) error
+}
+
+type roleService struct {
+	roleStorage      RoleStorage
+	userStorage      user.UserStorage
+	tokenRevoker     user.TokenRevoker
+	mfaRequiredRoles []string
+}
+
+// NewRoleService returns a RoleService. Users given one of mfaRequiredRoles
+// have their tokens revoked by tokenRevoker, so they log in again with the
+// second factor the role needs.
+func NewRoleService(roleStorage RoleStorage, userStorage user.UserStorage, tokenRevoker user.TokenRevoker, mfaRequiredRoles []string) RoleService {
+	return &roleService{
+		roleStorage:      roleStorage,
+		userStorage:      userStorage,
+		tokenRevoker:     tokenRevoker,
+		mfaRequiredRoles: mfaRequiredRoles,
+	}
+}
+
+func (s *roleService) GetUserRoles(ctx context.Context, userID int) (*GetUserRolesResponse, error) {
+	ctx, span := app.StartSpan(ctx, "RoleService.GetUserRoles")
+	defer span.End()
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleStorage.GetRolesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.roleStorage.GetPermissionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := GetUserRolesResponse{
+		Roles:       []string{},
+		Permissions: []string{},
+	}
+	for _, role := range roles {
+		res.Roles = append(res.Roles, role.Name)
+	}
+	res.Permissions = append(res.Permissions, permissions...)
+	return &res, nil
+}
+
+func (s *roleService) AssignRole(ctx context.Context, userID int, req AssignRoleRequest) error {
+	ctx, span := app.StartSpan(ctx, "RoleService.AssignRole")
+	defer span.End()
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	role, err := s.getRole(ctx, req.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleStorage.AssignRole(ctx, userID, role.ID); err != nil {
+		return err
+	}
+	if slices.Contains(s.mfaRequiredRoles, role.Name) {
+		return s.tokenRevoker.RevokeTokensByUserID(ctx, userID)
+	}
+	return nil
+}
+
+func (s *roleService) UnassignRole(ctx context.Context, userID int, name string) error {
+	ctx, span := app.StartSpan(ctx, "RoleService.UnassignRole")
+	defer span.End()
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	role, err := s.getRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return s.roleStorage.UnassignRole(ctx, userID, role.ID)
+}
+
+func (s *roleService) checkUser(ctx context.Context, userID int) error {
+	_, err := s.userStorage.GetUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func (s *roleService) getRole(ctx context.Context, name string) (*RoleModel, error) {
+	role, err := s.roleStorage.GetRoleByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
@@ -0,0 +1,177 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"go-restapi/app/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+var mockUser = &user.UserModel{ID: 1, Username: "test"}
+
+var mockRole = &RoleModel{ID: 2, Name: "admin"}
+
+func TestGetUserRolesService(t *testing.T) {
+	t.Run("Should return roles and permissions", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, 1).Return([]RoleModel{*mockRole}, nil)
+		roleStorage.On("GetPermissionsByUserID", mock.Anything, 1).Return([]string{PermissionBooksRead}, nil)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		got, err := service.GetUserRoles(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, &GetUserRolesResponse{Roles: []string{"admin"}, Permissions: []string{PermissionBooksRead}}, got)
+	})
+
+	t.Run("Should return empty slices when user has no role", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, 1).Return([]RoleModel{}, nil)
+		roleStorage.On("GetPermissionsByUserID", mock.Anything, 1).Return([]string{}, nil)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		got, err := service.GetUserRoles(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, &GetUserRolesResponse{Roles: []string{}, Permissions: []string{}}, got)
+	})
+
+	t.Run("Should return error when user not found", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewRoleService(&mockRoleStorage{}, userStorage, &mockTokenRevoker{}, nil)
+		_, err := service.GetUserRoles(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("Should return error when get roles", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, 1).Return(nil, errors.New("error"))
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		_, err := service.GetUserRoles(context.Background(), 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("Should return error when get permissions", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRolesByUserID", mock.Anything, 1).Return([]RoleModel{*mockRole}, nil)
+		roleStorage.On("GetPermissionsByUserID", mock.Anything, 1).Return(nil, errors.New("error"))
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		_, err := service.GetUserRoles(context.Background(), 1)
+		assert.Error(t, err)
+	})
+}
+
+func TestAssignRoleService(t *testing.T) {
+	t.Run("Should return nil", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(mockRole, nil)
+		roleStorage.On("AssignRole", mock.Anything, 1, mockRole.ID).Return(nil)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "admin"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should revoke the tokens when the role requires mfa", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(mockRole, nil)
+		roleStorage.On("AssignRole", mock.Anything, 1, mockRole.ID).Return(nil)
+		tokenRevoker := &mockTokenRevoker{}
+		tokenRevoker.On("RevokeTokensByUserID", mock.Anything, 1).Return(nil)
+
+		service := NewRoleService(roleStorage, userStorage, tokenRevoker, []string{"admin"})
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "admin"})
+		assert.NoError(t, err)
+		tokenRevoker.AssertExpectations(t)
+	})
+
+	t.Run("Should keep the tokens when the role does not require mfa", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(mockRole, nil)
+		roleStorage.On("AssignRole", mock.Anything, 1, mockRole.ID).Return(nil)
+		tokenRevoker := &mockTokenRevoker{}
+
+		service := NewRoleService(roleStorage, userStorage, tokenRevoker, []string{"auditor"})
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "admin"})
+		assert.NoError(t, err)
+		tokenRevoker.AssertNotCalled(t, "RevokeTokensByUserID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return error when user not found", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewRoleService(&mockRoleStorage{}, userStorage, &mockTokenRevoker{}, nil)
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "admin"})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("Should return error when role not found", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "unknown").Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "unknown"})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("Should return error when assign role", func(t *testing.T) {
+		errWant := errors.New("error")
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(mockRole, nil)
+		roleStorage.On("AssignRole", mock.Anything, 1, mockRole.ID).Return(errWant)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		err := service.AssignRole(context.Background(), 1, AssignRoleRequest{Role: "admin"})
+		assert.ErrorIs(t, err, errWant)
+	})
+}
+
+func TestUnassignRoleService(t *testing.T) {
+	t.Run("Should return nil", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(mockRole, nil)
+		roleStorage.On("UnassignRole", mock.Anything, 1, mockRole.ID).Return(nil)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		err := service.UnassignRole(context.Background(), 1, "admin")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should return error when role not found", func(t *testing.T) {
+		userStorage := &mockUserStorage{}
+		userStorage.On("GetUserByID", mock.Anything, 1).Return(mockUser, nil)
+		roleStorage := &mockRoleStorage{}
+		roleStorage.On("GetRoleByName", mock.Anything, "admin").Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewRoleService(roleStorage, userStorage, &mockTokenRevoker{}, nil)
+		err := service.UnassignRole(context.Background(), 1, "admin")
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+}
@@ -0,0 +1,77 @@
+package role
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleStorage interface {
+	GetRoleByName(context.Context, string) (*RoleModel, error)
+	GetRolesByUserID(context.Context, int) ([]RoleModel, error)
+	GetPermissionsByUserID(context.Context, int) ([]string, error)
+	AssignRole(context.Context, int, int64) error
+	UnassignRole(context.Context, int, int64) error
+}
+
+type roleStorage struct {
+	db *gorm.DB
+}
+
+func NewRoleStorage(db *gorm.DB) RoleStorage {
+	return &roleStorage{db: db}
+}
+
+func (s *roleStorage) GetRoleByName(ctx context.Context, name string) (*RoleModel, error) {
+	var role RoleModel
+	q := s.db.WithContext(ctx).Table(RoleTableName).Where("name = ?", name).First(&role)
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	return &role, nil
+}
+
+func (s *roleStorage) GetRolesByUserID(ctx context.Context, userID int) ([]RoleModel, error) {
+	var roles []RoleModel
+	q := s.db.WithContext(ctx).Table(RoleTableName).
+		Select("roles.id, roles.name").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles)
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	return roles, nil
+}
+
+func (s *roleStorage) GetPermissionsByUserID(ctx context.Context, userID int) ([]string, error) {
+	var permissions []string
+	q := s.db.WithContext(ctx).Table(RolePermissionTableName).
+		Distinct().
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions)
+	if q.Error != nil {
+		return nil, q.Error
+	}
+	return permissions, nil
+}
+
+func (s *roleStorage) AssignRole(ctx context.Context, userID int, roleID int64) error {
+	userRole := UserRoleModel{UserID: userID, RoleID: roleID}
+	q := s.db.WithContext(ctx).Table(UserRoleTableName).Clauses(clause.OnConflict{DoNothing: true}).Create(&userRole)
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
+
+func (s *roleStorage) UnassignRole(ctx context.Context, userID int, roleID int64) error {
+	q := s.db.WithContext(ctx).Table(UserRoleTableName).Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&UserRoleModel{})
+	if q.Error != nil {
+		return q.Error
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type testStorageSuite struct {
+	suite.Suite
+	sqlmockDB *sql.DB
+	mock      sqlmock.Sqlmock
+	gormDB    *gorm.DB
+}
+
+func (s *testStorageSuite) SetupTest() {
+	sqlmockDB, mock, _ := sqlmock.New()
+
+	mock.ExpectQuery(`SELECT VERSION()`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("7.2"))
+
+	gormDB, _ := gorm.Open(mysql.New(mysql.Config{Conn: sqlmockDB}), &gorm.Config{})
+
+	s.sqlmockDB = sqlmockDB
+	s.mock = mock
+	s.gormDB = gormDB
+}
+
+func (s *testStorageSuite) TearDownTest() {
+	s.sqlmockDB.Close()
+}
+
+func (s *testStorageSuite) TestGetRoleByName() {
+	s.Run("Should return role", func() {
+		s.mock.ExpectQuery("SELECT").WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+
+		storage := NewRoleStorage(s.gormDB)
+		got, err := storage.GetRoleByName(context.Background(), "admin")
+		s.NoError(err)
+		s.Equal(&RoleModel{ID: 1, Name: "admin"}, got)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectQuery("SELECT").WithArgs("admin").WillReturnError(gorm.ErrRecordNotFound)
+
+		storage := NewRoleStorage(s.gormDB)
+		got, err := storage.GetRoleByName(context.Background(), "admin")
+		s.ErrorIs(err, gorm.ErrRecordNotFound)
+		s.Nil(got)
+	})
+}
+
+func (s *testStorageSuite) TestGetRolesByUserID() {
+	s.Run("Should return roles", func() {
+		s.mock.ExpectQuery("SELECT roles.id, roles.name FROM `roles` JOIN user_roles").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin").AddRow(2, "user"))
+
+		storage := NewRoleStorage(s.gormDB)
+		got, err := storage.GetRolesByUserID(context.Background(), 1)
+		s.NoError(err)
+		s.Equal([]RoleModel{{ID: 1, Name: "admin"}, {ID: 2, Name: "user"}}, got)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectQuery("SELECT").WithArgs(1).WillReturnError(sql.ErrConnDone)
+
+		storage := NewRoleStorage(s.gormDB)
+		_, err := storage.GetRolesByUserID(context.Background(), 1)
+		s.Error(err)
+	})
+}
+
+func (s *testStorageSuite) TestGetPermissionsByUserID() {
+	s.Run("Should return permissions", func() {
+		s.mock.ExpectQuery("SELECT DISTINCT `role_permissions`.`permission` FROM `role_permissions` JOIN user_roles").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow("books:read").AddRow("books:write"))
+
+		storage := NewRoleStorage(s.gormDB)
+		got, err := storage.GetPermissionsByUserID(context.Background(), 1)
+		s.NoError(err)
+		s.Equal([]string{"books:read", "books:write"}, got)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectQuery("SELECT").WithArgs(1).WillReturnError(sql.ErrConnDone)
+
+		storage := NewRoleStorage(s.gormDB)
+		_, err := storage.GetPermissionsByUserID(context.Background(), 1)
+		s.Error(err)
+	})
+}
+
+func (s *testStorageSuite) TestAssignRole() {
+	s.Run("Should return nil", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("INSERT INTO `user_roles`").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectCommit()
+
+		storage := NewRoleStorage(s.gormDB)
+		err := storage.AssignRole(context.Background(), 1, 2)
+		s.NoError(err)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("INSERT").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRoleStorage(s.gormDB)
+		err := storage.AssignRole(context.Background(), 1, 2)
+		s.Error(err)
+	})
+}
+
+func (s *testStorageSuite) TestUnassignRole() {
+	s.Run("Should return nil", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("DELETE FROM `user_roles`").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectCommit()
+
+		storage := NewRoleStorage(s.gormDB)
+		err := storage.UnassignRole(context.Background(), 1, 2)
+		s.NoError(err)
+	})
+
+	s.Run("Should return error", func() {
+		s.mock.ExpectBegin()
+		s.mock.ExpectExec("DELETE").WillReturnError(sql.ErrConnDone)
+		s.mock.ExpectRollback()
+
+		storage := NewRoleStorage(s.gormDB)
+		err := storage.UnassignRole(context.Background(), 1, 2)
+		s.Error(err)
+	})
+}
+
+func TestRoleStorage(t *testing.T) {
+	suite.Run(t, new(testStorageSuite))
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	gocontext "context"
+	"go-restapi/metrics"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the spans the service creates.
+const TracerName = "go-restapi"
+
+const serverSpanKey = "serverSpan"
+
+// StartSpan starts a child span of the span in ctx, e.g. for a service call.
+// The caller must end it.
+func StartSpan(ctx gocontext.Context, name string) (gocontext.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name)
+}
+
+// withServerSpan runs next inside the server span of the request, starting it
+// from the request's traceparent header on the first call. The middlewares
+// and the handler of a route share one span, ended once the outermost of them
+// returns.
+func withServerSpan(c *gin.Context, next func()) {
+	if _, ok := c.Get(serverSpanKey); ok {
+		next()
+		return
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = metrics.UnmatchedRoute
+	}
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := otel.Tracer(TracerName).Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(c.Request.Method),
+			semconv.HTTPRoute(route),
+			attribute.String("transaction.id", transactionID(c)),
+		),
+	)
+	defer span.End()
+	c.Set(serverSpanKey, span)
+	c.Request = c.Request.WithContext(ctx)
+
+	next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
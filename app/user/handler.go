@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"go-restapi/app"
 	"go-restapi/utils"
 	"strconv"
@@ -12,6 +13,9 @@ type UserHandler interface {
 	GetUserByID(ctx app.Context)
 	UpdateUser(ctx app.Context)
 	DeleteUser(ctx app.Context)
+	GetMe(ctx app.Context)
+	PatchMe(ctx app.Context)
+	ChangePassword(ctx app.Context)
 }
 
 type userHandler struct {
@@ -34,14 +38,14 @@ func (h *userHandler) CreateUser(ctx app.Context) {
 		return
 	}
 
-	if _, err := ctx.Validate(&user); err != nil {
-		ctx.BadRequest(err)
+	if fields, err := ctx.Validate(&user); err != nil {
+		ctx.ValidationError(fields)
 		return
 	}
 
 	if err := h.userSvc.CreateUser(ctx, user); err != nil {
 		if err == ErrUsernameAlreadyExists {
-			ctx.BadRequest(err)
+			ctx.HandleError(err)
 			return
 		}
 
@@ -53,8 +57,16 @@ func (h *userHandler) CreateUser(ctx app.Context) {
 }
 
 func (h *userHandler) GetListUser(ctx app.Context) {
-	page, _ := h.utils.GetPage(ctx)
-	pageSize, _ := h.utils.GetPageSize(ctx)
+	page, err := h.utils.GetPage(ctx)
+	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
+	pageSize, err := h.utils.GetPageSize(ctx)
+	if err != nil {
+		ctx.HandleError(err)
+		return
+	}
 
 	users, err := h.userSvc.GetListUser(ctx, page, pageSize)
 	if err != nil {
@@ -89,7 +101,7 @@ func (h *userHandler) GetUserByID(ctx app.Context) {
 	user, err := h.userSvc.GetUserByID(ctx, id)
 	if err != nil {
 		if err == ErrUserNotFound {
-			ctx.NotFound()
+			ctx.HandleError(err)
 			return
 		}
 		ctx.StoreError(err)
@@ -114,14 +126,14 @@ func (h *userHandler) UpdateUser(ctx app.Context) {
 		return
 	}
 
-	if _, err := ctx.Validate(&user); err != nil {
-		ctx.BadRequest(err)
+	if fields, err := ctx.Validate(&user); err != nil {
+		ctx.ValidationError(fields)
 		return
 	}
 
 	if err := h.userSvc.UpdateUser(ctx, id, user); err != nil {
 		if err == ErrUserNotFound {
-			ctx.NotFound()
+			ctx.HandleError(err)
 			return
 		}
 
@@ -142,10 +154,77 @@ func (h *userHandler) DeleteUser(ctx app.Context) {
 
 	if err := h.userSvc.DeleteUser(ctx, id); err != nil {
 		if err == ErrUserNotFound {
-			ctx.NotFound()
+			ctx.HandleError(err)
+			return
+		}
+
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
+
+// GetMe returns the caller, as identified by its access token.
+func (h *userHandler) GetMe(ctx app.Context) {
+	user, err := h.userSvc.GetUserByID(ctx, ctx.GetTokenData().UserID)
+	if err != nil {
+		if err == ErrUserNotFound {
+			ctx.HandleError(err)
+			return
+		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(user)
+}
+
+func (h *userHandler) PatchMe(ctx app.Context) {
+	var user PatchMeRequest
+	if err := ctx.Bind(&user); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&user); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	user.ClientIP = ctx.ClientIP()
+	if err := h.userSvc.PatchMe(ctx, ctx.GetTokenData().UserID, user); err != nil {
+		var locked app.RetryableError
+		if err == ErrUserNotFound || err == ErrCurrentPasswordNotMatch || errors.As(err, &locked) {
+			ctx.HandleError(err)
 			return
 		}
+		ctx.StoreError(err)
+		return
+	}
+
+	ctx.OK(nil)
+}
 
+func (h *userHandler) ChangePassword(ctx app.Context) {
+	var req ChangePasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if fields, err := ctx.Validate(&req); err != nil {
+		ctx.ValidationError(fields)
+		return
+	}
+
+	req.ClientIP = ctx.ClientIP()
+	if err := h.userSvc.ChangePassword(ctx, ctx.GetTokenData().UserID, req); err != nil {
+		var locked app.RetryableError
+		if err == ErrUserNotFound || err == ErrCurrentPasswordNotMatch || errors.As(err, &locked) {
+			ctx.HandleError(err)
+			return
+		}
 		ctx.StoreError(err)
 		return
 	}
@@ -49,7 +49,15 @@ var CreateUserFailCases = []TestCases{
 		method:         "POST",
 		reqBody:        `{"username":"test","password":"test","firstname":"test","lastname":"test"}`,
 		expectedStatus: 400,
-		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"password","value":"[REDACTED]","tag":"min","param":"8"}]}`,
+	},
+	{
+		name:           "CreateUser: Should return error (Validate email)",
+		url:            "/users",
+		method:         "POST",
+		reqBody:        `{"username":"test","password":"password","firstname":"test","lastname":"test","email":"test"}`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"email","value":"test","tag":"email"}]}`,
 	},
 	{
 		name:           "CreateUser: Should return error (Service error)",
@@ -313,7 +321,7 @@ var UpdateUserNotFoundFailCases = []TestCases{
 		method:         "PUT",
 		reqBody:        `{"firstname":"test","lastname":"test","status":"x"}`,
 		expectedStatus: 400,
-		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"status","value":"x","tag":"oneof","param":"active inactive"}]}`,
 	},
 	{
 		name:           "UpdateUser: Should return error (Service error)",
@@ -412,6 +420,176 @@ func TestDeleteUserHandler(t *testing.T) {
 	t.Run("Fail Case Not found", RunTest(serviceNotFound, mockUtils, DeleteUserNotFoundFailCases))
 }
 
+var GetMeSuccessCases = []TestCases{
+	{
+		name:           "GetMe: Should return the caller",
+		url:            "/me",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: 200,
+		expectedBody:   `{"status":"SUCCESS","message":"","data":{"id":1,"username":"test","firstname":"test","lastname":"test","status":"Active"}}`,
+	},
+}
+
+var GetMeNotFoundCases = []TestCases{
+	{
+		name:           "GetMe: Should return error (Not found)",
+		url:            "/me",
+		method:         "GET",
+		reqBody:        ``,
+		expectedStatus: 404,
+		expectedBody:   `{"status":"ERROR","message":"The requested resource could not be found but may be available in the future."}`,
+	},
+}
+
+func TestGetMeHandler(t *testing.T) {
+	mockData := &GetUserResponse{
+		ID:        1,
+		Username:  "test",
+		FirstName: "test",
+		LastName:  "test",
+		Status:    "Active",
+	}
+
+	mockUtils := &mockUtils{}
+	serviceSuccess := &mockUserService{}
+	serviceSuccess.On("GetUserByID", mock.Anything, 1).Return(mockData, nil)
+
+	serviceNotFound := &mockUserService{}
+	serviceNotFound.On("GetUserByID", mock.Anything, 1).Return(nil, ErrUserNotFound)
+
+	t.Run("Success Case", RunTest(serviceSuccess, mockUtils, GetMeSuccessCases))
+	t.Run("Fail Case Not found", RunTest(serviceNotFound, mockUtils, GetMeNotFoundCases))
+}
+
+// ----------------------------
+
+var PatchMeSuccessCases = []TestCases{
+	{
+		name:           "PatchMe: Should return success message",
+		url:            "/me",
+		method:         "PATCH",
+		reqBody:        `{"firstname":"first"}`,
+		expectedStatus: 200,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+}
+
+var PatchMeFailCases = []TestCases{
+	{
+		name:           "PatchMe: Should return error (Bind error)",
+		url:            "/me",
+		method:         "PATCH",
+		reqBody:        `{"firstname":"first"`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+	},
+	{
+		name:           "PatchMe: Should return error (Validate error)",
+		url:            "/me",
+		method:         "PATCH",
+		reqBody:        `{"firstname":"ab"}`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"firstname","value":"ab","tag":"min","param":"3"}]}`,
+	},
+	{
+		name:           "PatchMe: Should return error (Email without current password)",
+		url:            "/me",
+		method:         "PATCH",
+		reqBody:        `{"email":"new@example.com"}`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"currentPassword","value":"[REDACTED]","tag":"required_with","param":"Email"}]}`,
+	},
+	{
+		name:           "PatchMe: Should return error (Service error)",
+		url:            "/me",
+		method:         "PATCH",
+		reqBody:        `{"lastname":"last"}`,
+		expectedStatus: 507,
+		expectedBody:   `{"status":"ERROR","message":"The server encountered an unexpected condition which prevented it from fulfilling the request."}`,
+	},
+}
+
+func TestPatchMeHandler(t *testing.T) {
+	firstName := "first"
+	mockUtils := &mockUtils{}
+	serviceSuccess := &mockUserService{}
+	serviceSuccess.On("PatchMe", mock.Anything, 1, PatchMeRequest{FirstName: &firstName, ClientIP: "192.0.2.1"}).Return(nil)
+
+	serviceFail := &mockUserService{}
+	serviceFail.On("PatchMe", mock.Anything, 1, mock.Anything).Return(errors.New("error"))
+
+	t.Run("Success Case", RunTest(serviceSuccess, mockUtils, PatchMeSuccessCases))
+	t.Run("Fail Case", RunTest(serviceFail, mockUtils, PatchMeFailCases))
+}
+
+// ----------------------------
+
+var ChangePasswordSuccessCases = []TestCases{
+	{
+		name:           "ChangePassword: Should return success message",
+		url:            "/me/password",
+		method:         "PUT",
+		reqBody:        `{"currentPassword":"password","newPassword":"new-password"}`,
+		expectedStatus: 200,
+		expectedBody:   `{"status":"SUCCESS","message":""}`,
+	},
+}
+
+var ChangePasswordFailCases = []TestCases{
+	{
+		name:           "ChangePassword: Should return error (Bind error)",
+		url:            "/me/password",
+		method:         "PUT",
+		reqBody:        `{"currentPassword":"password"`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+	},
+	{
+		name:           "ChangePassword: Should return error (Validate error)",
+		url:            "/me/password",
+		method:         "PUT",
+		reqBody:        `{"currentPassword":"password","newPassword":"password"}`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!","errors":[{"field":"newPassword","value":"[REDACTED]","tag":"nefield","param":"CurrentPassword"}]}`,
+	},
+	{
+		name:           "ChangePassword: Should return error (Current password not match)",
+		url:            "/me/password",
+		method:         "PUT",
+		reqBody:        `{"currentPassword":"wrong-password","newPassword":"new-password"}`,
+		expectedStatus: 400,
+		expectedBody:   `{"status":"ERROR","message":"Invalid request body, Please check your request body and try again!"}`,
+	},
+}
+
+var ChangePasswordLockedCases = []TestCases{
+	{
+		name:           "ChangePassword: Should return error (Locked)",
+		url:            "/me/password",
+		method:         "PUT",
+		reqBody:        `{"currentPassword":"wrong-password","newPassword":"new-password"}`,
+		expectedStatus: 429,
+		expectedBody:   `{"status":"ERROR","message":"Too many attempts, Please try again later!"}`,
+	},
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	mockUtils := &mockUtils{}
+	serviceSuccess := &mockUserService{}
+	serviceSuccess.On("ChangePassword", mock.Anything, 1, ChangePasswordRequest{CurrentPassword: "password", NewPassword: "new-password", ClientIP: "192.0.2.1"}).Return(nil)
+
+	serviceFail := &mockUserService{}
+	serviceFail.On("ChangePassword", mock.Anything, 1, mock.Anything).Return(ErrCurrentPasswordNotMatch)
+
+	serviceLocked := &mockUserService{}
+	serviceLocked.On("ChangePassword", mock.Anything, 1, mock.Anything).Return(testLockedError{})
+
+	t.Run("Success Case", RunTest(serviceSuccess, mockUtils, ChangePasswordSuccessCases))
+	t.Run("Fail Case", RunTest(serviceFail, mockUtils, ChangePasswordFailCases))
+	t.Run("Locked Case", RunTest(serviceLocked, mockUtils, ChangePasswordLockedCases))
+}
+
 // ----------------------------
 
 func RunTest(service UserService, utils utils.Utils, testCases []TestCases) func(t *testing.T) {
@@ -425,6 +603,9 @@ func RunTest(service UserService, utils utils.Utils, testCases []TestCases) func
 		r.GET("/users/:id", toGinHandlerFunc(h.GetUserByID))
 		r.PUT("/users/:id", toGinHandlerFunc(h.UpdateUser))
 		r.DELETE("/users/:id", toGinHandlerFunc(h.DeleteUser))
+		r.GET("/me", toGinHandlerFunc(asUser(1, h.GetMe)))
+		r.PATCH("/me", toGinHandlerFunc(asUser(1, h.PatchMe)))
+		r.PUT("/me/password", toGinHandlerFunc(asUser(1, h.ChangePassword)))
 
 		for _, tc := range testCases {
 			t.Run(tc.name, func(t *testing.T) {
@@ -444,8 +625,16 @@ func RunTest(service UserService, utils utils.Utils, testCases []TestCases) func
 
 func toGinHandlerFunc(f func(ctx app.Context)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		l := logger.New()
+		l, _ := logger.New("debug")
 		ctx := app.NewContext(c, l.Handler())
 		f(ctx)
 	}
 }
+
+// asUser runs f as if the auth middleware had verified a token of userID.
+func asUser(userID int, f func(ctx app.Context)) func(ctx app.Context) {
+	return func(ctx app.Context) {
+		ctx.SetTokenData(&app.TokenData{UserID: userID})
+		f(ctx)
+	}
+}
@@ -1,8 +1,11 @@
 package user
 
 import (
+	"context"
 	"go-restapi/app"
 	"go-restapi/utils"
+	"net/http"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -11,22 +14,22 @@ type mockUserService struct {
 	mock.Mock
 }
 
-func (m *mockUserService) CreateUser(ctx app.Context, req CreateUserRequest) error {
+func (m *mockUserService) CreateUser(ctx context.Context, req CreateUserRequest) error {
 	args := m.Called(ctx, req)
 	return args.Error(0)
 }
 
-func (m *mockUserService) GetListUser(ctx app.Context, page, pageSize int) ([]GetListUserResponse, error) {
+func (m *mockUserService) GetListUser(ctx context.Context, page, pageSize int) ([]GetListUserResponse, error) {
 	args := m.Called(ctx, page, pageSize)
 	return args.Get(0).([]GetListUserResponse), args.Error(1)
 }
 
-func (m *mockUserService) CountListUser(ctx app.Context) (int, error) {
+func (m *mockUserService) CountListUser(ctx context.Context) (int, error) {
 	args := m.Called(ctx)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *mockUserService) GetUserByID(ctx app.Context, id int) (*GetUserResponse, error) {
+func (m *mockUserService) GetUserByID(ctx context.Context, id int) (*GetUserResponse, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -34,60 +37,127 @@ func (m *mockUserService) GetUserByID(ctx app.Context, id int) (*GetUserResponse
 	return args.Get(0).(*GetUserResponse), args.Error(1)
 }
 
-func (m *mockUserService) UpdateUser(ctx app.Context, id int, req UpdateUserRequest) error {
+func (m *mockUserService) UpdateUser(ctx context.Context, id int, req UpdateUserRequest) error {
 	args := m.Called(ctx, id, req)
 	return args.Error(0)
 }
 
-func (m *mockUserService) DeleteUser(ctx app.Context, id int) error {
+func (m *mockUserService) DeleteUser(ctx context.Context, id int) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *mockUserService) PatchMe(ctx context.Context, id int, req PatchMeRequest) error {
+	args := m.Called(ctx, id, req)
+	return args.Error(0)
+}
+
+func (m *mockUserService) ChangePassword(ctx context.Context, id int, req ChangePasswordRequest) error {
+	args := m.Called(ctx, id, req)
+	return args.Error(0)
+}
+
 // ----------------------------
 
 type mockUserStorage struct {
 	mock.Mock
 }
 
-func (m *mockUserStorage) CreateUser(model UserModel) error {
-	args := m.Called(model)
+func (m *mockUserStorage) CreateUser(ctx context.Context, model UserModel) error {
+	args := m.Called(ctx, model)
 	return args.Error(0)
 }
 
-func (m *mockUserStorage) GetListUser(page, pageSize int) ([]UserModel, error) {
-	args := m.Called(page, pageSize)
+func (m *mockUserStorage) GetListUser(ctx context.Context, page, pageSize int) ([]UserModel, error) {
+	args := m.Called(ctx, page, pageSize)
 	return args.Get(0).([]UserModel), args.Error(1)
 }
 
-func (m *mockUserStorage) GetUserByUsername(username string) (*UserModel, error) {
-	args := m.Called(username)
+func (m *mockUserStorage) GetUserByUsername(ctx context.Context, username string) (*UserModel, error) {
+	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*UserModel), args.Error(1)
 }
 
-func (m *mockUserStorage) GetUserByID(id int) (*UserModel, error) {
-	args := m.Called(id)
+func (m *mockUserStorage) GetUserByID(ctx context.Context, id int) (*UserModel, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*UserModel), args.Error(1)
 }
 
-func (m *mockUserStorage) CountListUser() (int64, error) {
-	args := m.Called()
+func (m *mockUserStorage) CountListUser(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *mockUserStorage) UpdateUser(model UserModel) error {
-	args := m.Called(model)
+func (m *mockUserStorage) UpdateUser(ctx context.Context, model UserModel) error {
+	args := m.Called(ctx, model)
 	return args.Error(0)
 }
 
-func (m *mockUserStorage) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *mockUserStorage) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+type mockTokenRevoker struct {
+	mock.Mock
+}
+
+func (m *mockTokenRevoker) RevokeTokensByUserID(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// ----------------------------
+
+// fakePasswordLimiter locks a username once it failed maxFailures times, or
+// never when maxFailures is 0.
+type fakePasswordLimiter struct {
+	maxFailures int
+	failures    map[string]int
+}
+
+func (l *fakePasswordLimiter) Check(ctx context.Context, username, clientIP string) error {
+	if l.maxFailures > 0 && l.failures[username] >= l.maxFailures {
+		return testLockedError{}
+	}
+	return nil
+}
+
+func (l *fakePasswordLimiter) Failed(ctx context.Context, username, clientIP string) error {
+	if l.failures == nil {
+		l.failures = map[string]int{}
+	}
+	l.failures[username]++
+	return nil
+}
+
+var errTestLocked = app.NewCodedError("ACCOUNT_LOCKED", http.StatusTooManyRequests, "too many failed logins")
+
+// testLockedError stands in for the lock error of package auth.
+type testLockedError struct{}
+
+func (testLockedError) Error() string { return "too many failed logins, locked until 00:00" }
+
+func (testLockedError) Unwrap() error { return errTestLocked }
+
+func (testLockedError) RetryAt() time.Time { return time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC) }
+
+// ----------------------------
+
+type mockRoleAssigner struct {
+	mock.Mock
+}
+
+func (m *mockRoleAssigner) AssignDefaultRole(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
 	return args.Error(0)
 }
 
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"go-restapi/app"
 	"go-restapi/utils"
@@ -9,29 +10,39 @@ import (
 )
 
 type UserService interface {
-	CreateUser(app.Context, CreateUserRequest) error
-	GetListUser(app.Context, int, int) ([]GetListUserResponse, error)
-	GetUserByID(app.Context, int) (*GetUserResponse, error)
-	CountListUser(app.Context) (int, error)
-	UpdateUser(app.Context, int, UpdateUserRequest) error
-	DeleteUser(app.Context, int) error
+	CreateUser(context.Context, CreateUserRequest) error
+	GetListUser(context.Context, int, int) ([]GetListUserResponse, error)
+	GetUserByID(context.Context, int) (*GetUserResponse, error)
+	CountListUser(context.Context) (int, error)
+	UpdateUser(context.Context, int, UpdateUserRequest) error
+	DeleteUser(context.Context, int) error
+	PatchMe(context.Context, int, PatchMeRequest) error
+	ChangePassword(context.Context, int, ChangePasswordRequest) error
 }
 
 type userService struct {
-	userStorage UserStorage
-	utils       utils.Utils
+	userStorage     UserStorage
+	tokenRevoker    TokenRevoker
+	roleAssigner    RoleAssigner
+	passwordLimiter PasswordLimiter
+	utils           utils.Utils
 }
 
-func NewUserService(userStorage UserStorage, utils utils.Utils) UserService {
+func NewUserService(userStorage UserStorage, tokenRevoker TokenRevoker, roleAssigner RoleAssigner, passwordLimiter PasswordLimiter, utils utils.Utils) UserService {
 	return &userService{
-		userStorage: userStorage,
-		utils:       utils,
+		userStorage:     userStorage,
+		tokenRevoker:    tokenRevoker,
+		roleAssigner:    roleAssigner,
+		passwordLimiter: passwordLimiter,
+		utils:           utils,
 	}
 }
 
-func (s *userService) CreateUser(ctx app.Context, req CreateUserRequest) error {
+func (s *userService) CreateUser(ctx context.Context, req CreateUserRequest) error {
+	ctx, span := app.StartSpan(ctx, "UserService.CreateUser")
+	defer span.End()
 
-	checkDup, err := s.userStorage.GetUserByUsername(req.Username)
+	checkDup, err := s.userStorage.GetUserByUsername(ctx, req.Username)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
@@ -50,15 +61,31 @@ func (s *userService) CreateUser(ctx app.Context, req CreateUserRequest) error {
 		Password:  hashPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Email:     req.Email,
 		Status:    1,
 	}
-	return s.userStorage.CreateUser(user)
+	err = s.userStorage.CreateUser(ctx, user)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another request created the same username after the check above.
+		return ErrUsernameAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	created, err := s.userStorage.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		return err
+	}
+	return s.roleAssigner.AssignDefaultRole(ctx, int(created.ID))
 }
 
-func (s *userService) GetListUser(ctx app.Context, page int, pageSize int) ([]GetListUserResponse, error) {
+func (s *userService) GetListUser(ctx context.Context, page int, pageSize int) ([]GetListUserResponse, error) {
+	ctx, span := app.StartSpan(ctx, "UserService.GetListUser")
+	defer span.End()
 	limit := pageSize
 	offset := (page - 1) * pageSize
-	users, err := s.userStorage.GetListUser(limit, offset)
+	users, err := s.userStorage.GetListUser(ctx, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +101,17 @@ func (s *userService) GetListUser(ctx app.Context, page int, pageSize int) ([]Ge
 			Username:  user.Username,
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
+			Email:     user.Email,
 			Status:    status,
 		})
 	}
 	return res, nil
 }
 
-func (s *userService) GetUserByID(ctx app.Context, id int) (*GetUserResponse, error) {
-	user, err := s.userStorage.GetUserByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id int) (*GetUserResponse, error) {
+	ctx, span := app.StartSpan(ctx, "UserService.GetUserByID")
+	defer span.End()
+	user, err := s.userStorage.GetUserByID(ctx, id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrUserNotFound
 	}
@@ -98,21 +128,26 @@ func (s *userService) GetUserByID(ctx app.Context, id int) (*GetUserResponse, er
 		Username:  user.Username,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
+		Email:     user.Email,
 		Status:    status,
 	}
 	return &res, nil
 }
 
-func (s *userService) CountListUser(ctx app.Context) (int, error) {
-	count, err := s.userStorage.CountListUser()
+func (s *userService) CountListUser(ctx context.Context) (int, error) {
+	ctx, span := app.StartSpan(ctx, "UserService.CountListUser")
+	defer span.End()
+	count, err := s.userStorage.CountListUser(ctx)
 	if err != nil {
 		return 0, err
 	}
 	return int(count), nil
 }
 
-func (s *userService) UpdateUser(ctx app.Context, id int, req UpdateUserRequest) error {
-	user, err := s.userStorage.GetUserByID(id)
+func (s *userService) UpdateUser(ctx context.Context, id int, req UpdateUserRequest) error {
+	ctx, span := app.StartSpan(ctx, "UserService.UpdateUser")
+	defer span.End()
+	user, err := s.userStorage.GetUserByID(ctx, id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return ErrUserNotFound
 	}
@@ -127,11 +162,29 @@ func (s *userService) UpdateUser(ctx app.Context, id int, req UpdateUserRequest)
 	} else {
 		user.Status = 0
 	}
-	return s.userStorage.UpdateUser(*user)
+	return s.userStorage.UpdateUser(ctx, *user)
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := app.StartSpan(ctx, "UserService.DeleteUser")
+	defer span.End()
+	_, err := s.userStorage.GetUserByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.userStorage.DeleteUser(ctx, id)
 }
 
-func (s *userService) DeleteUser(ctx app.Context, id int) error {
-	_, err := s.userStorage.GetUserByID(id)
+// PatchMe changes the names and email of the user id, the caller itself. The
+// email is only changed when the current password matches.
+func (s *userService) PatchMe(ctx context.Context, id int, req PatchMeRequest) error {
+	ctx, span := app.StartSpan(ctx, "UserService.PatchMe")
+	defer span.End()
+	user, err := s.userStorage.GetUserByID(ctx, id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return ErrUserNotFound
 	}
@@ -139,5 +192,65 @@ func (s *userService) DeleteUser(ctx app.Context, id int) error {
 		return err
 	}
 
-	return s.userStorage.DeleteUser(id)
+	if req.Email != nil {
+		if err := s.checkCurrentPassword(ctx, user, req.CurrentPassword, req.ClientIP); err != nil {
+			return err
+		}
+	}
+
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	return s.userStorage.UpdateUser(ctx, *user)
+}
+
+// ChangePassword sets a new password once the current one is verified, then
+// revokes the user's refresh tokens so other sessions have to log in again,
+// and its reset tokens so an older reset link cannot undo the change.
+func (s *userService) ChangePassword(ctx context.Context, id int, req ChangePasswordRequest) error {
+	ctx, span := app.StartSpan(ctx, "UserService.ChangePassword")
+	defer span.End()
+	user, err := s.userStorage.GetUserByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCurrentPassword(ctx, user, req.CurrentPassword, req.ClientIP); err != nil {
+		return err
+	}
+
+	hashPassword, err := s.utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashPassword
+	if err := s.userStorage.UpdateUser(ctx, *user); err != nil {
+		return err
+	}
+	return s.tokenRevoker.RevokeTokensByUserID(ctx, id)
+}
+
+// checkCurrentPassword returns ErrCurrentPasswordNotMatch unless password is
+// the one of user. Mismatches count as failed logins, so a stolen access
+// token cannot be used to guess the password past the lockout.
+func (s *userService) checkCurrentPassword(ctx context.Context, user *UserModel, password, clientIP string) error {
+	if err := s.passwordLimiter.Check(ctx, user.Username, clientIP); err != nil {
+		return err
+	}
+	if s.utils.CheckPasswordHash(password, user.Password) {
+		return nil
+	}
+	if err := s.passwordLimiter.Failed(ctx, user.Username, clientIP); err != nil {
+		return errors.Join(ErrCurrentPasswordNotMatch, err)
+	}
+	return ErrCurrentPasswordNotMatch
 }
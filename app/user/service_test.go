@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -71,51 +72,84 @@ var mockGetUserResponse2 = GetUserResponse{
 func TestCreateUserService(t *testing.T) {
 	t.Run("Should return success", func(tc *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByUsername", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
-		storage.On("CreateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
 		utils := &mockUtils{}
 		utils.On("HashPassword", mock.Anything).Return("password", nil)
+		roleAssigner := &mockRoleAssigner{}
+		roleAssigner.On("AssignDefaultRole", mock.Anything, int(mockUserModel[0].ID)).Return(nil)
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, roleAssigner, &fakePasswordLimiter{}, utils)
 
-		err := service.CreateUser(nil, mockCreateUserRequest)
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
 		assert.NoError(tc, err)
+		roleAssigner.AssertExpectations(tc)
+	})
+
+	t.Run("Should return error (AssignDefaultRole)", func(t *testing.T) {
+		storage := &mockUserStorage{}
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
+		utils := &mockUtils{}
+		utils.On("HashPassword", mock.Anything).Return("password", nil)
+		roleAssigner := &mockRoleAssigner{}
+		roleAssigner.On("AssignDefaultRole", mock.Anything, mock.Anything).Return(errors.New("error"))
+
+		service := NewUserService(storage, &mockTokenRevoker{}, roleAssigner, &fakePasswordLimiter{}, utils)
+
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
+		assert.Error(t, err)
 	})
 
 	t.Run("Should return error (Other error)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByUsername", mock.Anything).Return(nil, errors.New("error"))
-		storage.On("CreateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.CreateUser(nil, mockCreateUserRequest)
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
 		assert.Error(t, err)
 	})
 
 	t.Run("Should return error (Dup data)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByUsername", mock.Anything).Return(&mockUserModel[0], nil)
-		storage.On("CreateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.CreateUser(nil, mockCreateUserRequest)
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
 		assert.Error(t, err)
 	})
 
+	t.Run("Should return ErrUsernameAlreadyExists (Unique violation)", func(t *testing.T) {
+		storage := &mockUserStorage{}
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(gorm.ErrDuplicatedKey)
+		utils := &mockUtils{}
+		utils.On("HashPassword", mock.Anything).Return("password", nil)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
+		assert.ErrorIs(t, err, ErrUsernameAlreadyExists)
+	})
+
 	t.Run("Should return error (HashPassword)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByUsername", mock.Anything).Return(nil, nil)
-		storage.On("CreateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByUsername", mock.Anything, mock.Anything).Return(nil, nil)
+		storage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
 		utils := &mockUtils{}
 		utils.On("HashPassword", mock.Anything).Return("", errors.New("error"))
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.CreateUser(nil, mockCreateUserRequest)
+		err := service.CreateUser(context.Background(), mockCreateUserRequest)
 		assert.Error(t, err)
 	})
 }
@@ -123,12 +157,12 @@ func TestCreateUserService(t *testing.T) {
 func TestGetListUserService(t *testing.T) {
 	t.Run("Should return success", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetListUser", mock.Anything, mock.Anything).Return(mockUserModel, nil)
+		storage.On("GetListUser", mock.Anything, mock.Anything, mock.Anything).Return(mockUserModel, nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		got, err := service.GetListUser(nil, 1, 10)
+		got, err := service.GetListUser(context.Background(), 1, 10)
 		assert.NoError(t, err)
 		assert.Equal(t, len(mockUserModel), len(got))
 		assert.EqualValues(t, mockGetListUserResponse, got)
@@ -136,12 +170,12 @@ func TestGetListUserService(t *testing.T) {
 
 	t.Run("Should return error", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetListUser", mock.Anything, mock.Anything).Return([]UserModel{}, errors.New("error"))
+		storage.On("GetListUser", mock.Anything, mock.Anything, mock.Anything).Return([]UserModel{}, errors.New("error"))
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		_, err := service.GetListUser(nil, 1, 10)
+		_, err := service.GetListUser(context.Background(), 1, 10)
 		assert.Error(t, err)
 	})
 }
@@ -149,24 +183,24 @@ func TestGetListUserService(t *testing.T) {
 func TestCountListUserService(t *testing.T) {
 	t.Run("Should return success", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("CountListUser").Return(int64(1), nil)
+		storage.On("CountListUser", mock.Anything, mock.Anything).Return(int64(1), nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		got, err := service.CountListUser(nil)
+		got, err := service.CountListUser(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, 1, got)
 	})
 
 	t.Run("Should return error", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("CountListUser").Return(int64(0), errors.New("error"))
+		storage.On("CountListUser", mock.Anything, mock.Anything).Return(int64(0), errors.New("error"))
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		_, err := service.CountListUser(nil)
+		_, err := service.CountListUser(context.Background())
 		assert.Error(t, err)
 	})
 }
@@ -174,47 +208,47 @@ func TestCountListUserService(t *testing.T) {
 func TestGetUserByIDService(t *testing.T) {
 	t.Run("Should return success (Active)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], nil)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		got, err := service.GetUserByID(nil, 1)
+		got, err := service.GetUserByID(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.EqualValues(t, &mockGetUserResponse, got)
 	})
 
 	t.Run("Should return success (Inactive)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[1], nil)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[1], nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		got, err := service.GetUserByID(nil, 1)
+		got, err := service.GetUserByID(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.EqualValues(t, &mockGetUserResponse2, got)
 	})
 
 	t.Run("Should return error (Not found)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], gorm.ErrRecordNotFound)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], gorm.ErrRecordNotFound)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		_, err := service.GetUserByID(nil, 1)
+		_, err := service.GetUserByID(context.Background(), 1)
 		assert.Error(t, err)
 	})
 
 	t.Run("Should return error", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], errors.New("error"))
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], errors.New("error"))
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		_, err := service.GetUserByID(nil, 1)
+		_, err := service.GetUserByID(context.Background(), 1)
 		assert.Error(t, err)
 	})
 }
@@ -228,12 +262,12 @@ func TestUpdateUserService(t *testing.T) {
 		}
 		utils := &mockUtils{}
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], nil)
-		storage.On("UpdateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
+		storage.On("UpdateUser", mock.Anything, mock.Anything).Return(nil)
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.UpdateUser(nil, 1, dataUpdate)
+		err := service.UpdateUser(context.Background(), 1, dataUpdate)
 		assert.NoError(t, err)
 	})
 
@@ -245,34 +279,34 @@ func TestUpdateUserService(t *testing.T) {
 		}
 		utils := &mockUtils{}
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], nil)
-		storage.On("UpdateUser", mock.Anything).Return(nil)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
+		storage.On("UpdateUser", mock.Anything, mock.Anything).Return(nil)
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.UpdateUser(nil, 1, dataUpdate)
+		err := service.UpdateUser(context.Background(), 1, dataUpdate)
 		assert.NoError(t, err)
 	})
 
 	t.Run("Should return error (Not found)", func(t *testing.T) {
 		utils := &mockUtils{}
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.UpdateUser(nil, 1, UpdateUserRequest{})
+		err := service.UpdateUser(context.Background(), 1, UpdateUserRequest{})
 		assert.Error(t, err)
 	})
 
 	t.Run("Should return error (Other error)", func(t *testing.T) {
 		utils := &mockUtils{}
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(nil, errors.New("error"))
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.UpdateUser(nil, 1, UpdateUserRequest{})
+		err := service.UpdateUser(context.Background(), 1, UpdateUserRequest{})
 		assert.Error(t, err)
 	})
 }
@@ -280,35 +314,186 @@ func TestUpdateUserService(t *testing.T) {
 func TestDeleteUserService(t *testing.T) {
 	t.Run("Should return success", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(&mockUserModel[0], nil)
-		storage.On("DeleteUser", mock.Anything).Return(nil)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(&mockUserModel[0], nil)
+		storage.On("DeleteUser", mock.Anything, mock.Anything).Return(nil)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.DeleteUser(nil, 1)
+		err := service.DeleteUser(context.Background(), 1)
 		assert.NoError(t, err)
 	})
 
 	t.Run("Should return error (Not found)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.DeleteUser(nil, 1)
+		err := service.DeleteUser(context.Background(), 1)
 		assert.Error(t, err)
 	})
 
 	t.Run("Should return error (Other error)", func(t *testing.T) {
 		storage := &mockUserStorage{}
-		storage.On("GetUserByID", mock.Anything).Return(nil, errors.New("error"))
+		storage.On("GetUserByID", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
 		utils := &mockUtils{}
 
-		service := NewUserService(storage, utils)
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
 
-		err := service.DeleteUser(nil, 1)
+		err := service.DeleteUser(context.Background(), 1)
 		assert.Error(t, err)
 	})
 }
+
+func TestPatchMeService(t *testing.T) {
+	t.Run("Should only change the given names", func(t *testing.T) {
+		firstName := "first"
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", FirstName: "test", LastName: "test"}, nil)
+		storage.On("UpdateUser", mock.Anything, UserModel{ID: 1, Username: "test", FirstName: "first", LastName: "test"}).Return(nil)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, &mockUtils{})
+
+		err := service.PatchMe(context.Background(), 1, PatchMeRequest{FirstName: &firstName})
+		assert.NoError(t, err)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("Should change the email when the current password matches", func(t *testing.T) {
+		email := "new@example.com"
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(true)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash", Email: "old@example.com"}, nil)
+		storage.On("UpdateUser", mock.Anything, UserModel{ID: 1, Username: "test", Password: "hash", Email: "new@example.com"}).Return(nil)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.PatchMe(context.Background(), 1, PatchMeRequest{Email: &email, CurrentPassword: "password"})
+		assert.NoError(t, err)
+		storage.AssertExpectations(t)
+	})
+
+	t.Run("Should not change the email when the current password does not match", func(t *testing.T) {
+		email := "new@example.com"
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "wrong", "hash").Return(false)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash", Email: "old@example.com"}, nil)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.PatchMe(context.Background(), 1, PatchMeRequest{Email: &email, CurrentPassword: "wrong"})
+		assert.ErrorIs(t, err, ErrCurrentPasswordNotMatch)
+		storage.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should not check the current password while locked", func(t *testing.T) {
+		email := "new@example.com"
+		utils := &mockUtils{}
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash"}, nil)
+		limiter := &fakePasswordLimiter{maxFailures: 1, failures: map[string]int{"test": 1}}
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, limiter, utils)
+
+		err := service.PatchMe(context.Background(), 1, PatchMeRequest{Email: &email, CurrentPassword: "password"})
+		assert.ErrorIs(t, err, errTestLocked)
+		utils.AssertNotCalled(t, "CheckPasswordHash", mock.Anything, mock.Anything)
+		storage.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should return error (Not found)", func(t *testing.T) {
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, &mockUtils{})
+
+		err := service.PatchMe(context.Background(), 1, PatchMeRequest{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestChangePasswordService(t *testing.T) {
+	req := ChangePasswordRequest{CurrentPassword: "password", NewPassword: "new-password"}
+
+	t.Run("Should save the new password and revoke tokens", func(t *testing.T) {
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(true)
+		utils.On("HashPassword", "new-password").Return("new-hash", nil)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash"}, nil)
+		storage.On("UpdateUser", mock.Anything, UserModel{ID: 1, Username: "test", Password: "new-hash"}).Return(nil)
+		tokenRevoker := &mockTokenRevoker{}
+		tokenRevoker.On("RevokeTokensByUserID", mock.Anything, 1).Return(nil)
+
+		service := NewUserService(storage, tokenRevoker, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.NoError(t, err)
+		storage.AssertExpectations(t)
+		tokenRevoker.AssertExpectations(t)
+	})
+
+	t.Run("Should return error when current password does not match", func(t *testing.T) {
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(false)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash"}, nil)
+		tokenRevoker := &mockTokenRevoker{}
+
+		service := NewUserService(storage, tokenRevoker, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.ErrorIs(t, err, ErrCurrentPasswordNotMatch)
+		storage.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+		tokenRevoker.AssertNotCalled(t, "RevokeTokensByUserID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should lock out after too many wrong current passwords", func(t *testing.T) {
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(false)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash"}, nil)
+		limiter := &fakePasswordLimiter{maxFailures: 3}
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, limiter, utils)
+
+		for i := 0; i < 3; i++ {
+			err := service.ChangePassword(context.Background(), 1, req)
+			assert.ErrorIs(t, err, ErrCurrentPasswordNotMatch)
+		}
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.ErrorIs(t, err, errTestLocked)
+		utils.AssertNumberOfCalls(t, "CheckPasswordHash", 3)
+	})
+
+	t.Run("Should return error when revoke fails", func(t *testing.T) {
+		errWant := errors.New("error")
+		utils := &mockUtils{}
+		utils.On("CheckPasswordHash", "password", "hash").Return(true)
+		utils.On("HashPassword", "new-password").Return("new-hash", nil)
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(&UserModel{ID: 1, Username: "test", Password: "hash"}, nil)
+		storage.On("UpdateUser", mock.Anything, mock.Anything).Return(nil)
+		tokenRevoker := &mockTokenRevoker{}
+		tokenRevoker.On("RevokeTokensByUserID", mock.Anything, 1).Return(errWant)
+
+		service := NewUserService(storage, tokenRevoker, &mockRoleAssigner{}, &fakePasswordLimiter{}, utils)
+
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.ErrorIs(t, err, errWant)
+	})
+
+	t.Run("Should return error (Not found)", func(t *testing.T) {
+		storage := &mockUserStorage{}
+		storage.On("GetUserByID", mock.Anything, 1).Return(nil, gorm.ErrRecordNotFound)
+
+		service := NewUserService(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{}, &mockUtils{})
+
+		err := service.ChangePassword(context.Background(), 1, req)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
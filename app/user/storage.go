@@ -1,15 +1,19 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 type UserStorage interface {
-	CreateUser(UserModel) error
-	GetListUser(int, int) ([]UserModel, error)
-	GetUserByUsername(string) (*UserModel, error)
-	GetUserByID(int) (*UserModel, error)
-	CountListUser() (int64, error)
-	UpdateUser(UserModel) error
-	DeleteUser(int) error
+	CreateUser(context.Context, UserModel) error
+	GetListUser(context.Context, int, int) ([]UserModel, error)
+	GetUserByUsername(context.Context, string) (*UserModel, error)
+	GetUserByID(context.Context, int) (*UserModel, error)
+	CountListUser(context.Context) (int64, error)
+	UpdateUser(context.Context, UserModel) error
+	DeleteUser(context.Context, int) error
 }
 
 type userStorage struct {
@@ -20,60 +24,60 @@ func NewUserStorage(db *gorm.DB) UserStorage {
 	return &userStorage{db: db}
 }
 
-func (s *userStorage) CreateUser(user UserModel) error {
-	q := s.db.Table(UserTableName).Create(&user)
+func (s *userStorage) CreateUser(ctx context.Context, user UserModel) error {
+	q := s.db.WithContext(ctx).Table(UserTableName).Create(&user)
 	if q.Error != nil {
 		return q.Error
 	}
 	return nil
 }
 
-func (s *userStorage) GetListUser(limit, offset int) ([]UserModel, error) {
+func (s *userStorage) GetListUser(ctx context.Context, limit, offset int) ([]UserModel, error) {
 	var users []UserModel
-	q := s.db.Debug().Table(UserTableName).Limit(limit).Offset(offset).Find(&users)
+	q := s.db.WithContext(ctx).Table(UserTableName).Limit(limit).Offset(offset).Find(&users)
 	if q.Error != nil {
 		return nil, q.Error
 	}
 	return users, nil
 }
 
-func (s *userStorage) CountListUser() (int64, error) {
+func (s *userStorage) CountListUser(ctx context.Context) (int64, error) {
 	var count int64
-	q := s.db.Debug().Table(UserTableName).Count(&count)
+	q := s.db.WithContext(ctx).Table(UserTableName).Count(&count)
 	if q.Error != nil {
 		return 0, q.Error
 	}
 	return count, nil
 }
 
-func (s *userStorage) GetUserByUsername(username string) (*UserModel, error) {
+func (s *userStorage) GetUserByUsername(ctx context.Context, username string) (*UserModel, error) {
 	var user UserModel
-	q := s.db.Debug().Table(UserTableName).Where("username = ?", username).First(&user)
+	q := s.db.WithContext(ctx).Table(UserTableName).Where("username = ?", username).First(&user)
 	if q.Error != nil {
 		return nil, q.Error
 	}
 	return &user, nil
 }
 
-func (s *userStorage) GetUserByID(id int) (*UserModel, error) {
+func (s *userStorage) GetUserByID(ctx context.Context, id int) (*UserModel, error) {
 	var user UserModel
-	q := s.db.Debug().Table(UserTableName).Where("id = ?", id).First(&user)
+	q := s.db.WithContext(ctx).Table(UserTableName).Where("id = ?", id).First(&user)
 	if q.Error != nil {
 		return nil, q.Error
 	}
 	return &user, nil
 }
 
-func (s *userStorage) UpdateUser(user UserModel) error {
-	q := s.db.Table(UserTableName).Save(&user)
+func (s *userStorage) UpdateUser(ctx context.Context, user UserModel) error {
+	q := s.db.WithContext(ctx).Table(UserTableName).Save(&user)
 	if q.Error != nil {
 		return q.Error
 	}
 	return nil
 }
 
-func (s *userStorage) DeleteUser(id int) error {
-	q := s.db.Table(UserTableName).Where("id = ?", id).Delete(&UserModel{})
+func (s *userStorage) DeleteUser(ctx context.Context, id int) error {
+	q := s.db.WithContext(ctx).Table(UserTableName).Where("id = ?", id).Delete(&UserModel{})
 	if q.Error != nil {
 		return q.Error
 	}
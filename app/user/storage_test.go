@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -62,7 +63,7 @@ func (s *testStorageSuite) TestCreateUserStorage() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.CreateUser(s.data)
+		err := storage.CreateUser(context.Background(), s.data)
 		s.NoError(err)
 	})
 
@@ -72,7 +73,7 @@ func (s *testStorageSuite) TestCreateUserStorage() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.CreateUser(s.data)
+		err := storage.CreateUser(context.Background(), s.data)
 		s.Error(err)
 	})
 }
@@ -85,7 +86,7 @@ func (s *testStorageSuite) TestGetListUserStorage() {
 				AddRow(1, "test", "password", "test", "test", 1))
 
 		storage := NewUserStorage(s.gormDB)
-		got, err := storage.GetListUser(1, 10)
+		got, err := storage.GetListUser(context.Background(), 1, 10)
 		s.NoError(err)
 		s.EqualValues(mockUserStorageDataList, got)
 	})
@@ -94,7 +95,7 @@ func (s *testStorageSuite) TestGetListUserStorage() {
 		s.mock.ExpectQuery("SELECT").WillReturnError(sql.ErrConnDone)
 
 		storage := NewUserStorage(s.gormDB)
-		_, err := storage.GetListUser(1, 10)
+		_, err := storage.GetListUser(context.Background(), 1, 10)
 		s.Error(err)
 	})
 }
@@ -107,7 +108,7 @@ func (s *testStorageSuite) TestCountListUserStorage() {
 				AddRow(1))
 
 		storage := NewUserStorage(s.gormDB)
-		got, err := storage.CountListUser()
+		got, err := storage.CountListUser(context.Background())
 		s.NoError(err)
 		s.EqualValues(1, got)
 	})
@@ -116,7 +117,7 @@ func (s *testStorageSuite) TestCountListUserStorage() {
 		s.mock.ExpectQuery("SELECT").WillReturnError(sql.ErrConnDone)
 
 		storage := NewUserStorage(s.gormDB)
-		_, err := storage.CountListUser()
+		_, err := storage.CountListUser(context.Background())
 		s.Error(err)
 	})
 }
@@ -129,7 +130,7 @@ func (s *testStorageSuite) TestGetUserByUsername() {
 				AddRow(1, "test", "password", "test", "test", 1))
 
 		storage := NewUserStorage(s.gormDB)
-		got, err := storage.GetUserByUsername("test")
+		got, err := storage.GetUserByUsername(context.Background(), "test")
 		s.NoError(err)
 		s.EqualValues(mockUserStorageData, *got)
 	})
@@ -138,7 +139,7 @@ func (s *testStorageSuite) TestGetUserByUsername() {
 		s.mock.ExpectQuery("SELECT").WillReturnError(sql.ErrConnDone)
 
 		storage := NewUserStorage(s.gormDB)
-		_, err := storage.GetUserByUsername("test")
+		_, err := storage.GetUserByUsername(context.Background(), "test")
 		s.Error(err)
 	})
 }
@@ -151,7 +152,7 @@ func (s *testStorageSuite) TestGetUserByID() {
 				AddRow(1, "test", "password", "test", "test", 1))
 
 		storage := NewUserStorage(s.gormDB)
-		got, err := storage.GetUserByID(1)
+		got, err := storage.GetUserByID(context.Background(), 1)
 		s.NoError(err)
 		s.EqualValues(mockUserStorageData, *got)
 	})
@@ -160,7 +161,7 @@ func (s *testStorageSuite) TestGetUserByID() {
 		s.mock.ExpectQuery("SELECT").WillReturnError(sql.ErrConnDone)
 
 		storage := NewUserStorage(s.gormDB)
-		_, err := storage.GetUserByID(1)
+		_, err := storage.GetUserByID(context.Background(), 1)
 		s.Error(err)
 	})
 }
@@ -172,7 +173,7 @@ func (s *testStorageSuite) TestUpdateUser() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.UpdateUser(s.data)
+		err := storage.UpdateUser(context.Background(), s.data)
 		s.NoError(err)
 	})
 
@@ -182,7 +183,7 @@ func (s *testStorageSuite) TestUpdateUser() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.UpdateUser(s.data)
+		err := storage.UpdateUser(context.Background(), s.data)
 		s.Error(err)
 	})
 }
@@ -194,7 +195,7 @@ func (s *testStorageSuite) TestDeleteUser() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.DeleteUser(1)
+		err := storage.DeleteUser(context.Background(), 1)
 		s.NoError(err)
 	})
 
@@ -204,7 +205,7 @@ func (s *testStorageSuite) TestDeleteUser() {
 		s.mock.ExpectCommit()
 
 		storage := NewUserStorage(s.gormDB)
-		err := storage.DeleteUser(1)
+		err := storage.DeleteUser(context.Background(), 1)
 		s.Error(err)
 	})
 }
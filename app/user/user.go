@@ -1,8 +1,10 @@
 package user
 
 import (
-	"errors"
+	"context"
+	"go-restapi/app"
 	"go-restapi/utils"
+	"net/http"
 )
 
 type CreateUserRequest struct {
@@ -10,6 +12,8 @@ type CreateUserRequest struct {
 	Password  string `json:"password" validate:"required,min=8,max=50"`
 	FirstName string `json:"firstname" validate:"required,min=3,max=50"`
 	LastName  string `json:"lastname" validate:"required,min=3,max=50"`
+	// Email is where password reset mail goes.
+	Email string `json:"email" validate:"omitempty,email,max=255"`
 }
 
 type GetListUserResponse struct {
@@ -17,6 +21,7 @@ type GetListUserResponse struct {
 	Username  string `json:"username"`
 	FirstName string `json:"firstname"`
 	LastName  string `json:"lastname"`
+	Email     string `json:"email,omitempty"`
 	Status    string `json:"status"`
 }
 
@@ -25,6 +30,7 @@ type GetUserResponse struct {
 	Username  string `json:"username"`
 	FirstName string `json:"firstname"`
 	LastName  string `json:"lastname"`
+	Email     string `json:"email,omitempty"`
 	Status    string `json:"status"`
 }
 
@@ -34,6 +40,25 @@ type UpdateUserRequest struct {
 	Status    string `json:"status" validate:"required,oneof=active inactive"`
 }
 
+// PatchMeRequest changes the names and email of the caller; fields left out
+// stay. The email receives password reset links, so changing it needs the
+// current password as well as the access token.
+type PatchMeRequest struct {
+	FirstName       *string `json:"firstname" validate:"omitempty,min=3,max=50"`
+	LastName        *string `json:"lastname" validate:"omitempty,min=3,max=50"`
+	Email           *string `json:"email" validate:"omitempty,email,max=255"`
+	CurrentPassword string  `json:"currentPassword" validate:"required_with=Email"`
+	// ClientIP is set by the handler, for throttling guessed passwords.
+	ClientIP string `json:"-"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,max=50,nefield=CurrentPassword"`
+	// ClientIP is set by the handler, for throttling guessed passwords.
+	ClientIP string `json:"-"`
+}
+
 const UserTableName = "users"
 
 type UserModel struct {
@@ -42,14 +67,38 @@ type UserModel struct {
 	Password  string `db:"password"`
 	FirstName string `db:"first_name"`
 	LastName  string `db:"last_name"`
+	Email     string `db:"email"`
 	Status    int    `db:"status" gorm:"default:1"`
 }
 
-var ErrUsernameAlreadyExists = errors.New("username already exists")
-var ErrUserNotFound = errors.New("user not found")
+var ErrUsernameAlreadyExists = app.NewCodedError("USERNAME_ALREADY_EXISTS", http.StatusBadRequest, "username already exists")
+var ErrUserNotFound = app.NewCodedError("USER_NOT_FOUND", http.StatusNotFound, "user not found")
+var ErrCurrentPasswordNotMatch = app.NewCodedError("CURRENT_PASSWORD_NOT_MATCH", http.StatusBadRequest, "current password does not match")
+
+// TokenRevoker revokes the refresh tokens and unused password reset tokens
+// of a user, so a password change signs out every other session and voids
+// the reset links already mailed. auth.NewTokenRevoker implements it.
+type TokenRevoker interface {
+	RevokeTokensByUserID(ctx context.Context, userID int) error
+}
+
+// PasswordLimiter limits how often the current password of a user can be
+// guessed, sharing the lockout of failed logins. Check returns a coded error
+// that carries its retry time while the username or client IP is locked.
+// auth.NewPasswordLimiter implements it.
+type PasswordLimiter interface {
+	Check(ctx context.Context, username, clientIP string) error
+	Failed(ctx context.Context, username, clientIP string) error
+}
+
+// RoleAssigner gives a new user its default role.
+// role.NewDefaultRoleAssigner implements it.
+type RoleAssigner interface {
+	AssignDefaultRole(ctx context.Context, userID int) error
+}
 
-func New(userStorage UserStorage) UserHandler {
-	service := NewUserService(userStorage, utils.NewUtils())
+func New(userStorage UserStorage, tokenRevoker TokenRevoker, roleAssigner RoleAssigner, passwordLimiter PasswordLimiter) UserHandler {
+	service := NewUserService(userStorage, tokenRevoker, roleAssigner, passwordLimiter, utils.NewUtils())
 	handler := NewUserHandler(service, utils.NewUtils())
 	return handler
 }
@@ -8,6 +8,6 @@ import (
 
 func TestNew(t *testing.T) {
 	storage := &mockUserStorage{}
-	handler := New(storage)
+	handler := New(storage, &mockTokenRevoker{}, &mockRoleAssigner{}, &fakePasswordLimiter{})
 	assert.NotNil(t, handler)
 }
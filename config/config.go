@@ -1,7 +1,6 @@
 package config
 
 import (
-	"fmt"
 	"go-restapi/app"
 	"strings"
 
@@ -20,6 +19,5 @@ func GetConfig() (config app.Config, err error) {
 	}
 
 	err = viper.Unmarshal(&config)
-	fmt.Printf("%#v\n", config)
 	return
 }
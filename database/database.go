@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"go-restapi/app"
+	"go-restapi/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	// DriverMemory keeps data in process memory (see package memstore). It
+	// has no database, so NewDB and migrations do not apply to it.
+	DriverMemory = "memory"
+)
+
+// Drivers lists every supported db.driver value. Each one has its own
+// migrations directory.
+var Drivers = []string{DriverMySQL, DriverPostgres, DriverSQLite}
+
+// NewDB opens the database selected by db.driver, defaulting to MySQL, with
+// its queries traced.
+func NewDB(conf app.Config) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+	switch conf.Database.Driver {
+	case "", DriverMySQL:
+		db, err = NewMysqlDB(conf)
+	case DriverPostgres:
+		db, err = NewPostgresDB(conf)
+	case DriverSQLite:
+		db, err = NewSqliteDB(conf)
+	case DriverMemory:
+		return nil, fmt.Errorf("db.driver %q has no database", DriverMemory)
+	default:
+		return nil, fmt.Errorf("unknown db.driver %q", conf.Database.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(NewTracingPlugin(Driver(conf))); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Driver returns the normalized db.driver of conf.
+func Driver(conf app.Config) string {
+	if conf.Database.Driver == "" {
+		return DriverMySQL
+	}
+	return conf.Database.Driver
+}
+
+// newGormConfig is shared by every driver. TranslateError makes a unique key
+// violation come back as gorm.ErrDuplicatedKey whatever the database, and
+// queries are logged through slog as log.sql configures.
+func newGormConfig(conf app.Config) (*gorm.Config, error) {
+	level, err := logger.ParseGormLogLevel(conf.Log.SQL.Level)
+	if err != nil {
+		return nil, err
+	}
+	return &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.NewGormLogger(nil, level, conf.Log.SQL.SlowThreshold),
+	}, nil
+}
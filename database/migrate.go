@@ -0,0 +1,369 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const SchemaMigrationsTableName = "schema_migrations"
+
+// Each driver has its own directory of migrations, one file set per version
+// in every directory.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// migrationLockName names the database-wide lock held while migrating.
+const migrationLockName = "go-restapi.schema_migrations"
+
+const createSchemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// Migration is one schema change, read from a "<version>_<name>.up.sql" file
+// and its matching ".down.sql" file.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigrationModel struct {
+	Version   int64     `db:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// LoadMigrations reads the migrations in dir of fsys, ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up.sql", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// MigrationsDir is where `migrate create` writes new migration files for
+// driver, relative to the repository root.
+func MigrationsDir(driver string) string {
+	return filepath.Join("database", "migrations", driver)
+}
+
+// NewDriverMigrator returns a Migrator for the driver's migrations embedded in
+// the binary.
+func NewDriverMigrator(db *gorm.DB, driver string) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, path.Join("migrations", driver))
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(db, migrations), nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied. It holds the migration lock, so of several instances starting
+// at once only one applies each migration.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.run(db, migration.Up, func(tx *gorm.DB) error {
+				return record(tx, migration)
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Baseline records every pending migration up to version as applied without
+// running it, for databases whose tables were created before migrations, and
+// returns the ones it recorded. A version of 0 records them all.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if version > 0 && migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := record(db, migration); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest steps applied migrations and returns the ones it
+// rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down.sql", migration.Version, migration.Name)
+			}
+			err := m.run(db, migration.Down, func(tx *gorm.DB) error {
+				return tx.Table(SchemaMigrationsTableName).Where("version = ?", migration.Version).Delete(&schemaMigrationModel{}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration with the time it was applied, or nil
+// when it is pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]time.Time, error) {
+	if err := db.Exec(createSchemaMigrationsTable).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigrationModel
+	if err := db.Table(SchemaMigrationsTableName).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// run executes the statements of a migration file and then record in one
+// transaction. MySQL commits DDL implicitly, so there a failed migration may
+// still leave part of its changes behind.
+func (m *Migrator) run(db *gorm.DB, sql string, record func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(sql) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+func record(db *gorm.DB, migration Migration) error {
+	return db.Table(SchemaMigrationsTableName).Create(&schemaMigrationModel{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		AppliedAt: time.Now(),
+	}).Error
+}
+
+// locked runs fc on one connection holding a database-wide lock, so the
+// migrators of several instances run one after another. SQLite has no such
+// lock; there fc runs unlocked.
+func (m *Migrator) locked(ctx context.Context, fc func(db *gorm.DB) error) error {
+	var lock, unlock string
+	switch m.db.Dialector.Name() {
+	case DriverMySQL:
+		lock, unlock = "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)"
+	case DriverPostgres:
+		lock, unlock = "SELECT pg_advisory_lock(hashtext(?))::text", "SELECT pg_advisory_unlock(hashtext(?))::text"
+	default:
+		return fc(m.db.WithContext(ctx))
+	}
+
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		db := conn.Session(&gorm.Session{})
+		if err := takeLock(db, lock); err != nil {
+			return fmt.Errorf("take migration lock: %w", err)
+		}
+		// The lock belongs to the connection, which goes back to the pool,
+		// so release it even when ctx was canceled meanwhile.
+		defer db.WithContext(context.WithoutCancel(ctx)).Exec(unlock, migrationLockName)
+		return fc(db)
+	})
+}
+
+// takeLock runs the lock query, which blocks until the lock is free. MySQL
+// answers 1 once it holds the lock.
+func takeLock(db *gorm.DB, lock string) error {
+	var got sql.NullString
+	if err := db.Raw(lock, migrationLockName).Row().Scan(&got); err != nil {
+		return err
+	}
+	if db.Dialector.Name() == DriverMySQL && got.String != "1" {
+		return errors.New("GET_LOCK failed")
+	}
+	return nil
+}
+
+// splitStatements splits a migration file into statements ending with ";" at
+// the end of a line, dropping "--" comment lines.
+func splitStatements(sql string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+// CreateMigration writes empty up and down files for a new migration named
+// name into dir, versioned by the current time, and returns their paths.
+func CreateMigration(dir, name string, now time.Time) ([]string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+
+	version := now.UTC().Format("20060102150405")
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		p := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		if _, err := fmt.Fprintf(f, "-- %s %s\n", name, direction); err != nil {
+			f.Close()
+			return paths, err
+		}
+		if err := f.Close(); err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
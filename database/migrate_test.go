@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("Should order migrations by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/2_create_books.up.sql":   {Data: []byte("CREATE TABLE books (id INT);")},
+			"m/2_create_books.down.sql": {Data: []byte("DROP TABLE books;")},
+			"m/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+			"m/README.md":               {Data: []byte("ignored")},
+		}
+
+		got, err := LoadMigrations(fsys, "m")
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INT);"},
+			{Version: 2, Name: "create_books", Up: "CREATE TABLE books (id INT);", Down: "DROP TABLE books;"},
+		}, got)
+	})
+
+	t.Run("Should fail when up.sql is missing", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := LoadMigrations(fsys, "m")
+		assert.EqualError(t, err, "migration 1_create_users has no up.sql")
+	})
+
+	t.Run("Should fail when a version has two names", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+			"m/1_create_people.up.sql":  {Data: []byte("CREATE TABLE people (id INT);")},
+			"m/1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		}
+
+		_, err := LoadMigrations(fsys, "m")
+		assert.Error(t, err)
+	})
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	var want []Migration
+	for _, driver := range Drivers {
+		migrator, err := NewDriverMigrator(nil, driver)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrator.migrations)
+		for i, m := range migrator.migrations {
+			assert.NotEmpty(t, splitStatements(m.Up), "%s %d_%s up", driver, m.Version, m.Name)
+			assert.NotEmpty(t, splitStatements(m.Down), "%s %d_%s down", driver, m.Version, m.Name)
+			if want != nil && assert.Less(t, i, len(want), driver) {
+				assert.Equal(t, want[i].Version, m.Version, driver)
+				assert.Equal(t, want[i].Name, m.Name, driver)
+			}
+		}
+		if want == nil {
+			want = migrator.migrations
+		}
+		assert.Len(t, migrator.migrations, len(want), driver)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- two tables
+CREATE TABLE a (
+    id INT
+);
+
+CREATE TABLE b (id INT);
+INSERT INTO b VALUES (1)`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n    id INT\n)",
+		"CREATE TABLE b (id INT)",
+		"INSERT INTO b VALUES (1)",
+	}, splitStatements(sql))
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	paths, err := CreateMigration(dir, "Add Book ISBN", time.Date(2023, 10, 2, 3, 4, 5, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "20231002030405_add_book_isbn.up.sql"),
+		filepath.Join(dir, "20231002030405_add_book_isbn.down.sql"),
+	}, paths)
+
+	migrations, err := LoadMigrations(os.DirFS(dir), ".")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 1)
+
+	_, err = CreateMigration(dir, "bad-name", time.Now())
+	assert.Error(t, err)
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlmockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { sqlmockDB.Close() })
+
+	mock.ExpectQuery(`SELECT VERSION()`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("7.2"))
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlmockDB}), &gorm.Config{})
+	assert.NoError(t, err)
+	return gormDB, mock
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INT);", Down: "DROP TABLE users;"},
+	{Version: 2, Name: "create_books", Up: "CREATE TABLE books (id INT);", Down: "DROP TABLE books;"},
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, -1)")).
+		WithArgs(migrationLockName).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT RELEASE_LOCK(?)")).
+		WithArgs(migrationLockName).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigratorUp(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLock(mock)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `schema_migrations`")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).AddRow(1, "create_users", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE books (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `schema_migrations`")).
+		WithArgs(2, "create_books", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	got, err := NewMigrator(db, testMigrations).Up(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, testMigrations[1:], got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorUpLock(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, -1)")).
+		WithArgs(migrationLockName).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(nil))
+
+	_, err := NewMigrator(db, testMigrations).Up(context.Background())
+	assert.ErrorContains(t, err, "take migration lock")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorBaseline(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLock(mock)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `schema_migrations`")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `schema_migrations`")).
+		WithArgs(1, "create_users", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	got, err := NewMigrator(db, testMigrations).Baseline(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testMigrations[:1], got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorDown(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectLock(mock)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `schema_migrations`")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+			AddRow(1, "create_users", time.Now()).
+			AddRow(2, "create_books", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DROP TABLE books")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `schema_migrations` WHERE version = ?")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	got, err := NewMigrator(db, testMigrations).Down(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, testMigrations[1:], got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorStatus(t *testing.T) {
+	db, mock := newMockDB(t)
+	appliedAt := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `schema_migrations`")).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).AddRow(1, "create_users", appliedAt))
+
+	got, err := NewMigrator(db, testMigrations).Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []MigrationStatus{
+		{Migration: testMigrations[0], AppliedAt: &appliedAt},
+		{Migration: testMigrations[1]},
+	}, got)
+}
@@ -0,0 +1,41 @@
+CREATE TABLE roles (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_roles_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE role_permissions (
+    role_id BIGINT NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role_id, permission),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE user_roles (
+    user_id BIGINT NOT NULL,
+    role_id BIGINT NOT NULL,
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Default roles: admin may do everything, user may read books.
+INSERT INTO roles (name) VALUES ('admin'), ('user');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, permission FROM roles
+CROSS JOIN (
+    SELECT 'books:read' AS permission UNION ALL
+    SELECT 'books:write' UNION ALL
+    SELECT 'books:delete' UNION ALL
+    SELECT 'users:read' UNION ALL
+    SELECT 'users:write' UNION ALL
+    SELECT 'users:delete' UNION ALL
+    SELECT 'roles:read' UNION ALL
+    SELECT 'roles:write'
+) AS permissions
+WHERE name = 'admin';
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'books:read' FROM roles WHERE name = 'user';
@@ -0,0 +1,6 @@
+-- Admins may read and change the log level and ask for debug logs.
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'logs:read' FROM roles WHERE name = 'admin';
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'logs:write' FROM roles WHERE name = 'admin';
@@ -0,0 +1,3 @@
+-- Where password reset mail is sent. Optional, users without one cannot
+-- reset their password.
+ALTER TABLE users ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '';
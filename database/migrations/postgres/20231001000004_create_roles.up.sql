@@ -0,0 +1,37 @@
+CREATE TABLE roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    CONSTRAINT uk_roles_name UNIQUE (name)
+);
+
+CREATE TABLE role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+-- Default roles: admin may do everything, user may read books.
+INSERT INTO roles (name) VALUES ('admin'), ('user');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, permission FROM roles
+CROSS JOIN (
+    SELECT 'books:read' AS permission UNION ALL
+    SELECT 'books:write' UNION ALL
+    SELECT 'books:delete' UNION ALL
+    SELECT 'users:read' UNION ALL
+    SELECT 'users:write' UNION ALL
+    SELECT 'users:delete' UNION ALL
+    SELECT 'roles:read' UNION ALL
+    SELECT 'roles:write'
+) AS permissions
+WHERE name = 'admin';
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'books:read' FROM roles WHERE name = 'user';
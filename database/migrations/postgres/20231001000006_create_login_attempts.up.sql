@@ -0,0 +1,8 @@
+-- Failed logins per subject, "user:<username>" or "ip:<client ip>".
+CREATE TABLE login_attempts (
+    subject VARCHAR(255) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,35 @@
+-- TOTP second factor. enabled_at is NULL until the first code confirms the
+-- secret; last_used_step keeps every code single-use.
+CREATE TABLE user_mfa (
+    user_id BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMPTZ NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Recovery codes are stored as their SHA-256 and work once.
+CREATE TABLE mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_mfa_recovery_codes_user_code UNIQUE (user_id, code_hash)
+);
+
+-- Logins waiting for their second factor, "verify", or for the user to
+-- enroll one, "enroll".
+CREATE TABLE mfa_challenges (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL,
+    purpose VARCHAR(16) NOT NULL,
+    expired_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_mfa_challenges_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_mfa_challenges_user_id ON mfa_challenges (user_id);
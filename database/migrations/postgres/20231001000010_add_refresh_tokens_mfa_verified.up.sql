@@ -0,0 +1,3 @@
+-- Whether the session was opened with the second factor. Refreshing a
+-- session without it fails once the user needs one.
+ALTER TABLE refresh_tokens ADD COLUMN mfa_verified BOOLEAN NOT NULL DEFAULT FALSE;
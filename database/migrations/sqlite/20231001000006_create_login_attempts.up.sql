@@ -0,0 +1,8 @@
+-- Failed logins per subject, "user:<username>" or "ip:<client ip>".
+CREATE TABLE login_attempts (
+    subject VARCHAR(255) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME NOT NULL,
+    locked_until DATETIME NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"go-restapi/app"
+	"net"
+	"net/url"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func NewPostgresDB(conf app.Config) (*gorm.DB, error) {
+	gormConfig, err := newGormConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	sslMode := conf.Database.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(conf.Database.Username, conf.Database.Password),
+		Host:     net.JoinHostPort(conf.Database.Host, conf.Database.Port),
+		Path:     conf.Database.Database,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+
+	gormDB, err := gorm.Open(postgres.Open(dsn.String()), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	return gormDB, nil
+}
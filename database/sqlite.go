@@ -0,0 +1,33 @@
+package database
+
+import (
+	"go-restapi/app"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSqliteDB opens the SQLite file at db.path, or a private in-memory
+// database when the path is ":memory:". The driver is pure Go, so it needs no
+// cgo or external server.
+func NewSqliteDB(conf app.Config) (*gorm.DB, error) {
+	gormConfig, err := newGormConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := conf.Database.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	gormDB, err := gorm.Open(sqlite.Open(dsn), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows one writer at a time, and every connection to ":memory:"
+	// would see its own empty database.
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return gormDB, nil
+}
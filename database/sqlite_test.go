@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"go-restapi/app"
+	"go-restapi/app/auth"
+	"go-restapi/app/book"
+	"go-restapi/app/role"
+	"go-restapi/app/user"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestSqliteStorages runs the migrations and the real storages against an
+// in-memory SQLite database.
+func TestSqliteStorages(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(app.Config{Database: app.Database{Driver: DriverSQLite, Path: ":memory:"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	migrator, err := NewDriverMigrator(db, DriverSQLite)
+	assert.NoError(t, err)
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	t.Run("UserStorage", func(t *testing.T) {
+		storage := user.NewUserStorage(db)
+		u := user.UserModel{Username: "sqlite", Password: "hash", FirstName: "first", LastName: "last", Email: "sqlite@example.com", Status: 1}
+		assert.NoError(t, storage.CreateUser(ctx, u))
+		assert.ErrorIs(t, storage.CreateUser(ctx, u), gorm.ErrDuplicatedKey)
+
+		got, err := storage.GetUserByUsername(ctx, "sqlite")
+		assert.NoError(t, err)
+		assert.Equal(t, "first", got.FirstName)
+		assert.Equal(t, "sqlite@example.com", got.Email)
+
+		count, err := storage.CountListUser(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("BookStorage", func(t *testing.T) {
+		storage := book.NewBookStorage(db)
+		assert.NoError(t, storage.CreateBook(ctx, book.BookRequest{Title: "title", Author: "author"}))
+
+		books, err := storage.GetListBook(ctx, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, books, 1)
+
+		assert.NoError(t, storage.DeleteBook(ctx, int(books[0].ID)))
+		_, err = storage.GetBookByID(ctx, int(books[0].ID))
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("RefreshTokenStorage", func(t *testing.T) {
+		u, err := user.NewUserStorage(db).GetUserByUsername(ctx, "sqlite")
+		assert.NoError(t, err)
+
+		storage := auth.NewRefreshTokenStorage(db)
+		token := auth.RefreshTokenModel{UserID: int(u.ID), Token: "old", ExpiredAt: time.Now().Add(time.Hour), UpdatedBy: "sqlite"}
+		assert.NoError(t, storage.CreateRefreshToken(ctx, token))
+		assert.ErrorIs(t, storage.CreateRefreshToken(ctx, token), gorm.ErrDuplicatedKey)
+
+		rotated := auth.RefreshTokenModel{UserID: int(u.ID), Token: "new", ExpiredAt: time.Now().Add(time.Hour), UpdatedBy: "sqlite"}
+		assert.NoError(t, storage.RotateRefreshToken(ctx, "old", rotated))
+		assert.ErrorIs(t, storage.RotateRefreshToken(ctx, "old", rotated), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.RevokeRefreshTokensByUserID(ctx, int(u.ID)))
+		got, err := storage.GetRefreshTokenByToken(ctx, "new")
+		assert.NoError(t, err)
+		assert.NotNil(t, got.RevokedAt)
+	})
+
+	t.Run("ResetTokenStorage", func(t *testing.T) {
+		u, err := user.NewUserStorage(db).GetUserByUsername(ctx, "sqlite")
+		assert.NoError(t, err)
+
+		storage := auth.NewResetTokenStorage(db)
+		token := auth.ResetTokenModel{UserID: int(u.ID), TokenHash: "hash", ExpiredAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, storage.CreateResetToken(ctx, token))
+		assert.ErrorIs(t, storage.CreateResetToken(ctx, token), gorm.ErrDuplicatedKey)
+
+		assert.NoError(t, storage.UseResetToken(ctx, "hash"))
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "hash"), gorm.ErrRecordNotFound)
+
+		got, err := storage.GetResetTokenByHash(ctx, "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, int(u.ID), got.UserID)
+		assert.NotNil(t, got.UsedAt)
+
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: int(u.ID), TokenHash: "other", ExpiredAt: time.Now().Add(time.Hour)}))
+		assert.NoError(t, storage.UseResetTokensByUserID(ctx, int(u.ID)))
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "other"), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("MFAStorage", func(t *testing.T) {
+		u, err := user.NewUserStorage(db).GetUserByUsername(ctx, "sqlite")
+		assert.NoError(t, err)
+		userID := int(u.ID)
+
+		storage := auth.NewMFAStorage(db)
+		assert.NoError(t, storage.SaveMFA(ctx, auth.MFAModel{UserID: userID, Secret: "first"}))
+		assert.NoError(t, storage.SaveMFA(ctx, auth.MFAModel{UserID: userID, Secret: "second"}))
+		assert.ErrorIs(t, storage.UseMFAStep(ctx, userID, 1), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.EnableMFA(ctx, userID, 10, []string{"a", "b"}))
+		assert.ErrorIs(t, storage.EnableMFA(ctx, userID, 11, nil), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, storage.UseMFAStep(ctx, userID, 10), gorm.ErrRecordNotFound)
+		assert.NoError(t, storage.UseMFAStep(ctx, userID, 11))
+
+		got, err := storage.GetMFA(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", got.Secret)
+		assert.NotNil(t, got.EnabledAt)
+		assert.Equal(t, int64(11), got.LastUsedStep)
+
+		assert.NoError(t, storage.UseRecoveryCode(ctx, userID, "a"))
+		assert.ErrorIs(t, storage.UseRecoveryCode(ctx, userID, "a"), gorm.ErrRecordNotFound)
+
+		challenge := auth.MFAChallengeModel{UserID: userID, TokenHash: "hash", Purpose: auth.MFAChallengeVerify, ExpiredAt: time.Now().Add(time.Minute)}
+		assert.NoError(t, storage.CreateMFAChallenge(ctx, challenge))
+		assert.ErrorIs(t, storage.CreateMFAChallenge(ctx, challenge), gorm.ErrDuplicatedKey)
+		assert.NoError(t, storage.UseMFAChallenge(ctx, "hash"))
+		assert.ErrorIs(t, storage.UseMFAChallenge(ctx, "hash"), gorm.ErrRecordNotFound)
+		gotChallenge, err := storage.GetMFAChallengeByHash(ctx, "hash")
+		assert.NoError(t, err)
+		assert.NotNil(t, gotChallenge.UsedAt)
+
+		assert.NoError(t, storage.DeleteMFA(ctx, userID))
+		_, err = storage.GetMFA(ctx, userID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, storage.UseRecoveryCode(ctx, userID, "b"), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("LoginAttemptStorage", func(t *testing.T) {
+		storage := auth.NewLoginAttemptStorage(db)
+		_, err := storage.GetLoginAttempt(ctx, "user:sqlite")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		lockedUntil := now.Add(time.Minute)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:sqlite", func(attempt *auth.LoginAttemptModel) {
+					attempt.Failures++
+					attempt.LastFailedAt = now
+					if attempt.Failures >= 2 {
+						attempt.LockedUntil = &lockedUntil
+					}
+				}))
+			}()
+		}
+		wg.Wait()
+
+		got, err := storage.GetLoginAttempt(ctx, "user:sqlite")
+		assert.NoError(t, err)
+		assert.Equal(t, 10, got.Failures)
+		if assert.NotNil(t, got.LockedUntil) {
+			assert.True(t, lockedUntil.Equal(*got.LockedUntil))
+		}
+
+		assert.NoError(t, storage.DeleteLoginAttempt(ctx, "user:sqlite"))
+		_, err = storage.GetLoginAttempt(ctx, "user:sqlite")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.UpdateLoginAttempt(ctx, "ip:old", func(attempt *auth.LoginAttemptModel) {
+			attempt.LastFailedAt = now.Add(-time.Hour)
+		}))
+		assert.NoError(t, storage.UpdateLoginAttempt(ctx, "ip:recent", func(attempt *auth.LoginAttemptModel) {
+			attempt.LastFailedAt = now
+		}))
+		assert.NoError(t, storage.DeleteExpiredLoginAttempts(ctx, now.Add(-time.Minute)))
+		_, err = storage.GetLoginAttempt(ctx, "ip:old")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = storage.GetLoginAttempt(ctx, "ip:recent")
+		assert.NoError(t, err)
+	})
+
+	t.Run("RoleStorage", func(t *testing.T) {
+		u, err := user.NewUserStorage(db).GetUserByUsername(ctx, "sqlite")
+		assert.NoError(t, err)
+
+		storage := role.NewRoleStorage(db)
+		r, err := storage.GetRoleByName(ctx, "user")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.AssignRole(ctx, int(u.ID), r.ID))
+		assert.NoError(t, storage.AssignRole(ctx, int(u.ID), r.ID))
+
+		permissions, err := storage.GetPermissionsByUserID(ctx, int(u.ID))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{role.PermissionBooksRead}, permissions)
+	})
+
+	rolledBack, err := migrator.Down(ctx, len(migrator.migrations))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(migrator.migrations))
+	assert.False(t, db.Migrator().HasTable(user.UserTableName))
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"go-restapi/app"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// tracingPlugin gives every query GORM runs a client span, a child of the
+// span in the query's context. Only the SQL with its placeholders is
+// recorded, never the values bound to them.
+type tracingPlugin struct {
+	system attribute.KeyValue
+}
+
+// NewTracingPlugin returns the GORM plugin tracing the queries of driver.
+func NewTracingPlugin(driver string) gorm.Plugin {
+	system := semconv.DBSystemKey.String(driver)
+	switch driver {
+	case DriverMySQL:
+		system = semconv.DBSystemMySQL
+	case DriverPostgres:
+		system = semconv.DBSystemPostgreSQL
+	case DriverSQLite:
+		system = semconv.DBSystemSqlite
+	}
+	return &tracingPlugin{system: system}
+}
+
+func (p *tracingPlugin) Name() string {
+	return "tracing"
+}
+
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+type tracingContext struct {
+	span   trace.Span
+	parent context.Context
+}
+
+func (p *tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		ctx, span := otel.Tracer(app.TracerName).Start(parent, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(p.system),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, tracingContext{span: span, parent: parent})
+	}
+}
+
+func (p *tracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	tc := v.(tracingContext)
+	defer tc.span.End()
+	// Later statements of the same session must not be children of this one.
+	db.Statement.Context = tc.parent
+
+	tc.span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		semconv.DBSQLTable(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tc.span.RecordError(err)
+		tc.span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"go-restapi/app"
+	"go-restapi/app/book"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func TestTracingPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, err := NewDB(app.Config{Database: app.Database{Driver: DriverSQLite, Path: ":memory:"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, db.Exec("CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT, author TEXT, created_at DATETIME, updated_at DATETIME)").Error)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	storage := book.NewBookStorage(db)
+	assert.NoError(t, storage.CreateBook(ctx, book.BookRequest{Title: "title", Author: "secret author"}))
+	_, err = storage.GetBookByID(ctx, 42)
+	assert.Error(t, err)
+	parent.End()
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			spans = append(spans, span)
+		}
+	}
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	create, query := spans[0], spans[1]
+	assert.Equal(t, "gorm.create", create.Name())
+	assert.Contains(t, create.Attributes(), semconv.DBSystemSqlite)
+	assert.Contains(t, create.Attributes(), semconv.DBSQLTable("books"))
+	for _, attr := range create.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "secret author")
+	}
+
+	assert.Equal(t, "gorm.query", query.Name())
+	assert.Equal(t, codes.Unset, query.Status().Code, "record not found is not an error")
+}
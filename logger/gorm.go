@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// driverValue matches the values drivers quote in their errors, as in
+// MySQL's "Duplicate entry 'alice' for key 'users.username'" or the
+// "Key (username)=(alice)" detail of PostgreSQL. Key names after "for key"
+// are kept.
+var driverValue = regexp.MustCompile(`(for key )?'(?:[^'\\]|\\.)*'|=\([^)]*\)`)
+
+// GormLogger sends the logs of GORM to slog instead of stdout, with the
+// transaction-id of the request that ran the query. The values bound to a
+// query are masked before the SQL is written.
+type GormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger returns a GORM logger writing to l, or to the slog default
+// at the time of each record when l is nil.
+//
+// level picks what is logged: errors at Error, slow queries as well at Warn,
+// and every query at Info. Queries that are neither failed nor slow are
+// logged at slog's debug level, so they only show when the logger or the
+// request (see WithDebug) logs debug records. A zero slowThreshold disables
+// slow query detection.
+func NewGormLogger(l *slog.Logger, level gormlogger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: l, level: level, slowThreshold: slowThreshold}
+}
+
+// ParseGormLogLevel parses silent, error, warn or info. Empty means info.
+func ParseGormLogLevel(level string) (gormlogger.LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn":
+		return gormlogger.Warn, nil
+	case "", "info":
+		return gormlogger.Info, nil
+	default:
+		return 0, fmt.Errorf("unknown sql log level %q", level)
+	}
+}
+
+func (l *GormLogger) slog() *slog.Logger {
+	if l.logger == nil {
+		return slog.Default()
+	}
+	return l.logger
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.log(ctx, slog.LevelInfo, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.log(ctx, slog.LevelWarn, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.log(ctx, slog.LevelError, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a query once it has run. A missing record is not a failure.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+
+	var level slog.Level
+	var msg string
+	var attrs []slog.Attr
+	switch {
+	case failed && l.level >= gormlogger.Error:
+		level, msg = slog.LevelError, "query failed"
+		attrs = append(attrs, slog.String("error", redactQueryError(err)))
+	case slow && l.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+		attrs = append(attrs, slog.Duration("slow_threshold", l.slowThreshold))
+	case l.level >= gormlogger.Info:
+		level, msg = slog.LevelDebug, "query"
+	default:
+		return
+	}
+	if !l.slog().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs = append(attrs,
+		slog.String("sql", sql),
+		slog.Int64("rows_affected", rows),
+		slog.Duration("elapsed", elapsed),
+	)
+	l.log(ctx, level, msg, attrs...)
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if id := TransactionID(ctx); id != "" {
+		attrs = append(attrs, slog.String("transaction-id", id))
+	}
+	l.slog().LogAttrs(ctx, level, msg, attrs...)
+}
+
+// redactQueryError masks the values quoted in a driver error, which are the
+// same bound values ParamsFilter masks in the SQL.
+func redactQueryError(err error) string {
+	return driverValue.ReplaceAllStringFunc(err.Error(), func(s string) string {
+		switch {
+		case strings.HasPrefix(s, "for key "):
+			return s
+		case strings.HasPrefix(s, "="):
+			return "=(" + RedactedValue + ")"
+		default:
+			return "'" + RedactedValue + "'"
+		}
+	})
+}
+
+// ParamsFilter masks the text values bound to a query, which may be
+// usernames, password hashes or tokens. Numbers, times and other values are
+// kept, so ids and limits still show.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	masked := make([]interface{}, len(params))
+	for i, p := range params {
+		switch p.(type) {
+		case string, []byte, *string:
+			masked[i] = RedactedValue
+		default:
+			masked[i] = p
+		}
+	}
+	return sql, masked
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestGormLogger(t *testing.T) {
+	newLogger := func(level gormlogger.LogLevel, slogLevel slog.Level) (*GormLogger, *bytes.Buffer) {
+		var out bytes.Buffer
+		handler := slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slogLevel})
+		return NewGormLogger(slog.New(handler), level, 100*time.Millisecond), &out
+	}
+	query := func() (string, int64) { return "UPDATE users SET status = 1 WHERE id = 1", 3 }
+	ctx := WithTransactionID(context.Background(), "tx-1")
+
+	t.Run("Should log failed queries with the transaction-id", func(t *testing.T) {
+		l, out := newLogger(gormlogger.Error, slog.LevelInfo)
+		l.Trace(ctx, time.Now(), query, nil)
+		l.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+		assert.Empty(t, out.String())
+
+		l.Trace(ctx, time.Now(), query, errors.New("connection refused"))
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "query failed", record["msg"])
+		assert.Equal(t, "connection refused", record["error"])
+		assert.Equal(t, "UPDATE users SET status = 1 WHERE id = 1", record["sql"])
+		assert.Equal(t, float64(3), record["rows_affected"])
+		assert.Equal(t, "tx-1", record["transaction-id"])
+	})
+
+	t.Run("Should mask the values quoted in driver errors", func(t *testing.T) {
+		l, out := newLogger(gormlogger.Error, slog.LevelInfo)
+		l.Trace(ctx, time.Now(), query, errors.New("Error 1062 (23000): Duplicate entry 'alice@example.com' for key 'users.email'"))
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "Error 1062 (23000): Duplicate entry '[REDACTED]' for key 'users.email'", record["error"])
+
+		out.Reset()
+		l.Trace(ctx, time.Now(), query, errors.New(`duplicate key value violates unique constraint "users_email_key": Key (email)=(alice@example.com) already exists.`))
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, `duplicate key value violates unique constraint "users_email_key": Key (email)=([REDACTED]) already exists.`, record["error"])
+		assert.NotContains(t, out.String(), "alice")
+	})
+
+	t.Run("Should warn about slow queries", func(t *testing.T) {
+		l, out := newLogger(gormlogger.Warn, slog.LevelInfo)
+		l.Trace(ctx, time.Now(), query, nil)
+		assert.Empty(t, out.String())
+
+		l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+		assert.Contains(t, out.String(), `"msg":"slow query"`)
+		assert.Contains(t, out.String(), `"level":"WARN"`)
+	})
+
+	t.Run("Should log every query as debug in info mode", func(t *testing.T) {
+		l, out := newLogger(gormlogger.Info, slog.LevelInfo)
+		l.Trace(ctx, time.Now(), query, nil)
+		assert.Empty(t, out.String(), "debug records are below the slog level")
+
+		l, out = newLogger(gormlogger.Info, slog.LevelDebug)
+		l.Trace(ctx, time.Now(), query, nil)
+		assert.Contains(t, out.String(), `"level":"DEBUG"`)
+		assert.Contains(t, out.String(), `"msg":"query"`)
+	})
+
+	t.Run("Should log nothing when silent", func(t *testing.T) {
+		l, out := newLogger(gormlogger.Info, slog.LevelDebug)
+		l.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), query, errors.New("connection refused"))
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("Should mask text parameters", func(t *testing.T) {
+		l, _ := newLogger(gormlogger.Info, slog.LevelDebug)
+		now := time.Now()
+		sql, params := l.ParamsFilter(ctx, "SELECT ?, ?, ?", "admin", 7, now)
+		assert.Equal(t, "SELECT ?, ?, ?", sql)
+		assert.Equal(t, []interface{}{RedactedValue, 7, now}, params)
+	})
+}
+
+func TestParseGormLogLevel(t *testing.T) {
+	level, err := ParseGormLogLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, gormlogger.Info, level)
+
+	level, err = ParseGormLogLevel("Warn")
+	assert.NoError(t, err)
+	assert.Equal(t, gormlogger.Warn, level)
+
+	_, err = ParseGormLogLevel("loud")
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+type debugKey struct{}
+
+type transactionIDKey struct{}
+
+// Level returns the level the logger currently logs from.
+func Level() slog.Level {
+	return logLevel.Level()
+}
+
+// SetLevel changes the level of the logger at runtime. level is a slog level
+// name such as "debug", "info", "warn" or "error"; empty means info.
+func SetLevel(level string) error {
+	var l slog.Level
+	if strings.TrimSpace(level) != "" {
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			return err
+		}
+	}
+	logLevel.Set(l)
+	return nil
+}
+
+// WithDebug returns a context whose records are logged down to debug level,
+// whatever the level of the logger.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey{}, true)
+}
+
+// DebugEnabled reports whether ctx was made by WithDebug.
+func DebugEnabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(debugKey{}).(bool)
+	return enabled
+}
+
+// WithTransactionID returns a context carrying the transaction-id of the
+// request, for records logged without the request's handler such as queries.
+func WithTransactionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, transactionIDKey{}, id)
+}
+
+// TransactionID returns the transaction-id set by WithTransactionID, or "".
+func TransactionID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(transactionIDKey{}).(string)
+	return id
+}
+
+// NewLevelHandler wraps h, which should let debug records through, to filter
+// records by the level of the logger, except those logged with a context
+// made by WithDebug.
+func NewLevelHandler(h slog.Handler) slog.Handler {
+	return levelHandler{h}
+}
+
+// levelHandler filters records by logLevel, except those logged with a
+// context made by WithDebug.
+type levelHandler struct {
+	slog.Handler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= logLevel.Level() || DebugEnabled(ctx)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	var out bytes.Buffer
+	l := slog.New(levelHandler{slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})})
+	t.Cleanup(func() { _ = SetLevel("info") })
+
+	assert.NoError(t, SetLevel("warn"))
+	l.InfoContext(context.Background(), "hidden")
+	assert.Empty(t, out.String())
+
+	l.DebugContext(WithDebug(context.Background()), "debug request")
+	assert.Contains(t, out.String(), "debug request")
+
+	out.Reset()
+	assert.NoError(t, SetLevel(""))
+	assert.Equal(t, slog.LevelInfo, Level())
+	l.With("key", "value").InfoContext(context.Background(), "shown")
+	assert.Contains(t, out.String(), `"key":"value"`)
+
+	assert.Error(t, SetLevel("loud"))
+	assert.Equal(t, slog.LevelInfo, Level())
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces every secret removed from a log record.
+const RedactedValue = "[REDACTED]"
+
+var redactKeys = struct {
+	sync.RWMutex
+	keys map[string]bool
+}{keys: map[string]bool{}}
+
+// Attributes under these keys are never written, whatever their value.
+var defaultRedactKeys = []string{
+	"password",
+	"token",
+	"access_token",
+	"refresh_token",
+	"authorization",
+	"secret",
+	"private_key",
+	"username",
+}
+
+// Values under these keys are ours and safe, even when they look like a
+// token.
+var safeKeys = map[string]bool{
+	"transaction-id": true,
+	"trace_id":       true,
+	"span_id":        true,
+}
+
+// Token and hash shaped values, masked wherever they appear in a message or
+// a string attribute.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\bbearer\s+\S+`), "Bearer " + RedactedValue},
+	{regexp.MustCompile(`\beyJ[\w-]*\.[\w-]+\.[\w-]*`), RedactedValue},
+	{regexp.MustCompile(`\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}`), RedactedValue},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), RedactedValue},
+}
+
+func init() {
+	AddRedactKeys(defaultRedactKeys...)
+}
+
+// AddRedactKeys adds attribute keys whose values are never written. Keys
+// match case-insensitively and ignore "_" and "-", so "refreshToken" also
+// covers "refresh_token".
+func AddRedactKeys(keys ...string) {
+	redactKeys.Lock()
+	defer redactKeys.Unlock()
+	for _, key := range keys {
+		redactKeys.keys[normalizeKey(key)] = true
+	}
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
+
+func isRedactKey(key string) bool {
+	redactKeys.RLock()
+	defer redactKeys.RUnlock()
+	return redactKeys.keys[normalizeKey(key)]
+}
+
+// Redact masks the token and hash shaped parts of s.
+func Redact(s string) string {
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// redactAttr is the slog ReplaceAttr of the logger. It also sees the message,
+// so error strings logged by AppErrorf are masked too.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if safeKeys[a.Key] || a.Key == slog.SourceKey {
+		return a
+	}
+	if isRedactKey(a.Key) {
+		return slog.String(a.Key, RedactedValue)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
@@ -10,6 +10,8 @@ import (
 	"runtime/debug"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var logLevel = &slog.LevelVar{}
@@ -23,8 +25,13 @@ func init() {
 	}
 }
 
-func New() *slog.Logger {
-	logLevel.Set(slog.LevelDebug)
+// New builds the JSON logger (text when ENV=local), logging from level up,
+// and makes it the slog default. An empty level means info. Secrets are
+// masked from every record, see redactAttr.
+func New(level string) (*slog.Logger, error) {
+	if err := SetLevel(level); err != nil {
+		return nil, err
+	}
 
 	replace := func(groups []string, a slog.Attr) slog.Attr {
 		if a.Key == slog.SourceKey {
@@ -32,11 +39,13 @@ func New() *slog.Logger {
 			source.File = filepath.Base(source.File)
 			source.Function = filepath.Base(source.Function)
 		}
-		return a
+		return redactAttr(groups, a)
 	}
 
+	// The handler itself lets every level through; levelHandler filters by
+	// logLevel so that WithDebug can lift the level of a single request.
 	opts := &slog.HandlerOptions{
-		Level:       logLevel,
+		Level:       slog.LevelDebug,
 		AddSource:   true,
 		ReplaceAttr: replace,
 	}
@@ -46,15 +55,17 @@ func New() *slog.Logger {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
 
-	logger = slog.New(handler)
+	logger = slog.New(NewLevelHandler(handler))
 
 	slog.SetDefault(logger)
 
-	return logger
+	return logger, nil
 }
 
-func Debugf(format string, args ...any) {
-	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+// Debugf logs a debug record with ctx, so it is written for a request made
+// by WithDebug even when the logger's level is above debug.
+func Debugf(ctx context.Context, format string, args ...any) {
+	if !logger.Enabled(ctx, slog.LevelDebug) {
 		return
 	}
 	var pcs [1]uintptr
@@ -64,7 +75,7 @@ func Debugf(format string, args ...any) {
 		slog.Int("pid", pid),
 		slog.String("go_version", goversion),
 	}...)
-	_ = logger.Handler().Handle(context.Background(), r)
+	_ = logger.Handler().Handle(ctx, r)
 }
 
 func Warnf(format string, args ...any) {
@@ -97,18 +108,38 @@ func Errorf(format string, args ...any) {
 	_ = logger.Handler().Handle(context.Background(), r)
 }
 
-func AppErrorf(handler slog.Handler, format string, args ...any) {
-	if !handler.Enabled(context.Background(), slog.LevelError) {
+// AppErrorf logs an error of a request. When ctx carries a span, the record
+// gets its trace_id and span_id so the log can be found from the trace.
+func AppErrorf(ctx context.Context, handler slog.Handler, format string, args ...any) {
+	appLog(ctx, handler, slog.LevelError, format, args...)
+}
+
+// AppDebugf logs a debug record of a request like AppErrorf. It is written
+// when the handler is at debug level or ctx was made by WithDebug.
+func AppDebugf(ctx context.Context, handler slog.Handler, format string, args ...any) {
+	appLog(ctx, handler, slog.LevelDebug, format, args...)
+}
+
+// appLog is called by AppErrorf and AppDebugf from the request helpers of
+// package app, so the source of the record is the caller of those helpers.
+func appLog(ctx context.Context, handler slog.Handler, level slog.Level, format string, args ...any) {
+	if !handler.Enabled(ctx, level) {
 		return
 	}
 
 	var pcs [1]uintptr
-	runtime.Callers(3, pcs[:])
-	r := slog.NewRecord(time.Now(), slog.LevelError, fmt.Sprintf(format, args...), pcs[0])
+	runtime.Callers(4, pcs[:])
+	r := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
 	r.AddAttrs([]slog.Attr{
 		slog.Int("pid", pid),
 		slog.String("go_version", goversion),
 	}...)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
 
-	_ = handler.Handle(context.Background(), r)
+	_ = handler.Handle(ctx, r)
 }
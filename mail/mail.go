@@ -0,0 +1,156 @@
+// Package mail delivers the mail the service sends, such as password reset
+// links, through the driver chosen by app.Mail.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-restapi/app"
+	"log/slog"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// asyncTimeout bounds a mail sent in the background by Async.
+const asyncTimeout = time.Minute
+
+var (
+	ErrInvalidConfig = errors.New("invalid mail config")
+	ErrInvalidHeader = errors.New("mail header contains a line break")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LocalEnv is the env, set by the env config key, of a developer machine. It
+// is the only one where the log driver runs.
+const LocalEnv = "local"
+
+// New returns the Mailer of conf.Driver. The log driver writes to logger,
+// reset tokens included, so it is the default and allowed only when env is
+// LocalEnv; other envs must pick a driver.
+func New(conf app.Mail, env string, logger *slog.Logger) (Mailer, error) {
+	switch conf.Driver {
+	case "", DriverLog:
+		if env != LocalEnv {
+			return nil, fmt.Errorf("%w: the log driver writes reset tokens to the log, set mail.driver to file or smtp outside env %s", ErrInvalidConfig, LocalEnv)
+		}
+		return NewLogMailer(logger), nil
+	case DriverFile:
+		if conf.File == "" {
+			return nil, fmt.Errorf("%w: the file driver needs a file", ErrInvalidConfig)
+		}
+		return NewFileMailer(conf.File, conf.From), nil
+	case DriverSMTP:
+		if conf.SMTP.Host == "" {
+			return nil, fmt.Errorf("%w: the smtp driver needs a host", ErrInvalidConfig)
+		}
+		return NewSMTPMailer(conf.SMTP, conf.From), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown driver %q", ErrInvalidConfig, conf.Driver)
+	}
+}
+
+type logMailer struct {
+	logger *slog.Logger
+}
+
+// NewLogMailer writes every mail to logger instead of sending it, for local
+// development.
+func NewLogMailer(logger *slog.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+type fileMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+// NewFileMailer appends every mail to the file at path, for local
+// development.
+func NewFileMailer(path, from string) Mailer {
+	return &fileMailer{path: path, from: from}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, "\r\n"...))
+	return errors.Join(err, f.Close())
+}
+
+type asyncMailer struct {
+	mailer Mailer
+	logger *slog.Logger
+}
+
+// Async returns a Mailer sending through m in the background. Send returns
+// at once, so callers answer as fast whether or not a mail goes out;
+// failures are logged to logger.
+func Async(m Mailer, logger *slog.Logger) Mailer {
+	return &asyncMailer{mailer: m, logger: logger}
+}
+
+func (m *asyncMailer) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncTimeout)
+	go func() {
+		defer cancel()
+		if err := m.mailer.Send(ctx, msg); err != nil {
+			m.logger.ErrorContext(ctx, "send mail: "+err.Error(), "subject", msg.Subject)
+		}
+	}()
+	return nil
+}
+
+// format writes msg as a plain text RFC 5322 message.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
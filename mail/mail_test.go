@@ -0,0 +1,161 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-restapi/app"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	testCases := []struct {
+		name    string
+		conf    app.Mail
+		env     string
+		want    Mailer
+		wantErr bool
+	}{
+		{name: "Should default to the log driver locally", conf: app.Mail{}, env: LocalEnv, want: &logMailer{}},
+		{name: "Should have no default driver outside local", conf: app.Mail{}, env: "production", wantErr: true},
+		{name: "Should refuse the log driver outside local", conf: app.Mail{Driver: DriverLog}, env: "production", wantErr: true},
+		{name: "Should return the file driver", conf: app.Mail{Driver: DriverFile, File: "mail.txt"}, want: &fileMailer{}},
+		{name: "Should return the smtp driver", conf: app.Mail{Driver: DriverSMTP, SMTP: app.SMTP{Host: "localhost"}}, want: &smtpMailer{}},
+		{name: "Should require a file", conf: app.Mail{Driver: DriverFile}, wantErr: true},
+		{name: "Should require a host", conf: app.Mail{Driver: DriverSMTP}, wantErr: true},
+		{name: "Should reject an unknown driver", conf: app.Mail{Driver: "pigeon"}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(tc.conf, tc.env, logger)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, tc.want, m)
+		})
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello", Body: "Hi"})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "to=a@example.com")
+	assert.Contains(t, buf.String(), "subject=Hello")
+	assert.Contains(t, buf.String(), "body=Hi")
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m := NewFileMailer(path, "noreply@example.com")
+
+	assert.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "First", Body: "line 1\nline 2"}))
+	assert.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "Second", Body: "Hi"}))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "From: noreply@example.com\r\nTo: a@example.com\r\nSubject: First\r\n")
+	assert.Contains(t, string(data), "\r\n\r\nline 1\r\nline 2\r\n")
+	assert.Contains(t, string(data), "To: b@example.com\r\n")
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Should write headers and body", func(t *testing.T) {
+		data, err := format("noreply@example.com", Message{To: "a@example.com", Subject: "Réinitialiser", Body: "Hi"}, date)
+		assert.NoError(t, err)
+		assert.Equal(t, "From: noreply@example.com\r\n"+
+			"To: a@example.com\r\n"+
+			"Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n"+
+			"Date: Sun, 01 Oct 2023 00:00:00 +0000\r\n"+
+			"MIME-Version: 1.0\r\n"+
+			"Content-Type: text/plain; charset=utf-8\r\n"+
+			"\r\n"+
+			"Hi", string(data))
+	})
+
+	t.Run("Should reject line breaks in headers", func(t *testing.T) {
+		_, err := format("noreply@example.com", Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "Hi"}, date)
+		assert.ErrorIs(t, err, ErrInvalidHeader)
+
+		_, err = format("noreply@example.com", Message{To: "a@example.com", Subject: "Hi\nBcc: b@example.com"}, date)
+		assert.ErrorIs(t, err, ErrInvalidHeader)
+	})
+}
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+	done chan struct{}
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg Message) error {
+	defer close(m.done)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return m.err
+}
+
+func TestAsync(t *testing.T) {
+	t.Run("Should send in the background", func(t *testing.T) {
+		fake := &fakeMailer{done: make(chan struct{})}
+		m := Async(fake, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+
+		// The request context is canceled once the response is written.
+		ctx, cancel := context.WithCancel(context.Background())
+		assert.NoError(t, m.Send(ctx, Message{To: "a@example.com"}))
+		cancel()
+
+		<-fake.done
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		assert.Equal(t, []Message{{To: "a@example.com"}}, fake.sent)
+	})
+
+	t.Run("Should log failures", func(t *testing.T) {
+		var buf syncBuffer
+		fake := &fakeMailer{done: make(chan struct{}), err: errors.New("connection refused")}
+		m := Async(fake, slog.New(slog.NewTextHandler(&buf, nil)))
+
+		assert.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello"}))
+
+		<-fake.done
+		assert.Eventually(t, func() bool {
+			return strings.Contains(buf.String(), "send mail: connection refused")
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"go-restapi/app"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// DefaultSMTPPort is the submission port, used when none is configured.
+const DefaultSMTPPort = "587"
+
+type smtpMailer struct {
+	conf app.SMTP
+	from string
+}
+
+// NewSMTPMailer sends mail through the SMTP server of conf, upgrading to TLS
+// with STARTTLS when the server offers it.
+func NewSMTPMailer(conf app.SMTP, from string) Mailer {
+	if conf.Port == "" {
+		conf.Port = DefaultSMTPPort
+	}
+	return &smtpMailer{conf: conf, from: from}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.conf.Host, m.conf.Port))
+	if err != nil {
+		return err
+	}
+	// net/smtp does not take a context, so bound the whole exchange by it.
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Join(err, conn.Close())
+		}
+	}
+
+	c, err := smtp.NewClient(conn, m.conf.Host)
+	if err != nil {
+		return errors.Join(err, conn.Close())
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.conf.Host}); err != nil {
+			return err
+		}
+	}
+	if m.conf.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.conf.Username, m.conf.Password, m.conf.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return errors.Join(err, w.Close())
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
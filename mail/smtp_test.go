@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"go-restapi/app"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer accepts one session and records what the client sent.
+type fakeSMTPServer struct {
+	addr     string
+	commands chan []string
+	data     chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSMTPServer{addr: ln.Addr().String(), commands: make(chan []string, 1), data: make(chan string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(textproto.NewConn(conn))
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(c *textproto.Conn) {
+	var commands []string
+	defer func() { s.commands <- commands }()
+
+	c.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		commands = append(commands, line)
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO":
+			c.PrintfLine("250-localhost")
+			c.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			c.PrintfLine("235 2.7.0 Authentication successful")
+		case "DATA":
+			c.PrintfLine("354 Go ahead")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data <- string(data)
+			c.PrintfLine("250 2.0.0 OK")
+		case "QUIT":
+			c.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			c.PrintfLine("250 2.0.0 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	t.Run("Should send the message", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		host, port, _ := net.SplitHostPort(server.addr)
+		m := NewSMTPMailer(app.SMTP{Host: host, Port: port, Username: "user", Password: "secret"}, "noreply@example.com")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := m.Send(ctx, Message{To: "a@example.com", Subject: "Hello", Body: "Hi"})
+		assert.NoError(t, err)
+
+		commands := <-server.commands
+		auth := base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))
+		assert.Equal(t, []string{
+			"EHLO localhost",
+			"AUTH PLAIN " + auth,
+			"MAIL FROM:<noreply@example.com>",
+			"RCPT TO:<a@example.com>",
+			"DATA",
+			"QUIT",
+		}, commands)
+
+		// ReadDotBytes turns the CRLF line endings into LF.
+		data := <-server.data
+		assert.Contains(t, data, "To: a@example.com\n")
+		assert.Contains(t, data, "Subject: Hello\n")
+		assert.True(t, strings.HasSuffix(data, "\n\nHi\n"), data)
+	})
+
+	t.Run("Should send without logging in", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		host, port, _ := net.SplitHostPort(server.addr)
+		m := NewSMTPMailer(app.SMTP{Host: host, Port: port}, "noreply@example.com")
+
+		err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello", Body: "Hi"})
+		assert.NoError(t, err)
+		assert.NotContains(t, strings.Join(<-server.commands, "\n"), "AUTH")
+	})
+
+	t.Run("Should return dial errors", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, port, _ := net.SplitHostPort(ln.Addr().String())
+		ln.Close()
+
+		m := NewSMTPMailer(app.SMTP{Host: host, Port: port}, "noreply@example.com")
+		assert.Error(t, m.Send(context.Background(), Message{To: "a@example.com"}))
+	})
+
+	t.Run("Should default to the submission port", func(t *testing.T) {
+		m := NewSMTPMailer(app.SMTP{Host: "localhost"}, "noreply@example.com")
+		assert.Equal(t, DefaultSMTPPort, m.(*smtpMailer).conf.Port)
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,10 +11,19 @@ import (
 	"time"
 
 	"go-restapi/app"
+	"go-restapi/app/auth"
+	"go-restapi/app/health"
+	"go-restapi/app/keys"
+	"go-restapi/app/role"
 	"go-restapi/config"
 	"go-restapi/database"
 	"go-restapi/logger"
+	"go-restapi/mail"
+	"go-restapi/metrics"
 	"go-restapi/router"
+	"go-restapi/tracing"
+
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -22,19 +32,110 @@ func main() {
 		panic(err)
 	}
 
-	db, err := database.NewMysqlDB(conf)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(conf, os.Args[0], os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger.AddRedactKeys(conf.Log.RedactKeys...)
+	logger, err := logger.New(conf.Log.Level)
+	if err != nil {
+		panic(err)
+	}
+
+	conf.Auth, err = auth.ValidateConfig(conf.Auth)
+	if err != nil {
+		panic(err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing)
+	if err != nil {
+		panic(err)
+	}
+
+	var db *gorm.DB
+	storages := router.NewMemoryStorages()
+	if database.Driver(conf) != database.DriverMemory {
+		db, err = database.NewDB(conf)
+		if err != nil {
+			panic(err)
+		}
+		storages = router.NewGormStorages(db)
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic(err)
+		}
+		if err := metrics.RegisterDBStats(sqlDB, database.Driver(conf)); err != nil {
+			panic(err)
+		}
+	}
+
+	if db != nil && conf.Database.AutoMigrate {
+		migrator, err := database.NewDriverMigrator(db, database.Driver(conf))
+		if err != nil {
+			panic(err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			panic(err)
+		}
+	}
+
+	admin := conf.Admin
+	if database.Driver(conf) == database.DriverMemory {
+		var generated bool
+		admin, generated, err = router.MemoryAdmin(conf.Admin, conf.Env)
+		if err != nil {
+			panic(err)
+		}
+		if generated {
+			// Kept out of the logs: shown once on stderr and never again.
+			fmt.Fprintf(os.Stderr, "WARNING: one-time credentials of db.driver %s, not shown again: log in as %q with password %q\n", database.DriverMemory, admin.Username, admin.Password)
+		}
+	}
+	if err := role.EnsureAdmin(context.Background(), storages.User, storages.Role, admin); err != nil {
+		panic(err)
+	}
+
+	checker := health.NewChecker()
+	if db != nil {
+		checker.Add("database", health.DatabaseCheck(db))
+	}
+	keyManager, err := keys.NewManager(conf.Auth.Keys, conf.Auth.Algorithm, conf.Auth.AccessTokenTTL)
+	if err != nil {
+		panic(err)
+	}
+	checker.Add("signingKey", health.SigningKeyCheck(keyManager))
+
+	mailer, err := mail.New(conf.Mail, conf.Env, logger)
 	if err != nil {
 		panic(err)
 	}
+	// Sending in the background keeps /password/forgot as fast for unknown
+	// usernames as for known ones.
+	mailer = mail.Async(mailer, logger)
 
-	logger := logger.New()
-	r := app.NewRouter(logger, conf)
-	r = router.Router(r, db)
+	r, err := app.NewRouter(logger, conf)
+	if err != nil {
+		panic(err)
+	}
+	r = router.Router(r, conf, storages, keyManager, mailer, checker)
+
+	// Every request context derives from baseCtx, so canceling it stops the
+	// queries of requests still running when shutdown gives up waiting.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	go keyManager.Watch(baseCtx, conf.Auth.Keys.ReloadInterval, logger)
+	go auth.PruneLoginAttempts(baseCtx, storages.LoginAttempt, conf.Auth.Lockout, logger)
 
 	srv := http.Server{
 		Addr:              ":" + conf.Server.Port,
 		Handler:           r,
 		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 	}
 
 	idleConnsClosed := make(chan struct{})
@@ -44,6 +145,11 @@ func main() {
 		signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
 		<-sigint
 
+		// Fail readiness first and give load balancers time to notice before
+		// the listener closes.
+		checker.Drain()
+		time.Sleep(conf.Server.ShutdownDelay)
+
 		d := time.Duration(5 * time.Second)
 		fmt.Printf("shutting down int %s ...", d)
 		// We received an interrupt signal, shut down.
@@ -53,9 +159,15 @@ func main() {
 			// Error from closing listeners, or context timeout:
 			logger.Info("HTTP server Shutdown: " + err.Error())
 		}
-		sqlDB, _ := db.DB()
-		if err := sqlDB.Close(); err != nil {
-			logger.Info("Database close: " + err.Error())
+		cancelRequests()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Info("Tracing shutdown: " + err.Error())
+		}
+		if db != nil {
+			sqlDB, _ := db.DB()
+			if err := sqlDB.Close(); err != nil {
+				logger.Info("Database close: " + err.Error())
+			}
 		}
 		close(idleConnsClosed)
 	}()
@@ -0,0 +1,107 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/book"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type bookStorage struct {
+	mu     sync.RWMutex
+	books  map[int64]book.BookModel
+	nextID int64
+}
+
+func NewBookStorage() book.BookStorage {
+	return &bookStorage{books: map[int64]book.BookModel{}}
+}
+
+func (s *bookStorage) GetListBook(ctx context.Context, limit, offset int) ([]book.BookModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	books := make([]book.BookModel, 0, len(s.books))
+	for _, b := range s.books {
+		books = append(books, b)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	start, end := page(len(books), limit, offset)
+	return books[start:end], nil
+}
+
+func (s *bookStorage) CountListBook(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.books)), nil
+}
+
+func (s *bookStorage) GetBookByID(ctx context.Context, id int) (*book.BookModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.books[int64(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &b, nil
+}
+
+func (s *bookStorage) CreateBook(ctx context.Context, req book.BookRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	s.books[s.nextID] = book.BookModel{
+		ID:     s.nextID,
+		Title:  req.Title,
+		Author: req.Author,
+	}
+	return nil
+}
+
+// UpdateBook saves every field of model, inserting it when the ID is new, as
+// gorm's Save does.
+func (s *bookStorage) UpdateBook(ctx context.Context, model book.BookModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if model.ID == 0 {
+		s.nextID++
+		model.ID = s.nextID
+	}
+	if model.ID > s.nextID {
+		s.nextID = model.ID
+	}
+	s.books[model.ID] = model
+	return nil
+}
+
+func (s *bookStorage) DeleteBook(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.books, int64(id))
+	return nil
+}
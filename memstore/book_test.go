@@ -0,0 +1,39 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/book"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestBookStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewBookStorage()
+
+	assert.NoError(t, storage.CreateBook(ctx, book.BookRequest{Title: "first", Author: "a"}))
+	assert.NoError(t, storage.CreateBook(ctx, book.BookRequest{Title: "second", Author: "b"}))
+
+	books, err := storage.GetListBook(ctx, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []book.BookModel{{ID: 1, Title: "first", Author: "a"}, {ID: 2, Title: "second", Author: "b"}}, books)
+
+	books, err = storage.GetListBook(ctx, 10, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, books)
+
+	count, err := storage.CountListBook(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	assert.NoError(t, storage.UpdateBook(ctx, book.BookModel{ID: 1, Title: "new", Author: "a"}))
+	got, err := storage.GetBookByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", got.Title)
+
+	assert.NoError(t, storage.DeleteBook(ctx, 1))
+	_, err = storage.GetBookByID(ctx, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
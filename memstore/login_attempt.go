@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginAttemptStorage struct {
+	mu       sync.RWMutex
+	attempts map[string]auth.LoginAttemptModel
+}
+
+func NewLoginAttemptStorage() auth.LoginAttemptStorage {
+	return &loginAttemptStorage{attempts: map[string]auth.LoginAttemptModel{}}
+}
+
+func (s *loginAttemptStorage) GetLoginAttempt(ctx context.Context, subject string) (*auth.LoginAttemptModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attempt, ok := s.attempts[subject]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &attempt, nil
+}
+
+// UpdateLoginAttempt applies update under the lock of the storage, so
+// concurrent updates of a subject apply one after another.
+func (s *loginAttemptStorage) UpdateLoginAttempt(ctx context.Context, subject string, update func(attempt *auth.LoginAttemptModel)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[subject]
+	if !ok {
+		attempt = auth.LoginAttemptModel{Subject: subject}
+	}
+	update(&attempt)
+	attempt.Subject = subject
+	attempt.UpdatedAt = time.Now()
+	s.attempts[subject] = attempt
+	return nil
+}
+
+func (s *loginAttemptStorage) DeleteLoginAttempt(ctx context.Context, subject string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, subject)
+	return nil
+}
+
+func (s *loginAttemptStorage) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for subject, attempt := range s.attempts {
+		if attempt.LastFailedAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(s.attempts, subject)
+		}
+	}
+	return nil
+}
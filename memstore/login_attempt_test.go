@@ -0,0 +1,86 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLoginAttemptStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLoginAttemptStorage()
+
+	_, err := storage.GetLoginAttempt(ctx, "user:alice")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:alice", func(attempt *auth.LoginAttemptModel) {
+		assert.Equal(t, 0, attempt.Failures)
+		attempt.Failures = 1
+	}))
+	assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:alice", func(attempt *auth.LoginAttemptModel) {
+		attempt.Failures++
+		attempt.LockedUntil = &lockedUntil
+	}))
+
+	got, err := storage.GetLoginAttempt(ctx, "user:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Failures)
+	assert.Equal(t, lockedUntil, *got.LockedUntil)
+
+	assert.NoError(t, storage.DeleteLoginAttempt(ctx, "user:alice"))
+	_, err = storage.GetLoginAttempt(ctx, "user:alice")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestLoginAttemptStorageConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLoginAttemptStorage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:alice", func(attempt *auth.LoginAttemptModel) {
+				attempt.Failures++
+			}))
+		}()
+	}
+	wg.Wait()
+
+	got, err := storage.GetLoginAttempt(ctx, "user:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, got.Failures)
+}
+
+func TestLoginAttemptStorageDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLoginAttemptStorage()
+	now := time.Now()
+	lockedUntil := now.Add(time.Minute)
+
+	assert.NoError(t, storage.UpdateLoginAttempt(ctx, "ip:old", func(attempt *auth.LoginAttemptModel) {
+		attempt.LastFailedAt = now.Add(-time.Hour)
+	}))
+	assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:locked", func(attempt *auth.LoginAttemptModel) {
+		attempt.LastFailedAt = now.Add(-time.Hour)
+		attempt.LockedUntil = &lockedUntil
+	}))
+	assert.NoError(t, storage.UpdateLoginAttempt(ctx, "user:recent", func(attempt *auth.LoginAttemptModel) {
+		attempt.LastFailedAt = now
+	}))
+
+	assert.NoError(t, storage.DeleteExpiredLoginAttempts(ctx, now.Add(-time.Minute)))
+	_, err := storage.GetLoginAttempt(ctx, "ip:old")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = storage.GetLoginAttempt(ctx, "user:locked")
+	assert.NoError(t, err)
+	_, err = storage.GetLoginAttempt(ctx, "user:recent")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,27 @@
+// Package memstore holds in-memory implementations of the storage interfaces.
+// They follow the SQL storages closely enough to run the whole API without a
+// database: unique keys fail with gorm.ErrDuplicatedKey, missing rows with
+// gorm.ErrRecordNotFound, and a canceled context fails every call.
+//
+// Data lives only as long as the process; rows are not cascaded between
+// stores the way the SQL foreign keys do.
+package memstore
+
+// page returns the [offset, offset+limit) window of n rows, like SQL LIMIT
+// and OFFSET. A limit below one returns no rows.
+func page(n, limit, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	end := n
+	if offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
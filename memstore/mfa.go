@@ -0,0 +1,185 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type mfaStorage struct {
+	mu         sync.RWMutex
+	mfa        map[int]auth.MFAModel
+	codes      map[int][]auth.RecoveryCodeModel
+	challenges map[string]auth.MFAChallengeModel
+	nextID     int
+}
+
+func NewMFAStorage() auth.MFAStorage {
+	return &mfaStorage{
+		mfa:        map[int]auth.MFAModel{},
+		codes:      map[int][]auth.RecoveryCodeModel{},
+		challenges: map[string]auth.MFAChallengeModel{},
+	}
+}
+
+func (s *mfaStorage) GetMFA(ctx context.Context, userID int) (*auth.MFAModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.mfa[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &m, nil
+}
+
+func (s *mfaStorage) SaveMFA(ctx context.Context, mfa auth.MFAModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	mfa.CreatedAt = now
+	if old, ok := s.mfa[mfa.UserID]; ok {
+		mfa.CreatedAt = old.CreatedAt
+	}
+	mfa.UpdatedAt = now
+	s.mfa[mfa.UserID] = mfa
+	return nil
+}
+
+// EnableMFA enables the pending secret of the user and replaces the recovery
+// codes. It fails with gorm.ErrRecordNotFound when there is no pending secret.
+func (s *mfaStorage) EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.mfa[userID]
+	if !ok || m.EnabledAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	m.EnabledAt = &now
+	m.LastUsedStep = step
+	m.UpdatedAt = now
+	s.mfa[userID] = m
+
+	codes := make([]auth.RecoveryCodeModel, len(codeHashes))
+	for i, hash := range codeHashes {
+		s.nextID++
+		codes[i] = auth.RecoveryCodeModel{ID: s.nextID, UserID: userID, CodeHash: hash, CreatedAt: now}
+	}
+	s.codes[userID] = codes
+	return nil
+}
+
+// UseMFAStep records step as used. It fails with gorm.ErrRecordNotFound when
+// MFA is not enabled or step, or a later one, was already used.
+func (s *mfaStorage) UseMFAStep(ctx context.Context, userID int, step int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.mfa[userID]
+	if !ok || m.EnabledAt == nil || m.LastUsedStep >= step {
+		return gorm.ErrRecordNotFound
+	}
+	m.LastUsedStep = step
+	m.UpdatedAt = time.Now()
+	s.mfa[userID] = m
+	return nil
+}
+
+func (s *mfaStorage) DeleteMFA(ctx context.Context, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.mfa, userID)
+	delete(s.codes, userID)
+	return nil
+}
+
+// UseRecoveryCode marks the code used. It fails with gorm.ErrRecordNotFound
+// when the code is missing or already used.
+func (s *mfaStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.codes[userID] {
+		if c.CodeHash == codeHash && c.UsedAt == nil {
+			now := time.Now()
+			s.codes[userID][i].UsedAt = &now
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *mfaStorage) CreateMFAChallenge(ctx context.Context, challenge auth.MFAChallengeModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.challenges[challenge.TokenHash]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	s.nextID++
+	challenge.ID = s.nextID
+	challenge.CreatedAt = time.Now()
+	s.challenges[challenge.TokenHash] = challenge
+	return nil
+}
+
+func (s *mfaStorage) GetMFAChallengeByHash(ctx context.Context, tokenHash string) (*auth.MFAChallengeModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.challenges[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &c, nil
+}
+
+// UseMFAChallenge marks the challenge used. It fails with
+// gorm.ErrRecordNotFound when the challenge is missing or already used.
+func (s *mfaStorage) UseMFAChallenge(ctx context.Context, tokenHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.challenges[tokenHash]
+	if !ok || c.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	s.challenges[tokenHash] = c
+	return nil
+}
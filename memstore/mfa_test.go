@@ -0,0 +1,70 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMFAStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should enable a pending secret once", func(t *testing.T) {
+		storage := NewMFAStorage()
+		assert.ErrorIs(t, storage.EnableMFA(ctx, 1, 10, nil), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.SaveMFA(ctx, auth.MFAModel{UserID: 1, Secret: "secret"}))
+		assert.NoError(t, storage.EnableMFA(ctx, 1, 10, []string{"a", "b"}))
+		assert.ErrorIs(t, storage.EnableMFA(ctx, 1, 11, nil), gorm.ErrRecordNotFound)
+
+		got, err := storage.GetMFA(ctx, 1)
+		assert.NoError(t, err)
+		assert.NotNil(t, got.EnabledAt)
+		assert.Equal(t, int64(10), got.LastUsedStep)
+	})
+
+	t.Run("Should use a step only once", func(t *testing.T) {
+		storage := NewMFAStorage()
+		assert.NoError(t, storage.SaveMFA(ctx, auth.MFAModel{UserID: 1, Secret: "secret"}))
+		assert.ErrorIs(t, storage.UseMFAStep(ctx, 1, 11), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.EnableMFA(ctx, 1, 10, nil))
+		assert.ErrorIs(t, storage.UseMFAStep(ctx, 1, 10), gorm.ErrRecordNotFound)
+		assert.NoError(t, storage.UseMFAStep(ctx, 1, 11))
+		assert.ErrorIs(t, storage.UseMFAStep(ctx, 1, 11), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should use a recovery code only once", func(t *testing.T) {
+		storage := NewMFAStorage()
+		assert.NoError(t, storage.SaveMFA(ctx, auth.MFAModel{UserID: 1, Secret: "secret"}))
+		assert.NoError(t, storage.EnableMFA(ctx, 1, 10, []string{"a", "b"}))
+
+		assert.NoError(t, storage.UseRecoveryCode(ctx, 1, "a"))
+		assert.ErrorIs(t, storage.UseRecoveryCode(ctx, 1, "a"), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, storage.UseRecoveryCode(ctx, 2, "b"), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.DeleteMFA(ctx, 1))
+		assert.ErrorIs(t, storage.UseRecoveryCode(ctx, 1, "b"), gorm.ErrRecordNotFound)
+		_, err := storage.GetMFA(ctx, 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should use a challenge only once", func(t *testing.T) {
+		storage := NewMFAStorage()
+		challenge := auth.MFAChallengeModel{UserID: 1, TokenHash: "a", Purpose: auth.MFAChallengeVerify, ExpiredAt: time.Now().Add(time.Minute)}
+		assert.NoError(t, storage.CreateMFAChallenge(ctx, challenge))
+		assert.ErrorIs(t, storage.CreateMFAChallenge(ctx, challenge), gorm.ErrDuplicatedKey)
+
+		assert.NoError(t, storage.UseMFAChallenge(ctx, "a"))
+		assert.ErrorIs(t, storage.UseMFAChallenge(ctx, "a"), gorm.ErrRecordNotFound)
+
+		got, err := storage.GetMFAChallengeByHash(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, auth.MFAChallengeVerify, got.Purpose)
+		assert.NotNil(t, got.UsedAt)
+	})
+}
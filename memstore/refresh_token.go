@@ -0,0 +1,131 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenStorage struct {
+	mu     sync.RWMutex
+	tokens map[string]auth.RefreshTokenModel
+	nextID int
+}
+
+func NewRefreshTokenStorage() auth.RefreshTokenStorage {
+	return &refreshTokenStorage{tokens: map[string]auth.RefreshTokenModel{}}
+}
+
+func (s *refreshTokenStorage) GetRefreshTokenByToken(ctx context.Context, token string) (*auth.RefreshTokenModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rt, ok := s.tokens[token]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rt, nil
+}
+
+func (s *refreshTokenStorage) CreateRefreshToken(ctx context.Context, refreshToken auth.RefreshTokenModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tokens[refreshToken.Token]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	now := time.Now()
+	s.nextID++
+	refreshToken.ID = s.nextID
+	refreshToken.CreatedAt = now
+	refreshToken.UpdatedAt = now
+	if refreshToken.CreatedBy == "" {
+		refreshToken.CreatedBy = "SYSTEM"
+	}
+	s.tokens[refreshToken.Token] = refreshToken
+	return nil
+}
+
+// RotateRefreshToken replaces oldToken with the non-zero fields of
+// refreshToken, like gorm's Updates with a struct. It fails with
+// gorm.ErrRecordNotFound when oldToken is missing or already revoked.
+func (s *refreshTokenStorage) RotateRefreshToken(ctx context.Context, oldToken string, refreshToken auth.RefreshTokenModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.tokens[oldToken]
+	if !ok || rt.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	if refreshToken.Token != "" && refreshToken.Token != oldToken {
+		if _, ok := s.tokens[refreshToken.Token]; ok {
+			return gorm.ErrDuplicatedKey
+		}
+		rt.Token = refreshToken.Token
+	}
+	if refreshToken.UserID != 0 {
+		rt.UserID = refreshToken.UserID
+	}
+	if !refreshToken.ExpiredAt.IsZero() {
+		rt.ExpiredAt = refreshToken.ExpiredAt
+	}
+	if refreshToken.RevokedAt != nil {
+		rt.RevokedAt = refreshToken.RevokedAt
+	}
+	if refreshToken.UpdatedBy != "" {
+		rt.UpdatedBy = refreshToken.UpdatedBy
+	}
+	rt.UpdatedAt = time.Now()
+
+	delete(s.tokens, oldToken)
+	s.tokens[rt.Token] = rt
+	return nil
+}
+
+func (s *refreshTokenStorage) RevokeRefreshToken(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rt, ok := s.tokens[token]; ok && rt.RevokedAt == nil {
+		s.revoke(rt)
+	}
+	return nil
+}
+
+func (s *refreshTokenStorage) RevokeRefreshTokensByUserID(ctx context.Context, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rt := range s.tokens {
+		if rt.UserID == userID && rt.RevokedAt == nil {
+			s.revoke(rt)
+		}
+	}
+	return nil
+}
+
+// revoke marks rt as revoked now. The caller must hold s.mu.
+func (s *refreshTokenStorage) revoke(rt auth.RefreshTokenModel) {
+	now := time.Now()
+	rt.RevokedAt = &now
+	rt.UpdatedAt = now
+	s.tokens[rt.Token] = rt
+}
@@ -0,0 +1,64 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRefreshTokenStorage(t *testing.T) {
+	ctx := context.Background()
+	expiredAt := time.Now().Add(time.Hour)
+
+	t.Run("Should create and reject duplicate token", func(t *testing.T) {
+		storage := NewRefreshTokenStorage()
+		assert.NoError(t, storage.CreateRefreshToken(ctx, auth.RefreshTokenModel{UserID: 1, Token: "a", ExpiredAt: expiredAt}))
+		assert.ErrorIs(t, storage.CreateRefreshToken(ctx, auth.RefreshTokenModel{UserID: 2, Token: "a"}), gorm.ErrDuplicatedKey)
+
+		got, err := storage.GetRefreshTokenByToken(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.UserID)
+		assert.Equal(t, "SYSTEM", got.CreatedBy)
+
+		_, err = storage.GetRefreshTokenByToken(ctx, "b")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should rotate a token only once", func(t *testing.T) {
+		storage := NewRefreshTokenStorage()
+		assert.NoError(t, storage.CreateRefreshToken(ctx, auth.RefreshTokenModel{UserID: 1, Token: "old", ExpiredAt: expiredAt}))
+
+		assert.NoError(t, storage.RotateRefreshToken(ctx, "old", auth.RefreshTokenModel{Token: "new", UpdatedBy: "u"}))
+		assert.ErrorIs(t, storage.RotateRefreshToken(ctx, "old", auth.RefreshTokenModel{Token: "newer"}), gorm.ErrRecordNotFound)
+
+		got, err := storage.GetRefreshTokenByToken(ctx, "new")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.UserID)
+		assert.Equal(t, "u", got.UpdatedBy)
+		_, err = storage.GetRefreshTokenByToken(ctx, "old")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should revoke tokens", func(t *testing.T) {
+		storage := NewRefreshTokenStorage()
+		for _, token := range []string{"a", "b"} {
+			assert.NoError(t, storage.CreateRefreshToken(ctx, auth.RefreshTokenModel{UserID: 1, Token: token, ExpiredAt: expiredAt}))
+		}
+		assert.NoError(t, storage.CreateRefreshToken(ctx, auth.RefreshTokenModel{UserID: 2, Token: "c", ExpiredAt: expiredAt}))
+
+		assert.NoError(t, storage.RevokeRefreshToken(ctx, "a"))
+		got, _ := storage.GetRefreshTokenByToken(ctx, "a")
+		assert.NotNil(t, got.RevokedAt)
+		assert.ErrorIs(t, storage.RotateRefreshToken(ctx, "a", auth.RefreshTokenModel{Token: "d"}), gorm.ErrRecordNotFound)
+
+		assert.NoError(t, storage.RevokeRefreshTokensByUserID(ctx, 1))
+		got, _ = storage.GetRefreshTokenByToken(ctx, "b")
+		assert.NotNil(t, got.RevokedAt)
+		got, _ = storage.GetRefreshTokenByToken(ctx, "c")
+		assert.Nil(t, got.RevokedAt)
+	})
+}
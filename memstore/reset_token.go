@@ -0,0 +1,87 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type resetTokenStorage struct {
+	mu     sync.RWMutex
+	tokens map[string]auth.ResetTokenModel
+	nextID int
+}
+
+func NewResetTokenStorage() auth.ResetTokenStorage {
+	return &resetTokenStorage{tokens: map[string]auth.ResetTokenModel{}}
+}
+
+func (s *resetTokenStorage) CreateResetToken(ctx context.Context, resetToken auth.ResetTokenModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tokens[resetToken.TokenHash]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	s.nextID++
+	resetToken.ID = s.nextID
+	resetToken.CreatedAt = time.Now()
+	s.tokens[resetToken.TokenHash] = resetToken
+	return nil
+}
+
+func (s *resetTokenStorage) GetResetTokenByHash(ctx context.Context, tokenHash string) (*auth.ResetTokenModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rt, ok := s.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rt, nil
+}
+
+// UseResetToken marks the token used. It fails with gorm.ErrRecordNotFound
+// when the token is missing or already used.
+func (s *resetTokenStorage) UseResetToken(ctx context.Context, tokenHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.tokens[tokenHash]
+	if !ok || rt.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	rt.UsedAt = &now
+	s.tokens[tokenHash] = rt
+	return nil
+}
+
+func (s *resetTokenStorage) UseResetTokensByUserID(ctx context.Context, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, rt := range s.tokens {
+		if rt.UserID == userID && rt.UsedAt == nil {
+			rt.UsedAt = &now
+			s.tokens[hash] = rt
+		}
+	}
+	return nil
+}
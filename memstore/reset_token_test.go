@@ -0,0 +1,55 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/auth"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestResetTokenStorage(t *testing.T) {
+	ctx := context.Background()
+	expiredAt := time.Now().Add(time.Hour)
+
+	t.Run("Should create and reject duplicate token", func(t *testing.T) {
+		storage := NewResetTokenStorage()
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 1, TokenHash: "a", ExpiredAt: expiredAt}))
+		assert.ErrorIs(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 2, TokenHash: "a"}), gorm.ErrDuplicatedKey)
+
+		got, err := storage.GetResetTokenByHash(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.UserID)
+		assert.Nil(t, got.UsedAt)
+
+		_, err = storage.GetResetTokenByHash(ctx, "b")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should use a token only once", func(t *testing.T) {
+		storage := NewResetTokenStorage()
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 1, TokenHash: "a", ExpiredAt: expiredAt}))
+
+		assert.NoError(t, storage.UseResetToken(ctx, "a"))
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "a"), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "b"), gorm.ErrRecordNotFound)
+
+		got, _ := storage.GetResetTokenByHash(ctx, "a")
+		assert.NotNil(t, got.UsedAt)
+	})
+
+	t.Run("Should use every token of a user", func(t *testing.T) {
+		storage := NewResetTokenStorage()
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 1, TokenHash: "a", ExpiredAt: expiredAt}))
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 1, TokenHash: "b", ExpiredAt: expiredAt}))
+		assert.NoError(t, storage.CreateResetToken(ctx, auth.ResetTokenModel{UserID: 2, TokenHash: "c", ExpiredAt: expiredAt}))
+
+		assert.NoError(t, storage.UseResetTokensByUserID(ctx, 1))
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "a"), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, storage.UseResetToken(ctx, "b"), gorm.ErrRecordNotFound)
+		assert.NoError(t, storage.UseResetToken(ctx, "c"))
+	})
+
+}
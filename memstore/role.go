@@ -0,0 +1,125 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/role"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type roleStorage struct {
+	mu          sync.RWMutex
+	roles       map[int64]role.RoleModel
+	permissions map[int64][]string
+	userRoles   map[int]map[int64]bool
+}
+
+// NewRoleStorage returns a role store seeded with the same admin and user
+// roles as the SQL migrations.
+func NewRoleStorage() role.RoleStorage {
+	return &roleStorage{
+		roles: map[int64]role.RoleModel{
+			1: {ID: 1, Name: "admin"},
+			2: {ID: 2, Name: "user"},
+		},
+		permissions: map[int64][]string{
+			1: {
+				role.PermissionBooksRead,
+				role.PermissionBooksWrite,
+				role.PermissionBooksDelete,
+				role.PermissionUsersRead,
+				role.PermissionUsersWrite,
+				role.PermissionUsersDelete,
+				role.PermissionRolesRead,
+				role.PermissionRolesWrite,
+				role.PermissionLogsRead,
+				role.PermissionLogsWrite,
+			},
+			2: {role.PermissionBooksRead},
+		},
+		userRoles: map[int]map[int64]bool{},
+	}
+}
+
+func (s *roleStorage) GetRoleByName(ctx context.Context, name string) (*role.RoleModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.roles {
+		if r.Name == name {
+			return &r, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *roleStorage) GetRolesByUserID(ctx context.Context, userID int) ([]role.RoleModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var roles []role.RoleModel
+	for roleID := range s.userRoles[userID] {
+		roles = append(roles, s.roles[roleID])
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (s *roleStorage) GetPermissionsByUserID(ctx context.Context, userID int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var permissions []string
+	for roleID := range s.userRoles[userID] {
+		for _, p := range s.permissions[roleID] {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+// AssignRole gives userID the role, doing nothing when it already has it.
+func (s *roleStorage) AssignRole(ctx context.Context, userID int, roleID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[roleID]; !ok {
+		// The SQL stores reject this with a foreign key error.
+		return gorm.ErrForeignKeyViolated
+	}
+	if s.userRoles[userID] == nil {
+		s.userRoles[userID] = map[int64]bool{}
+	}
+	s.userRoles[userID][roleID] = true
+	return nil
+}
+
+func (s *roleStorage) UnassignRole(ctx context.Context, userID int, roleID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.userRoles[userID], roleID)
+	return nil
+}
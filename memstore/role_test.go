@@ -0,0 +1,41 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/role"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRoleStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewRoleStorage()
+
+	admin, err := storage.GetRoleByName(ctx, "admin")
+	assert.NoError(t, err)
+	userRole, err := storage.GetRoleByName(ctx, "user")
+	assert.NoError(t, err)
+	_, err = storage.GetRoleByName(ctx, "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, storage.AssignRole(ctx, 1, userRole.ID))
+	assert.NoError(t, storage.AssignRole(ctx, 1, userRole.ID))
+	assert.NoError(t, storage.AssignRole(ctx, 1, admin.ID))
+	assert.ErrorIs(t, storage.AssignRole(ctx, 1, 99), gorm.ErrForeignKeyViolated)
+
+	roles, err := storage.GetRolesByUserID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []role.RoleModel{*admin, *userRole}, roles)
+
+	permissions, err := storage.GetPermissionsByUserID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 10)
+	assert.Contains(t, permissions, role.PermissionBooksRead)
+
+	assert.NoError(t, storage.UnassignRole(ctx, 1, admin.ID))
+	permissions, err = storage.GetPermissionsByUserID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{role.PermissionBooksRead}, permissions)
+}
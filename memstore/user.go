@@ -0,0 +1,140 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/user"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type userStorage struct {
+	mu     sync.RWMutex
+	users  map[int64]user.UserModel
+	nextID int64
+}
+
+func NewUserStorage() user.UserStorage {
+	return &userStorage{users: map[int64]user.UserModel{}}
+}
+
+func (s *userStorage) CreateUser(ctx context.Context, model user.UserModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usernameTaken(model.Username, 0) {
+		return gorm.ErrDuplicatedKey
+	}
+	s.nextID++
+	model.ID = s.nextID
+	if model.Status == 0 {
+		// The column defaults to 1, and gorm leaves zero values to the default.
+		model.Status = 1
+	}
+	s.users[model.ID] = model
+	return nil
+}
+
+func (s *userStorage) GetListUser(ctx context.Context, limit, offset int) ([]user.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]user.UserModel, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	start, end := page(len(users), limit, offset)
+	return users[start:end], nil
+}
+
+func (s *userStorage) GetUserByUsername(ctx context.Context, username string) (*user.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *userStorage) GetUserByID(ctx context.Context, id int) (*user.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[int64(id)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+func (s *userStorage) CountListUser(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.users)), nil
+}
+
+// UpdateUser saves every field of model, inserting it when the ID is new, as
+// gorm's Save does.
+func (s *userStorage) UpdateUser(ctx context.Context, model user.UserModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usernameTaken(model.Username, model.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	if model.ID == 0 {
+		s.nextID++
+		model.ID = s.nextID
+	}
+	if model.ID > s.nextID {
+		s.nextID = model.ID
+	}
+	s.users[model.ID] = model
+	return nil
+}
+
+func (s *userStorage) DeleteUser(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, int64(id))
+	return nil
+}
+
+// usernameTaken reports whether a user other than exceptID has username.
+// The caller must hold s.mu.
+func (s *userStorage) usernameTaken(username string, exceptID int64) bool {
+	for _, u := range s.users {
+		if u.Username == username && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package memstore
+
+import (
+	"context"
+	"go-restapi/app/user"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUserStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should create and get users", func(t *testing.T) {
+		storage := NewUserStorage()
+		assert.NoError(t, storage.CreateUser(ctx, user.UserModel{Username: "a", FirstName: "first"}))
+		assert.NoError(t, storage.CreateUser(ctx, user.UserModel{Username: "b"}))
+
+		got, err := storage.GetUserByUsername(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, &user.UserModel{ID: 1, Username: "a", FirstName: "first", Status: 1}, got)
+
+		got, err = storage.GetUserByID(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "b", got.Username)
+
+		count, err := storage.CountListUser(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Should reject duplicate username", func(t *testing.T) {
+		storage := NewUserStorage()
+		assert.NoError(t, storage.CreateUser(ctx, user.UserModel{Username: "a"}))
+		assert.NoError(t, storage.CreateUser(ctx, user.UserModel{Username: "b"}))
+
+		assert.ErrorIs(t, storage.CreateUser(ctx, user.UserModel{Username: "a"}), gorm.ErrDuplicatedKey)
+		assert.ErrorIs(t, storage.UpdateUser(ctx, user.UserModel{ID: 2, Username: "a"}), gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Should return ErrRecordNotFound", func(t *testing.T) {
+		storage := NewUserStorage()
+
+		_, err := storage.GetUserByID(ctx, 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = storage.GetUserByUsername(ctx, "a")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should page, update and delete", func(t *testing.T) {
+		storage := NewUserStorage()
+		for _, name := range []string{"a", "b", "c"} {
+			assert.NoError(t, storage.CreateUser(ctx, user.UserModel{Username: name}))
+		}
+
+		got, err := storage.GetListUser(ctx, 2, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []user.UserModel{{ID: 2, Username: "b", Status: 1}, {ID: 3, Username: "c", Status: 1}}, got)
+
+		assert.NoError(t, storage.UpdateUser(ctx, user.UserModel{ID: 2, Username: "b", Status: 0}))
+		u, _ := storage.GetUserByID(ctx, 2)
+		assert.Equal(t, 0, u.Status)
+
+		assert.NoError(t, storage.DeleteUser(ctx, 2))
+		_, err = storage.GetUserByID(ctx, 2)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Should return context error", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		assert.ErrorIs(t, NewUserStorage().CreateUser(canceled, user.UserModel{Username: "a"}), context.Canceled)
+	})
+
+	t.Run("Should allow one of concurrent duplicate creates", func(t *testing.T) {
+		storage := NewUserStorage()
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- storage.CreateUser(ctx, user.UserModel{Username: "same"})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			if err == nil {
+				created++
+			} else {
+				assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+			}
+		}
+		assert.Equal(t, 1, created)
+	})
+}
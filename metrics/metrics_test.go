@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/books/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	matched := HTTPRequestsTotal.WithLabelValues("/books/:id", http.MethodGet, "200")
+	unmatched := HTTPRequestsTotal.WithLabelValues(UnmatchedRoute, http.MethodGet, "404")
+	matchedBefore, unmatchedBefore := testutil.ToFloat64(matched), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/books/1", "/books/2", "/no/such/path"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, matchedBefore+2, testutil.ToFloat64(matched))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
+
+func TestObserveLogin(t *testing.T) {
+	success := testutil.ToFloat64(LoginTotal.WithLabelValues("success"))
+	failure := testutil.ToFloat64(LoginTotal.WithLabelValues("failure"))
+
+	ObserveLogin(true)
+	ObserveLogin(false)
+	ObserveLogin(false)
+
+	assert.Equal(t, success+1, testutil.ToFloat64(LoginTotal.WithLabelValues("success")))
+	assert.Equal(t, failure+2, testutil.ToFloat64(LoginTotal.WithLabelValues("failure")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveLogin(true)
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "restapi_auth_login_total")
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-restapi/app"
+	"go-restapi/database"
+)
+
+const migrateUsage = `usage: %s migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [-steps N] roll back the last N applied migrations (default 1)
+  status          list migrations and when they were applied
+  baseline [-version V]
+                  record migrations up to V (default all) as applied
+                  without running them, for tables that already exist
+  create <name>   write empty up/down files for a new migration, one set
+                  per driver
+`
+
+var errMigrateUsage = errors.New("invalid migrate command")
+
+// runMigrate runs the `migrate` subcommand with the arguments after "migrate".
+func runMigrate(conf app.Config, program string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintf(out, migrateUsage, program)
+		return errMigrateUsage
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	version := flags.Int64("version", 0, "last migration to record as applied")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if command == "create" {
+		if flags.NArg() == 0 {
+			fmt.Fprintf(out, migrateUsage, program)
+			return errMigrateUsage
+		}
+		now := time.Now()
+		for _, driver := range database.Drivers {
+			paths, err := database.CreateMigration(database.MigrationsDir(driver), strings.Join(flags.Args(), "_"), now)
+			for _, p := range paths {
+				fmt.Fprintf(out, "created %s\n", p)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if database.Driver(conf) == database.DriverMemory {
+		return fmt.Errorf("db.driver %q has no migrations", database.DriverMemory)
+	}
+
+	db, err := database.NewDB(conf)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	migrator, err := database.NewDriverMigrator(db, database.Driver(conf))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		rolledBack, err := migrator.Down(ctx, *steps)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "rolled back %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "baseline":
+		recorded, err := migrator.Baseline(ctx, *version)
+		for _, m := range recorded {
+			fmt.Fprintf(out, "recorded %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(recorded) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(out, "%-20s %d_%s\n", appliedAt, s.Version, s.Name)
+		}
+		return nil
+	default:
+		fmt.Fprintf(out, migrateUsage, program)
+		return errMigrateUsage
+	}
+}
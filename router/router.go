@@ -1,37 +1,147 @@
 package router
 
 import (
+	"errors"
 	"go-restapi/app"
 	"go-restapi/app/auth"
 	"go-restapi/app/book"
+	"go-restapi/app/health"
+	"go-restapi/app/keys"
+	"go-restapi/app/logging"
+	"go-restapi/app/role"
 	"go-restapi/app/user"
+	"go-restapi/mail"
+	"go-restapi/memstore"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func Router(r *app.Router, db *gorm.DB) *app.Router {
-	bookStorege := book.NewBookStorage(db)
-	userStorage := user.NewUserStorage(db)
-	refreshTokenStorage := auth.NewRefreshTokenStorage(db)
+// MemoryAdminUsername is the admin seeded into memory storages when
+// admin.username is not set.
+const MemoryAdminUsername = "admin"
 
-	authHandler := auth.New(userStorage, refreshTokenStorage)
+// Storages are the storage implementations the handlers are built on.
+type Storages struct {
+	Book         book.BookStorage
+	User         user.UserStorage
+	RefreshToken auth.RefreshTokenStorage
+	Role         role.RoleStorage
+	LoginAttempt auth.LoginAttemptStorage
+	ResetToken   auth.ResetTokenStorage
+	MFA          auth.MFAStorage
+}
+
+func NewGormStorages(db *gorm.DB) Storages {
+	return Storages{
+		Book:         book.NewBookStorage(db),
+		User:         user.NewUserStorage(db),
+		RefreshToken: auth.NewRefreshTokenStorage(db),
+		Role:         role.NewRoleStorage(db),
+		LoginAttempt: auth.NewLoginAttemptStorage(db),
+		ResetToken:   auth.NewResetTokenStorage(db),
+		MFA:          auth.NewMFAStorage(db),
+	}
+}
+
+// NewMemoryStorages keeps all data in process memory, for running the API
+// without a database.
+func NewMemoryStorages() Storages {
+	return Storages{
+		Book:         memstore.NewBookStorage(),
+		User:         memstore.NewUserStorage(),
+		RefreshToken: memstore.NewRefreshTokenStorage(),
+		Role:         memstore.NewRoleStorage(),
+		LoginAttempt: memstore.NewLoginAttemptStorage(),
+		ResetToken:   memstore.NewResetTokenStorage(),
+		MFA:          memstore.NewMFAStorage(),
+	}
+}
+
+// ErrMemoryAdminRequired is returned by MemoryAdmin outside env local when
+// admin.username is not set.
+var ErrMemoryAdminRequired = errors.New("db.driver memory: set admin.username and admin.password, a password is only generated in env " + mail.LocalEnv)
+
+// MemoryAdmin returns the admin to seed into memory storages. Their data
+// starts empty on every boot, so without an admin no protected endpoint could
+// be reached; when admin.username is not set it is MemoryAdminUsername with a
+// random password, and generated tells the caller to show that password.
+// Generating is only allowed in env local, elsewhere the admin must be set.
+func MemoryAdmin(conf app.Admin, env string) (admin app.Admin, generated bool, err error) {
+	if conf.Username != "" {
+		return conf, false, nil
+	}
+	if env != mail.LocalEnv {
+		return app.Admin{}, false, ErrMemoryAdminRequired
+	}
+	return app.Admin{Username: MemoryAdminUsername, Password: uuid.NewString()}, true, nil
+}
+
+func Router(r *app.Router, conf app.Config, storages Storages, keyManager *keys.Manager, mailer mail.Mailer, checker *health.Checker) *app.Router {
+	bookStorege := storages.Book
+	userStorage := storages.User
+	refreshTokenStorage := storages.RefreshToken
+	roleStorage := storages.Role
+
+	authHandler := auth.New(userStorage, refreshTokenStorage, roleStorage, storages.LoginAttempt, storages.ResetToken, storages.MFA, keyManager, mailer, conf.Auth)
 	bookHandler := book.New(bookStorege)
-	userHandler := user.New(userStorage)
+	tokenRevoker := auth.NewTokenRevoker(refreshTokenStorage, storages.ResetToken)
+	userHandler := user.New(userStorage, tokenRevoker, role.NewDefaultRoleAssigner(roleStorage), auth.NewPasswordLimiter(storages.LoginAttempt, conf.Auth.Lockout))
+	roleHandler := role.New(roleStorage, userStorage, tokenRevoker, conf.Auth.MFA.RequiredRoles)
+	healthHandler := health.NewHealthHandler(checker)
+	loggingHandler := logging.NewLoggingHandler()
+	keysHandler := keys.NewKeysHandler(keyManager)
+
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+	r.GET("/.well-known/jwks.json", keysHandler.JWKS)
 
 	v1 := r.Group("/api/v1")
 	{
 		v1.POST("/login", authHandler.Login)
-
-		v1.GET("/books", bookHandler.GetAllBook)
-		v1.POST("/books", bookHandler.CreateBook)
+		v1.POST("/logout", authHandler.Logout)
+		v1.POST("/token/refresh", authHandler.RefreshToken)
+		v1.POST("/password/forgot", authHandler.ForgotPassword)
+		v1.POST("/password/reset", authHandler.ResetPassword)
+		v1.POST("/login/mfa", authHandler.VerifyMFA)
+		v1.POST("/login/mfa/enroll", authHandler.EnrollMFAChallenge)
 
 		v1.POST("/users", userHandler.CreateUser)
-		v1.GET("/users", userHandler.GetListUser)
-		v1.GET("/users/:id", userHandler.GetUserByID)
-		v1.PUT("/users/:id", userHandler.UpdateUser)
-		v1.DELETE("/users/:id", userHandler.DeleteUser)
 	}
 
+	authorized := v1.Group("")
+	authorized.Authenticate(auth.NewTokenVerifier(keyManager.Keyfunc, conf.Auth))
+	authorized.AllowDebugLog(role.PermissionLogsWrite)
+	{
+		authorized.GET("/me", userHandler.GetMe)
+		authorized.PATCH("/me", userHandler.PatchMe)
+		authorized.PUT("/me/password", userHandler.ChangePassword)
+		authorized.POST("/me/mfa/enroll", authHandler.EnrollMFA)
+		authorized.POST("/me/mfa/confirm", authHandler.ConfirmMFA)
+		authorized.POST("/me/mfa/disable", authHandler.DisableMFA)
+
+		authorized.Require(role.PermissionBooksRead).GET("/books", bookHandler.GetListBook)
+		authorized.Require(role.PermissionBooksRead).GET("/books/:id", bookHandler.GetBookByID)
+		authorized.Require(role.PermissionBooksWrite).POST("/books", bookHandler.CreateBook)
+		authorized.Require(role.PermissionBooksWrite).PUT("/books/:id", bookHandler.UpdateBook)
+		authorized.Require(role.PermissionBooksWrite).PATCH("/books/:id", bookHandler.PatchBook)
+		authorized.Require(role.PermissionBooksDelete).DELETE("/books/:id", bookHandler.DeleteBook)
+
+		authorized.Require(role.PermissionUsersRead).GET("/users", userHandler.GetListUser)
+		authorized.Require(role.PermissionUsersRead).GET("/users/:id", userHandler.GetUserByID)
+		authorized.Require(role.PermissionUsersWrite).PUT("/users/:id", userHandler.UpdateUser)
+		authorized.Require(role.PermissionUsersDelete).DELETE("/users/:id", userHandler.DeleteUser)
+		authorized.Require(role.PermissionUsersWrite).POST("/users/:id/unlock", authHandler.UnlockUser)
+
+		authorized.Require(role.PermissionRolesRead).GET("/users/:id/roles", roleHandler.GetUserRoles)
+		authorized.Require(role.PermissionRolesWrite).POST("/users/:id/roles", roleHandler.AssignRole)
+		authorized.Require(role.PermissionRolesWrite).DELETE("/users/:id/roles/:role", roleHandler.UnassignRole)
+
+		authorized.Require(role.PermissionLogsRead).GET("/admin/log-level", loggingHandler.GetLogLevel)
+		authorized.Require(role.PermissionLogsWrite).PUT("/admin/log-level", loggingHandler.SetLogLevel)
+	}
+
+	r.Metrics()
 	r.NoRoute()
 	return r
 }
@@ -0,0 +1,219 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"go-restapi/app"
+	"go-restapi/app/health"
+	"go-restapi/app/keys"
+	"go-restapi/app/role"
+	"go-restapi/mail"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyManager(t *testing.T) *keys.Manager {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := keys.NewKey("key-1", rsaKey)
+	assert.NoError(t, err)
+	return keys.NewStaticManager(key)
+}
+
+func newTestRouter(t *testing.T, keyManager *keys.Manager, storages Storages) *app.Router {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	r, err := app.NewRouter(logger, app.Config{})
+	assert.NoError(t, err)
+	return Router(r, app.Config{}, storages, keyManager, mail.NewLogMailer(logger), health.NewChecker())
+}
+
+// serve sends body to path with the bearer token, when set.
+func serve(r http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+// login returns the access token of username.
+func login(t *testing.T, r http.Handler, username, password string) string {
+	rec := serve(r, http.MethodPost, "/api/v1/login", `{"username":"`+username+`","password":"`+password+`"}`, "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var res struct {
+		Data struct {
+			AccessToken string `json:"accessToken"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	return res.Data.AccessToken
+}
+
+// TestRouterWithMemoryStorages boots the whole API on the in-memory storages.
+func TestRouterWithMemoryStorages(t *testing.T) {
+	keyManager := newTestKeyManager(t)
+	jwks, err := json.Marshal(keyManager.JWKS())
+	assert.NoError(t, err)
+
+	r := newTestRouter(t, keyManager, NewMemoryStorages())
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Should create user",
+			method:         http.MethodPost,
+			path:           "/api/v1/users",
+			body:           `{"username":"memory","password":"password","firstname":"first","lastname":"last"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":""}`,
+		},
+		{
+			name:           "Should reject duplicate username",
+			method:         http.MethodPost,
+			path:           "/api/v1/users",
+			body:           `{"username":"memory","password":"password","firstname":"first","lastname":"last"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"ERROR","message":"` + app.BadRequestMsg + `"}`,
+		},
+		{
+			name:           "Should reject wrong password",
+			method:         http.MethodPost,
+			path:           "/api/v1/login",
+			body:           `{"username":"memory","password":"wrong"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + app.UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should report liveness",
+			method:         http.MethodGet,
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"UP","checks":{}}`,
+		},
+		{
+			name:           "Should publish the public keys",
+			method:         http.MethodGet,
+			path:           "/.well-known/jwks.json",
+			expectedStatus: http.StatusOK,
+			expectedBody:   string(jwks),
+		},
+		{
+			name:           "Should require a token for books",
+			method:         http.MethodGet,
+			path:           "/api/v1/books",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + app.UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should answer forgot password for an unknown username",
+			method:         http.MethodPost,
+			path:           "/api/v1/password/forgot",
+			body:           `{"username":"nobody"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"SUCCESS","message":""}`,
+		},
+		{
+			name:           "Should require a token for me",
+			method:         http.MethodGet,
+			path:           "/api/v1/me",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + app.UnauthorizedMsg + `"}`,
+		},
+		{
+			name:           "Should reject an unknown mfa challenge",
+			method:         http.MethodPost,
+			path:           "/api/v1/login/mfa",
+			body:           `{"challengeToken":"unknown","code":"123456"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"status":"ERROR","message":"` + app.UnauthorizedMsg + `"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+// TestRouterRoles checks that the seeded admin and new users can reach the
+// routes of their roles.
+func TestRouterRoles(t *testing.T) {
+	storages := NewMemoryStorages()
+	err := role.EnsureAdmin(context.Background(), storages.User, storages.Role, app.Admin{Username: "root", Password: "password"})
+	assert.NoError(t, err)
+	r := newTestRouter(t, newTestKeyManager(t), storages)
+
+	t.Run("Should let the admin list users", func(t *testing.T) {
+		token := login(t, r, "root", "password")
+		rec := serve(r, http.MethodGet, "/api/v1/users", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+
+	t.Run("Should give new users the default role", func(t *testing.T) {
+		rec := serve(r, http.MethodPost, "/api/v1/users", `{"username":"reader","password":"password","firstname":"first","lastname":"last"}`, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		token := login(t, r, "reader", "password")
+		rec = serve(r, http.MethodGet, "/api/v1/books", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		rec = serve(r, http.MethodGet, "/api/v1/users", "", token)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestRouterMemoryAdmin(t *testing.T) {
+	t.Run("Should keep the configured admin", func(t *testing.T) {
+		admin, generated, err := MemoryAdmin(app.Admin{Username: "root", Password: "password"}, "production")
+		assert.NoError(t, err)
+		assert.False(t, generated)
+		assert.Equal(t, app.Admin{Username: "root", Password: "password"}, admin)
+	})
+
+	t.Run("Should refuse to generate the admin outside env local", func(t *testing.T) {
+		_, _, err := MemoryAdmin(app.Admin{}, "production")
+		assert.ErrorIs(t, err, ErrMemoryAdminRequired)
+	})
+
+	t.Run("Should let the generated admin log in and reach books", func(t *testing.T) {
+		admin, generated, err := MemoryAdmin(app.Admin{}, mail.LocalEnv)
+		assert.NoError(t, err)
+		assert.True(t, generated)
+		assert.Equal(t, MemoryAdminUsername, admin.Username)
+		assert.GreaterOrEqual(t, len(admin.Password), 8)
+
+		storages := NewMemoryStorages()
+		err = role.EnsureAdmin(context.Background(), storages.User, storages.Role, admin)
+		assert.NoError(t, err)
+		r := newTestRouter(t, newTestKeyManager(t), storages)
+
+		token := login(t, r, admin.Username, admin.Password)
+		rec := serve(r, http.MethodGet, "/api/v1/books", "", token)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+}
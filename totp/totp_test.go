@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA1 test vectors of RFC 6238, appendix B.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerate(t *testing.T) {
+	for _, v := range rfcVectors {
+		assert.Equal(t, v.code, generate([]byte("12345678901234567890"), uint64(Step(time.Unix(v.unix, 0))), 8), v.unix)
+	}
+}
+
+func TestCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		code, err := Code(rfcSecret, time.Unix(v.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, v.code[2:], code, v.unix)
+	}
+
+	_, err := Code("not base32!", time.Now())
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, now)
+
+	t.Run("Should accept the current code", func(t *testing.T) {
+		step, ok := Validate(rfcSecret, code, now, 0)
+		assert.True(t, ok)
+		assert.Equal(t, Step(now), step)
+	})
+
+	t.Run("Should accept a code one period off", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, code, now.Add(Period), 0)
+		assert.True(t, ok)
+		_, ok = Validate(rfcSecret, code, now.Add(-Period), 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("Should reject a code two periods off", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, code, now.Add(2*Period), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("Should reject a used step", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, code, now, Step(now))
+		assert.False(t, ok)
+	})
+
+	t.Run("Should reject malformed input", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, "12345", now, 0)
+		assert.False(t, ok)
+		_, ok = Validate("not base32!", code, now, 0)
+		assert.False(t, ok)
+	})
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := NewSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	code, err := Code(secret, time.Now())
+	assert.NoError(t, err)
+	_, ok := Validate(secret, code, time.Now(), 0)
+	assert.True(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("go-restapi", "admin", "JBSWY3DPEHPK3PXP"))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/go-restapi:admin", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "go-restapi", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+}
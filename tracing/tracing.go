@@ -0,0 +1,119 @@
+// Package tracing sets up the OpenTelemetry tracer provider and the W3C
+// traceparent propagation of the service.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restapi/app"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Setup installs the global propagator and, unless the exporter is none, a
+// tracer provider sending spans to the configured exporter. The returned
+// shutdown flushes the spans still buffered.
+func Setup(ctx context.Context, conf app.Tracing) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closeExporter, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	serviceName := conf.ServiceName
+	if serviceName == "" {
+		serviceName = app.TracerName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, errors.Join(err, closeExporter())
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(newSampler(conf.SampleRatio)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeExporter())
+	}, nil
+}
+
+// newExporter returns a nil exporter for none. closeExporter releases what
+// the exporter itself does not, such as the file it writes to.
+func newExporter(ctx context.Context, conf app.Tracing) (exporter sdktrace.SpanExporter, closeExporter func() error, err error) {
+	closeExporter = func() error { return nil }
+	switch conf.Exporter {
+	case "", ExporterNone:
+		return nil, closeExporter, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		return exporter, closeExporter, err
+	case ExporterFile:
+		if conf.File == "" {
+			return nil, nil, errors.New("tracing.file is required by the file exporter")
+		}
+		f, err := os.OpenFile(conf.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return nil, nil, errors.Join(err, f.Close())
+		}
+		return exporter, f.Close, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		return exporter, closeExporter, err
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing.exporter %q", conf.Exporter)
+	}
+}
+
+// newSampler records ratio of the traces started here, every one when ratio
+// is nil, and follows the sampling decision of the caller when the request
+// has a traceparent.
+func newSampler(ratio *float64) sdktrace.Sampler {
+	switch {
+	case ratio == nil || *ratio >= 1:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case *ratio <= 0:
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	default:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*ratio))
+	}
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"go-restapi/app"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("Should write spans to the file exporter", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "traces.json")
+		shutdown, err := Setup(context.Background(), app.Tracing{Exporter: ExporterFile, File: file})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+		span.End()
+		assert.NoError(t, shutdown(context.Background()))
+
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `"Name":"test-span"`)
+		assert.Contains(t, string(content), `"Value":"go-restapi"`)
+	})
+
+	t.Run("Should require a file for the file exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), app.Tracing{Exporter: ExporterFile})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should reject an unknown exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), app.Tracing{Exporter: "zipkin"})
+		assert.EqualError(t, err, `unknown tracing.exporter "zipkin"`)
+	})
+
+	t.Run("Should export nothing by default", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), app.Tracing{})
+		assert.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}
+
+func TestNewSampler(t *testing.T) {
+	ratio := func(r float64) *float64 { return &r }
+	testCases := []struct {
+		name     string
+		ratio    *float64
+		expected sdktrace.SamplingDecision
+	}{
+		{name: "Should record every trace when unset", ratio: nil, expected: sdktrace.RecordAndSample},
+		{name: "Should record every trace at 1", ratio: ratio(1), expected: sdktrace.RecordAndSample},
+		{name: "Should record no trace at 0", ratio: ratio(0), expected: sdktrace.Drop},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := newSampler(tc.ratio).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       trace.TraceID{1},
+				Name:          "test-span",
+			})
+			assert.Equal(t, tc.expected, result.Decision)
+		})
+	}
+}
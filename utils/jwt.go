@@ -1,49 +1,96 @@
 package utils
 
 import (
-	"crypto/rsa"
+	"errors"
+	"fmt"
 	"go-restapi/app"
-	"os"
+	"go-restapi/app/keys"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func (u *utils) GetPrivateKey() (*rsa.PrivateKey, error) {
-	pem, err := os.ReadFile("./config/private.pem")
-	if err != nil {
-		return nil, err
-	}
-	return jwt.ParseRSAPrivateKeyFromPEM(pem)
-}
+var ErrInvalidTokenClaims = errors.New("invalid token claims")
 
-func (u *utils) GetAccessToken(key *rsa.PrivateKey, data app.TokenData, expireHour int) (string, int64, error) {
+// GetAccessToken signs data with key, names the key in the kid header and
+// sets the issuer, audience and lifetime of conf.
+func (u *utils) GetAccessToken(key *keys.Key, data app.TokenData, conf app.Auth) (string, int64, error) {
 	var (
-		t           *jwt.Token
-		expDuration time.Duration
-		exp         time.Time
-		s           string
-		err         error
+		t   *jwt.Token
+		exp time.Time
+		s   string
+		err error
 	)
 
 	now := time.Now()
-	expDuration = time.Hour * time.Duration(expireHour)
-	exp = time.Now().Add(expDuration)
-	t = jwt.NewWithClaims(jwt.SigningMethodRS256,
-		jwt.MapClaims{
-			"iss":       "go-restapi",
-			"sub":       data.UserID,
-			"userID":    data.UserID,
-			"username":  data.Username,
-			"firstname": data.FirstName,
-			"lastname":  data.LastName,
-			"role":      data.Role,
-			"iat":       now.Unix(),
-			"exp":       exp.Unix(),
-		})
-	s, err = t.SignedString(key)
+	exp = now.Add(conf.AccessTokenTTL)
+	claims := jwt.MapClaims{
+		"iss":         conf.Issuer,
+		"sub":         data.UserID,
+		"userID":      data.UserID,
+		"username":    data.Username,
+		"firstname":   data.FirstName,
+		"lastname":    data.LastName,
+		"roles":       data.Roles,
+		"permissions": data.Permissions,
+		"iat":         now.Unix(),
+		"exp":         exp.Unix(),
+	}
+	if conf.Audience != "" {
+		claims["aud"] = conf.Audience
+	}
+	t = jwt.NewWithClaims(key.Method(), claims)
+	t.Header["kid"] = key.ID
+	s, err = t.SignedString(key.Private)
 	if err != nil {
 		return "", 0, err
 	}
 	return s, exp.Unix(), nil
 }
+
+// ParseAccessToken verifies tokenString with the key keyfunc finds for it,
+// such as keys.Manager.Keyfunc, checks the issuer and audience of conf, and
+// returns the data it carries.
+func (u *utils) ParseAccessToken(keyfunc jwt.Keyfunc, conf app.Auth, tokenString string) (*app.TokenData, error) {
+	claims := jwt.MapClaims{}
+	options := []jwt.ParserOption{jwt.WithValidMethods(keys.Algorithms), jwt.WithIssuer(conf.Issuer)}
+	if conf.Audience != "" {
+		options = append(options, jwt.WithAudience(conf.Audience))
+	}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyfunc, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// jwt/v5 only validates exp when present, so require it explicitly.
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, err
+	}
+	if exp == nil {
+		return nil, fmt.Errorf("%w: exp", jwt.ErrTokenRequiredClaimMissing)
+	}
+
+	userID, ok := claims["userID"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: userID", ErrInvalidTokenClaims)
+	}
+	data := &app.TokenData{UserID: int(userID)}
+	data.Username, _ = claims["username"].(string)
+	data.FirstName, _ = claims["firstname"].(string)
+	data.LastName, _ = claims["lastname"].(string)
+	data.Roles = stringSliceClaim(claims, "roles")
+	data.Permissions = stringSliceClaim(claims, "permissions")
+	return data, nil
+}
+
+func stringSliceClaim(claims jwt.MapClaims, key string) []string {
+	values, _ := claims[key].([]any)
+	var result []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
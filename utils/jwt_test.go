@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"go-restapi/app"
+	"go-restapi/app/keys"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccessToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := keys.NewKey("key-1", rsaKey)
+	assert.NoError(t, err)
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherKey, err := keys.NewKey("key-1", otherRSAKey)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	esKey, err := keys.NewKey("key-2", ecKey)
+	assert.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	edKey, err := keys.NewKey("key-ed", edPriv)
+	assert.NoError(t, err)
+	manager := keys.NewStaticManager(key, esKey, edKey)
+	conf := app.Auth{AccessTokenTTL: time.Hour, Issuer: "go-restapi"}
+
+	u := NewUtils()
+	data := app.TokenData{UserID: 13, Username: "admin", FirstName: "first", LastName: "last", Roles: []string{"admin"}, Permissions: []string{"books:read", "books:write"}}
+
+	t.Run("Should return token data", func(t *testing.T) {
+		token, _, err := u.GetAccessToken(key, data, conf)
+		assert.NoError(t, err)
+
+		got, err := u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.NoError(t, err)
+		assert.Equal(t, &data, got)
+	})
+
+	t.Run("Should verify with the key named by kid", func(t *testing.T) {
+		token, _, err := u.GetAccessToken(esKey, data, conf)
+		assert.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, "key-2", parsed.Header["kid"])
+		assert.Equal(t, "ES256", parsed.Header["alg"])
+
+		got, err := u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.NoError(t, err)
+		assert.Equal(t, &data, got)
+	})
+
+	t.Run("Should sign with EdDSA", func(t *testing.T) {
+		token, _, err := u.GetAccessToken(edKey, data, conf)
+		assert.NoError(t, err)
+
+		got, err := u.ParseAccessToken(func(*jwt.Token) (any, error) { return edPub, nil }, conf, token)
+		assert.NoError(t, err)
+		assert.Equal(t, &data, got)
+	})
+
+	t.Run("Should set the lifetime and audience", func(t *testing.T) {
+		withAudience := app.Auth{AccessTokenTTL: 90 * time.Second, Issuer: "issuer", Audience: "books-api"}
+		token, exp, err := u.GetAccessToken(key, data, withAudience)
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(90*time.Second).Unix(), exp, 1)
+
+		_, err = u.ParseAccessToken(manager.Keyfunc, withAudience, token)
+		assert.NoError(t, err)
+
+		withAudience.Audience = "other-api"
+		_, err = u.ParseAccessToken(manager.Keyfunc, withAudience, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	})
+
+	t.Run("Should return error when kid is unknown", func(t *testing.T) {
+		unknown, err := keys.NewKey("key-3", otherRSAKey)
+		assert.NoError(t, err)
+		token, _, err := u.GetAccessToken(unknown, data, conf)
+		assert.NoError(t, err)
+
+		_, err = u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, keys.ErrUnknownKey)
+	})
+
+	t.Run("Should return error when signed by another key", func(t *testing.T) {
+		token, _, err := u.GetAccessToken(otherKey, data, conf)
+		assert.NoError(t, err)
+
+		_, err = u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+
+	signClaims := func(claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(rsaKey)
+		assert.NoError(t, err)
+		return s
+	}
+
+	t.Run("Should return error when token expired", func(t *testing.T) {
+		token := signClaims(jwt.MapClaims{"iss": conf.Issuer, "userID": 1, "exp": time.Now().Add(-time.Minute).Unix()})
+		_, err := u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("Should return error when exp is missing", func(t *testing.T) {
+		token := signClaims(jwt.MapClaims{"iss": conf.Issuer, "userID": 1})
+		_, err := u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+	})
+
+	t.Run("Should return error when issuer is wrong", func(t *testing.T) {
+		token := signClaims(jwt.MapClaims{"iss": "someone-else", "userID": 1, "exp": time.Now().Add(time.Minute).Unix()})
+		_, err := u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+	})
+
+	t.Run("Should return error when signing method is not allowed", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": conf.Issuer, "userID": 1, "exp": time.Now().Add(time.Minute).Unix()}).SignedString([]byte("secret"))
+		assert.NoError(t, err)
+		_, err = u.ParseAccessToken(manager.Keyfunc, conf, token)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+}
@@ -1,10 +1,26 @@
 package utils
 
 import (
+	"fmt"
 	"go-restapi/app"
+	"math"
+	"net/http"
 	"strconv"
 )
 
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	// MaxPage keeps page*pageSize, and so the offset of the query, within
+	// an int32 for every valid pageSize.
+	MaxPage = math.MaxInt32 / MaxPageSize
+)
+
+var ErrInvalidPage = app.NewCodedError("INVALID_PAGE", http.StatusBadRequest, fmt.Sprintf("page must be a number up to %d", MaxPage))
+var ErrInvalidPageSize = app.NewCodedError("INVALID_PAGE_SIZE", http.StatusBadRequest, fmt.Sprintf("pageSize must be a number from 1 to %d", MaxPageSize))
+
+// GetPage returns the page query parameter, 1 when it is missing or below 1.
+// Values above MaxPage fail with ErrInvalidPage.
 func (u *utils) GetPage(ctx app.Context) (int, error) {
 	p := ctx.GetQuery("page")
 	if p == "" {
@@ -12,8 +28,8 @@ func (u *utils) GetPage(ctx app.Context) (int, error) {
 	}
 
 	page, err := strconv.Atoi(p)
-	if err != nil {
-		return 1, err
+	if err != nil || page > MaxPage {
+		return 1, ErrInvalidPage
 	}
 
 	if page < 1 {
@@ -22,16 +38,24 @@ func (u *utils) GetPage(ctx app.Context) (int, error) {
 	return page, nil
 }
 
+// GetPageSize returns the pageSize query parameter, DefaultPageSize when it
+// is missing. Values outside 1..MaxPageSize fail with ErrInvalidPageSize, so
+// a client cannot ask for the whole table at once.
 func (u *utils) GetPageSize(ctx app.Context) (int, error) {
 	ps := ctx.GetQuery("pageSize")
 	if ps == "" {
-		return 20, nil
+		return DefaultPageSize, nil
+	}
+
+	pageSize, err := strconv.Atoi(ps)
+	if err != nil || pageSize < 1 || pageSize > MaxPageSize {
+		return DefaultPageSize, ErrInvalidPageSize
 	}
-	return strconv.Atoi(ps)
+	return pageSize, nil
 }
 
 func (u *utils) GetTotalPage(total, pageSize int) int {
-	if total == 0 {
+	if total == 0 || pageSize < 1 {
 		return 0
 	}
 
@@ -1,8 +1,10 @@
 package utils
 
 import (
-	"crypto/rsa"
 	"go-restapi/app"
+	"go-restapi/app/keys"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type Utils interface {
@@ -11,8 +13,8 @@ type Utils interface {
 	GetPage(ctx app.Context) (int, error)
 	GetPageSize(ctx app.Context) (int, error)
 	GetTotalPage(total, pageSize int) int
-	GetAccessToken(key *rsa.PrivateKey, data app.TokenData, expireHour int) (string, int64, error)
-	GetPrivateKey() (*rsa.PrivateKey, error)
+	GetAccessToken(key *keys.Key, data app.TokenData, conf app.Auth) (string, int64, error)
+	ParseAccessToken(keyfunc jwt.Keyfunc, conf app.Auth, tokenString string) (*app.TokenData, error)
 	GetUUID() string
 }
 